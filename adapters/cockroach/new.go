@@ -2,6 +2,7 @@ package cockroach
 
 import (
 	//_ "github.com/denisenkom/go-postgresdb"
+	"github.com/rbastic/dyndao/adapters/core"
 	postgre "github.com/rbastic/dyndao/adapters/postgres"
 	sg "github.com/rbastic/dyndao/sqlgen"
 )
@@ -18,8 +19,14 @@ func New(g *sg.SQLGenerator) *sg.SQLGenerator {
 	g.IsFloatingType = sg.FnIsFloatingType(postgre.IsFloatingType)
 	g.IsTimestampType = sg.FnIsTimestampType(postgre.IsTimestampType)
 	g.IsLOBType = sg.FnIsLOBType(postgre.IsLOBType)
+	g.CurrentTimestamp = sg.FnCurrentTimestamp(postgre.CurrentTimestamp)
 	g.BindingInsertSQL = sg.FnBindingInsertSQL(postgre.BindingInsertSQL)
+	g.BindingInsertManySQL = sg.FnBindingInsertManySQL(postgre.BindingInsertManySQL)
 	g.RenderCreateColumn = sg.FnRenderCreateColumn(postgre.RenderCreateColumn)
+	g.AlterTable = sg.FnAlterTable(postgre.AlterTable)
+	g.GetLock = sg.FnGetLock(postgre.GetLock)
+	g.ReleaseLock = sg.FnReleaseLock(postgre.ReleaseLock)
+	g.CreateIndex = sg.FnCreateIndex(core.CreatePartialIndex)
 	g.RenderBindingValueWithInt = sg.FnRenderBindingValueWithInt(postgre.RenderBindingValueWithInt)
 	g.BindingUpdate = sg.FnBindingUpdate(postgre.BindingUpdate)
 	g.DynamicObjectSetter = sg.FnDynamicObjectSetter(postgre.DynamicObjectSetter)
@@ -0,0 +1,61 @@
+package common
+
+import (
+	"errors"
+
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// AlterTable renders the changes in d as standard SQL ALTER TABLE
+// statements, as understood by PostgreSQL, CockroachDB and DB2. columnType
+// renders a column's data type for the database. UNIQUE constraints are
+// assumed to be named as PostgreSQL names them when they are declared inline
+// (table_column_key), which is also the name given to those added here. DB2
+// names them otherwise, so its AlterTable refuses to remove uniqueness.
+func AlterTable(g *sg.SQLGenerator, d *schema.TableDiff, columnType func(f *schema.Column) string) ([]string, error) {
+	tableName := schema.GetTableName(d.New.Name, d.Name)
+	alter := "ALTER TABLE " + tableName + " "
+
+	var stmts []string
+	for _, f := range d.AddedColumns {
+		stmts = append(stmts, alter+"ADD COLUMN "+g.RenderCreateColumn(g, f))
+	}
+	for _, cd := range d.ChangedColumns {
+		if err := CheckIdentityUnchanged(d, cd); err != nil {
+			return nil, err
+		}
+		name := cd.New.Name
+		if cd.TypeChanged || cd.LengthChanged {
+			stmts = append(stmts, alter+"ALTER COLUMN "+name+" SET DATA TYPE "+columnType(cd.New))
+		}
+		if cd.NullChanged {
+			if cd.New.AllowNull {
+				stmts = append(stmts, alter+"ALTER COLUMN "+name+" DROP NOT NULL")
+			} else {
+				stmts = append(stmts, alter+"ALTER COLUMN "+name+" SET NOT NULL")
+			}
+		}
+		if cd.UniqueChanged {
+			constraint := tableName + "_" + name + "_key"
+			if cd.New.IsUnique {
+				stmts = append(stmts, alter+"ADD CONSTRAINT "+constraint+" UNIQUE ("+name+")")
+			} else {
+				stmts = append(stmts, alter+"DROP CONSTRAINT "+constraint)
+			}
+		}
+	}
+	for _, f := range d.RemovedColumns {
+		stmts = append(stmts, alter+"DROP COLUMN "+f.Name)
+	}
+	return stmts, nil
+}
+
+// CheckIdentityUnchanged returns an error if cd makes a column an identity
+// column or stops it being one, which no database can do with ALTER TABLE.
+func CheckIdentityUnchanged(d *schema.TableDiff, cd *schema.ColumnDiff) error {
+	if cd.Old.IsIdentity != cd.New.IsIdentity {
+		return errors.New("dyndao: cannot change whether column " + cd.New.Name + " of table " + d.Name + " is an identity column")
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package common
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/rbastic/dyndao/schema"
+)
+
+// RenderForeignKey renders ref as a FOREIGN KEY table constraint, for use
+// inside CREATE TABLE. actionFn renders a referential action for the
+// database, with onUpdate set for the ON UPDATE action, and may return an
+// empty string to leave the action out, or an error if the database
+// doesn't support it.
+func RenderForeignKey(sch *schema.Schema, ref *schema.Reference, actionFn func(a schema.FKAction, onUpdate bool) (string, error)) (string, error) {
+	tbl := sch.Tables[ref.Table]
+	refTbl := sch.Tables[ref.RefTable]
+	if tbl == nil || refTbl == nil {
+		return "", errors.New("dyndao: RenderForeignKey: unknown table in foreign key " + ref.Name)
+	}
+
+	columns := make([]string, len(ref.Columns))
+	for i, col := range ref.Columns {
+		columns[i] = tbl.GetColumnName(col)
+	}
+	refColumns := make([]string, len(ref.RefColumns))
+	for i, col := range ref.RefColumns {
+		refColumns[i] = refTbl.GetColumnName(col)
+	}
+
+	sql := "CONSTRAINT " + ref.Name +
+		" FOREIGN KEY (" + strings.Join(columns, ",") + ")" +
+		" REFERENCES " + schema.GetTableName(refTbl.Name, ref.RefTable) + " (" + strings.Join(refColumns, ",") + ")"
+
+	onDelete, err := actionFn(ref.OnDelete, false)
+	if err != nil {
+		return "", err
+	}
+	if onDelete != "" {
+		sql += " ON DELETE " + onDelete
+	}
+	onUpdate, err := actionFn(ref.OnUpdate, true)
+	if err != nil {
+		return "", err
+	}
+	if onUpdate != "" {
+		sql += " ON UPDATE " + onUpdate
+	}
+	return sql, nil
+}
+
+// FKAction renders a referential action as it is named in standard SQL,
+// which most databases understand.
+func FKAction(a schema.FKAction, onUpdate bool) (string, error) {
+	return string(a), nil
+}
@@ -0,0 +1,54 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/rbastic/dyndao/object"
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// InsertColumns resolves the (possibly aliased) keys of a row being inserted
+// to their column definitions.
+func InsertColumns(schTable *schema.Table, keys []string) ([]*schema.Column, error) {
+	fields := make([]*schema.Column, len(keys))
+	for i, k := range keys {
+		f := schTable.GetColumn(k)
+		if f == nil {
+			return nil, fmt.Errorf("dyndao: unknown field for key: [%s] for table %s", k, schTable.Name)
+		}
+		fields[i] = f
+	}
+	return fields, nil
+}
+
+// RenderInsertValues renders the values of row for an INSERT, in the order of
+// keys (which correspond to fields). Each value becomes a binding parameter,
+// except *object.SQLValue, which is rendered as-is, and nil, which is
+// rendered as NULL. bindI is the next binding parameter index, and it is
+// advanced past any parameters that are rendered.
+func RenderInsertValues(g *sg.SQLGenerator, fields []*schema.Column, keys []string, row map[string]interface{}, bindI *int) ([]string, []interface{}, error) {
+	var bindArgs []interface{}
+	bindNames := make([]string, len(keys))
+	for i, k := range keys {
+		v, ok := row[k]
+		if !ok {
+			return nil, nil, fmt.Errorf("dyndao: missing value for key: [%s]", k)
+		}
+		switch val := v.(type) {
+		case *object.SQLValue:
+			bindNames[i] = val.String()
+		case nil:
+			bindNames[i] = "NULL"
+		default:
+			bindNames[i] = g.RenderBindingValueWithInt(fields[i], *bindI)
+			barg, err := g.RenderInsertValue(bindI, fields[i], v)
+			if err != nil {
+				return nil, nil, err
+			}
+			bindArgs = append(bindArgs, barg)
+			*bindI++
+		}
+	}
+	return bindNames, bindArgs, nil
+}
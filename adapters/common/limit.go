@@ -0,0 +1,24 @@
+package common
+
+import (
+	"fmt"
+)
+
+// RenderOffsetFetch renders the SQL:2008 OFFSET ... FETCH clause, used by SQL
+// Server, Oracle 12c and later, and DB2. It returns an empty string if
+// neither limit nor offset are set.
+func RenderOffsetFetch(limit int, offset int) string {
+	if limit <= 0 && offset <= 0 {
+		return ""
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	// SQL Server insists on OFFSET whenever FETCH is used, and the others
+	// don't mind it, so always render it.
+	s := fmt.Sprintf("OFFSET %d ROWS", offset)
+	if limit > 0 {
+		s += fmt.Sprintf(" FETCH NEXT %d ROWS ONLY", limit)
+	}
+	return s
+}
@@ -0,0 +1,67 @@
+package common
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/rbastic/dyndao/query"
+	"github.com/rbastic/dyndao/schema"
+)
+
+// RenderOrderBy renders an ORDER BY clause for orders, resolving any column
+// aliases. NULLS FIRST / NULLS LAST are rendered as-is, which Postgres,
+// CockroachDB, Oracle, DB2 and SQLite (3.30.0 onwards) all understand. It
+// returns an empty string if there is nothing to order by.
+func RenderOrderBy(schTable *schema.Table, orders []query.Order) (string, error) {
+	return renderOrderBy(schTable, orders, func(name string, ord query.Order) string {
+		key := orderKey(name, ord)
+		if ord.Nulls != query.NullsDefault {
+			key += " NULLS " + string(ord.Nulls)
+		}
+		return key
+	})
+}
+
+// RenderOrderByEmulatingNulls is RenderOrderBy for databases that lack NULLS
+// FIRST / NULLS LAST (MySQL, SQL Server). NULL placement is emulated by
+// sorting on a CASE expression ahead of the column itself.
+func RenderOrderByEmulatingNulls(schTable *schema.Table, orders []query.Order) (string, error) {
+	return renderOrderBy(schTable, orders, func(name string, ord query.Order) string {
+		key := orderKey(name, ord)
+		switch ord.Nulls {
+		case query.NullsFirst:
+			return "CASE WHEN " + name + " IS NULL THEN 0 ELSE 1 END, " + key
+		case query.NullsLast:
+			return "CASE WHEN " + name + " IS NULL THEN 1 ELSE 0 END, " + key
+		}
+		return key
+	})
+}
+
+func orderKey(name string, ord query.Order) string {
+	if ord.Desc {
+		return name + " DESC"
+	}
+	return name
+}
+
+func renderOrderBy(schTable *schema.Table, orders []query.Order, renderKey func(string, query.Order) string) (string, error) {
+	if len(orders) == 0 {
+		return "", nil
+	}
+	keys := make([]string, len(orders))
+	for i, ord := range orders {
+		f := schTable.GetColumn(ord.Column)
+		if f == nil {
+			return "", errors.New("dyndao: RenderOrderBy: unknown field " + ord.Column + " in table " + schTable.Name)
+		}
+		switch ord.Nulls {
+		case query.NullsDefault, query.NullsFirst, query.NullsLast:
+		default:
+			return "", errors.New("dyndao: RenderOrderBy: unknown NULLS placement " + string(ord.Nulls) + " for field " + ord.Column)
+		}
+		keys[i] = renderKey(f.Name, ord)
+	}
+	return "ORDER BY " + strings.Join(keys, ", "), nil
+}
@@ -15,8 +15,7 @@ import (
 // See http://www.sqlitetutorial.net/sqlite-autoincrement/
 
 func RenderCreateColumn(sg *sg.SQLGenerator, f *schema.Column, identityStr string, mapTypeFn func(dbType string) string) string {
-	dataType := strings.ToUpper(f.DBType)
-
+	dataType := ColumnType(f, mapTypeFn)
 	notNull := ""
 	identity := ""
 	unique := ""
@@ -29,12 +28,6 @@ func RenderCreateColumn(sg *sg.SQLGenerator, f *schema.Column, identityStr strin
 	} else {
 		notNull = "NOT NULL"
 	}
-	if mapTypeFn != nil {
-		dataType = mapTypeFn(dataType)
-	}
-	if f.Length > 0 {
-		dataType = fmt.Sprintf("%s(%d)", dataType, f.Length)
-	}
 
 	if f.IsUnique {
 		unique = "UNIQUE"
@@ -42,3 +35,16 @@ func RenderCreateColumn(sg *sg.SQLGenerator, f *schema.Column, identityStr strin
 
 	return strings.Join([]string{f.Name, dataType, identity, notNull, unique}, " ")
 }
+
+// ColumnType renders the data type of a column, as mapped by mapTypeFn (which
+// may be nil) from the upper-cased DBType, with its Length if set.
+func ColumnType(f *schema.Column, mapTypeFn func(dbType string) string) string {
+	dataType := strings.ToUpper(f.DBType)
+	if mapTypeFn != nil {
+		dataType = mapTypeFn(dataType)
+	}
+	if f.Length > 0 {
+		dataType = fmt.Sprintf("%s(%d)", dataType, f.Length)
+	}
+	return dataType
+}
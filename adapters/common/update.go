@@ -10,7 +10,6 @@ import (
 	"github.com/rbastic/dyndao/object"
 	"github.com/rbastic/dyndao/schema"
 	sg "github.com/rbastic/dyndao/sqlgen"
-	"github.com/rbastic/nils"
 )
 
 func zeroTime(arg interface{}) bool {
@@ -74,83 +73,66 @@ func BindingUpdate(g *sg.SQLGenerator, sch *schema.Schema, obj *object.Object) (
 		return "", nil, nil, err
 	}
 
-	i := 0
+	var versionCol *schema.Column
+	if schTbl.VersionColumn != "" {
+		versionCol = schTbl.GetColumn(schTbl.VersionColumn)
+	}
+	isVersion := func(f *schema.Column) bool {
+		return versionCol != nil && f.Name == versionCol.Name
+	}
+
+	// If some things have changed, then only use fields that we're sure have
+	// changed. An update where it's not explicitly clear that anything has
+	// changed should just set every field we have available. A change to
+	// the version column alone doesn't count, as the ORM advances it.
+	var keys []string
+	for k := range obj.ChangedColumns {
+		if f := schTbl.GetColumn(k); f == nil || !isVersion(f) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		for k := range obj.KV {
+			keys = append(keys, k)
+		}
+	}
 
 	var bindArgs []interface{}
 	var newValuesAry []string
 
-	// If some things have changed, then only use fields that we're sure have changed
-
-	// TODO: Refactor this code.
-	if len(obj.ChangedColumns) > 0 {
-		bindArgs = make([]interface{}, len(obj.ChangedColumns))
-		newValuesAry = make([]string, len(obj.ChangedColumns))
-
-		for k := range obj.ChangedColumns {
-			f := schTbl.GetColumn(k)
-			if f == nil {
-				return "", nil, nil, errors.New("BindingUpdate: field config unavailable for object Type: " + obj.Type + ", key: " + k)
-			}
-			if f.IsIdentity {
-				continue
-			}
-			v := obj.KV[k]
-
-			vStr, wasSV := sqlValueConvert(v)
-			if wasSV {
-				newValuesAry[i] = fmt.Sprintf("%s = %s", f.Name, vStr)
-				bindArgs[i] = nil
-			} else {
-				if g.IsTimestampType(schTbl.GetColumn(k).DBType) {
-					v = safeConvert(v)
-				}
-				if v == nil || zeroTime(v) {
-					newValuesAry[i] = fmt.Sprintf("%s = NULL", f.Name)
-					bindArgs[i] = nil
-				} else {
-					newValuesAry[i] = fmt.Sprintf("%s = %s", f.Name, g.RenderBindingValueWithInt(f, *bindI))
-					bindArgs[i] = v
-				}
-			}
-			i++
+	for _, k := range keys {
+		f := schTbl.GetColumn(k)
+		if f == nil {
+			return "", nil, nil, errors.New("BindingUpdate: field config unavailable for object Type: " + obj.Type + ", key: " + k)
 		}
-	} else {
-		// An update where it's not explicitly clear that anything has changed should
-		// just set every field we have available.
-		bindArgs = make([]interface{}, len(obj.KV)-1)
-		// TODO: -1 for Oracle because we expect an identity field
-		newValuesAry = make([]string, len(obj.KV)-1)
-
-		for k, v := range obj.KV {
-			f := schTbl.GetColumn(k)
-			if f == nil {
-				return "", nil, nil, errors.New("BindingUpdate: field config unavailable for object Type: " + obj.Type + ", key: " + k)
-			}
-			if f.IsIdentity {
-				continue
-			}
-
-			vStr, wasSV := sqlValueConvert(v)
-			if wasSV {
-				newValuesAry[i] = fmt.Sprintf("%s = %s", f.Name, vStr)
-				bindArgs[i] = nil
-			} else {
-				if g.IsTimestampType(schTbl.GetColumn(k).DBType) {
-					v = safeConvert(v)
-				}
-				if v == nil || zeroTime(v) {
-					newValuesAry[i] = fmt.Sprintf("%s = NULL", f.Name)
-					bindArgs[i] = nil
-				} else {
-					newValuesAry[i] = fmt.Sprintf("%s = %s", f.Name, g.RenderBindingValueWithInt(f, *bindI))
-					bindArgs[i] = v
-				}
-			}
+		if f.IsIdentity || isVersion(f) {
+			continue
+		}
+		v := obj.KV[k]
 
-			i++
+		vStr, wasSV := sqlValueConvert(v)
+		if wasSV {
+			newValuesAry = append(newValuesAry, fmt.Sprintf("%s = %s", f.Name, vStr))
+			continue
 		}
+		if v != nil && g.IsTimestampType(f.DBType) {
+			v = safeConvert(v)
+		}
+		if v == nil || zeroTime(v) {
+			newValuesAry = append(newValuesAry, fmt.Sprintf("%s = NULL", f.Name))
+			continue
+		}
+		newValuesAry = append(newValuesAry, fmt.Sprintf("%s = %s", f.Name, g.RenderBindingValueWithInt(f, *bindI)))
+		bindArgs = append(bindArgs, v)
+		*bindI++
+	}
+
+	// The version column is always set, to the object's (advanced) value
+	if versionCol != nil {
+		newValuesAry = append(newValuesAry, fmt.Sprintf("%s = %s", versionCol.Name, g.RenderBindingValueWithInt(versionCol, *bindI)))
+		bindArgs = append(bindArgs, obj.Get(schTbl.VersionColumn))
+		*bindI++
 	}
-	bindArgs = nils.RemoveNilsIfNeeded(bindArgs)
 
 	tableName := schema.GetTableName(schTbl.Name, obj.Type)
 	sqlStr := fmt.Sprintf("UPDATE %s SET %s WHERE %s", tableName, strings.Join(newValuesAry, ","), whereClause)
@@ -0,0 +1,138 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// Upsert is an upsert request that has been checked against the schema and
+// had its values rendered, ready for a dialect to build a statement from.
+type Upsert struct {
+	TableName string
+
+	// Columns are inserted with the corresponding BindNames
+	Columns   []*schema.Column
+	BindNames []string
+	BindArgs  []interface{}
+
+	// Conflict identifies an existing row, Update are the columns that
+	// are overwritten when one exists.
+	Conflict []*schema.Column
+	Update   []*schema.Column
+}
+
+// PrepareUpsert checks an upsert of data into table against the schema, and
+// renders its values. data must contain every one of conflictColumns. The
+// columns to update are the rest of data, except identity and AutoCreateTime
+// columns.
+func PrepareUpsert(g *sg.SQLGenerator, sch *schema.Schema, table string, data map[string]interface{}, conflictColumns []string) (*Upsert, error) {
+	schTable := sch.GetTable(table)
+	if schTable == nil {
+		return nil, errors.New("BindingUpsert: Table map unavailable for table " + table)
+	}
+	if len(data) == 0 {
+		return nil, errors.New("BindingUpsert: Empty data passed")
+	}
+	if len(conflictColumns) == 0 {
+		return nil, errors.New("BindingUpsert: no conflict columns for table " + table)
+	}
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fields, err := InsertColumns(schTable, keys)
+	if err != nil {
+		return nil, errors.New("BindingUpsert: " + err.Error())
+	}
+	bindI := 1
+	bindNames, bindArgs, err := RenderInsertValues(g, fields, keys, data, &bindI)
+	if err != nil {
+		return nil, errors.New("BindingUpsert: " + err.Error())
+	}
+
+	u := &Upsert{
+		TableName: schema.GetTableName(schTable.Name, table),
+		Columns:   fields,
+		BindNames: bindNames,
+		BindArgs:  bindArgs,
+	}
+
+	isConflict := make(map[string]bool)
+	for _, c := range conflictColumns {
+		f := schTable.GetColumn(c)
+		if f == nil {
+			return nil, errors.New("BindingUpsert: unknown conflict field " + c + " in table " + table)
+		}
+		found := false
+		for _, inserted := range fields {
+			if inserted.Name == f.Name {
+				found = true
+			}
+		}
+		if !found {
+			return nil, errors.New("BindingUpsert: no value for conflict field " + c + " in table " + table)
+		}
+		isConflict[f.Name] = true
+		u.Conflict = append(u.Conflict, f)
+	}
+	for _, f := range fields {
+		if !isConflict[f.Name] && !f.IsIdentity && !f.AutoCreateTime {
+			u.Update = append(u.Update, f)
+		}
+	}
+	return u, nil
+}
+
+// ColumnNames returns the names of the inserted columns
+func (u *Upsert) ColumnNames() []string {
+	names := make([]string, len(u.Columns))
+	for i, f := range u.Columns {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// RenderMerge renders an upsert as a MERGE statement, as used by SQL Server,
+// Oracle and DB2. source is the USING clause's row source, which must be
+// aliased as 'source' and provide every inserted column by name. The target
+// table is aliased as 'target' with targetAlias (e.g. "AS target", as
+// Oracle won't accept AS for a table alias).
+func RenderMerge(u *Upsert, targetAlias string, source string) string {
+	on := make([]string, len(u.Conflict))
+	for i, f := range u.Conflict {
+		on[i] = fmt.Sprintf("target.%s = source.%s", f.Name, f.Name)
+	}
+	// Identity columns are only used to match existing rows, and are left
+	// for the database to generate on insert.
+	var insertNames, sourceValues []string
+	for _, f := range u.Columns {
+		if f.IsIdentity {
+			continue
+		}
+		insertNames = append(insertNames, f.Name)
+		sourceValues = append(sourceValues, "source."+f.Name)
+	}
+
+	sqlStr := fmt.Sprintf("MERGE INTO %s %s USING %s ON (%s)",
+		u.TableName, targetAlias, source, strings.Join(on, " AND "))
+	if len(u.Update) > 0 {
+		set := make([]string, len(u.Update))
+		for i, f := range u.Update {
+			set[i] = fmt.Sprintf("target.%s = source.%s", f.Name, f.Name)
+		}
+		sqlStr += " WHEN MATCHED THEN UPDATE SET " + strings.Join(set, ", ")
+	}
+	if len(insertNames) > 0 {
+		sqlStr += fmt.Sprintf(" WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)",
+			strings.Join(insertNames, ","), strings.Join(sourceValues, ","))
+	}
+	return sqlStr
+}
@@ -0,0 +1,140 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/rbastic/dyndao/query"
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+var aggregateAliasRE = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// BindingAggregate constructs a SELECT statement for the aggregates aggs over
+// the rows matching q, grouped by the groupBy columns. The result columns
+// are the groupBy columns followed by the aggregates, in the order given.
+// opts (which may be nil) may order the groups, by either groupBy columns or
+// aggregate aliases, and limit them.
+func BindingAggregate(g *sg.SQLGenerator, sch *schema.Schema, table string, q *query.Query, groupBy []string, aggs []query.Aggregate, opts *query.Options) (string, []interface{}, error) {
+	schTable := sch.GetTable(table)
+	if schTable == nil {
+		return "", nil, errors.New("BindingAggregate: Table map unavailable for table " + table)
+	}
+	if len(aggs) == 0 && len(groupBy) == 0 {
+		return "", nil, errors.New("BindingAggregate: no aggregates or group columns for table " + table)
+	}
+	if opts == nil {
+		opts = &query.Options{}
+	}
+
+	groupCols := make([]string, len(groupBy))
+	for i, name := range groupBy {
+		f := schTable.GetColumn(name)
+		if f == nil {
+			return "", nil, errors.New("BindingAggregate: unknown group field " + name + " in table " + table)
+		}
+		groupCols[i] = f.Name
+	}
+
+	// The aggregate aliases may be used in the ORDER BY, so we render it
+	// against a copy of the table that knows about them.
+	orderTable := *schTable
+	orderTable.Columns = make(map[string]*schema.Column, len(schTable.Columns)+len(aggs))
+	for k, v := range schTable.Columns {
+		orderTable.Columns[k] = v
+	}
+
+	selectCols := append([]string{}, groupCols...)
+	for _, agg := range aggs {
+		expr, err := renderAggregate(schTable, agg)
+		if err != nil {
+			return "", nil, errors.Wrap(err, "BindingAggregate")
+		}
+		selectCols = append(selectCols, expr)
+
+		aliasCol := schema.DefaultColumn()
+		aliasCol.Name = agg.Alias
+		orderTable.Columns[agg.Alias] = aliasCol
+	}
+	bindI := 1
+	whereClause, bindWhere, err := g.RenderWhereClause(g, schTable, q, &bindI)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "BindingAggregate")
+	}
+	orderBy, err := g.RenderOrderBy(g, &orderTable, opts.OrderBy)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "BindingAggregate")
+	}
+
+	tableName := schema.GetTableName(schTable.Name, table)
+
+	sqlStr := fmt.Sprintf("SELECT %s FROM %s", strings.Join(selectCols, ", "), tableName)
+	if whereClause != "" {
+		sqlStr += " WHERE " + whereClause
+	}
+	if len(groupCols) > 0 {
+		sqlStr += " GROUP BY " + strings.Join(groupCols, ", ")
+	}
+	if orderBy != "" {
+		sqlStr += " " + orderBy
+	}
+	limitOffset := g.RenderLimitOffset(g, opts.Limit, opts.Offset, orderBy != "")
+	if limitOffset != "" {
+		sqlStr += " " + limitOffset
+	}
+	return sqlStr, bindWhere, nil
+}
+
+func renderAggregate(schTable *schema.Table, agg query.Aggregate) (string, error) {
+	if !aggregateAliasRE.MatchString(agg.Alias) {
+		return "", errors.New("dyndao: invalid aggregate alias '" + agg.Alias + "'")
+	}
+	if schTable.GetColumn(agg.Alias) != nil {
+		return "", errors.New("dyndao: aggregate alias " + agg.Alias + " clashes with a column in table " + schTable.Name)
+	}
+	switch agg.Func {
+	case query.AggCount, query.AggSum, query.AggMin, query.AggMax, query.AggAvg:
+	default:
+		return "", errors.New("dyndao: unknown aggregate function " + string(agg.Func))
+	}
+
+	arg := "*"
+	if agg.Column != "" {
+		f := schTable.GetColumn(agg.Column)
+		if f == nil {
+			return "", errors.New("dyndao: unknown aggregate field " + agg.Column + " in table " + schTable.Name)
+		}
+		arg = f.Name
+	} else if agg.Func != query.AggCount {
+		return "", errors.New("dyndao: aggregate " + string(agg.Func) + " requires a column")
+	}
+	return fmt.Sprintf("%s(%s) AS %s", agg.Func, arg, agg.Alias), nil
+}
+
+// BindingExists constructs a statement that returns a single row if any row
+// in the table matches q, and no rows otherwise.
+func BindingExists(g *sg.SQLGenerator, sch *schema.Schema, table string, q *query.Query) (string, []interface{}, error) {
+	schTable := sch.GetTable(table)
+	if schTable == nil {
+		return "", nil, errors.New("BindingExists: Table map unavailable for table " + table)
+	}
+
+	bindI := 1
+	whereClause, bindWhere, err := g.RenderWhereClause(g, schTable, q, &bindI)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "BindingExists")
+	}
+
+	tableName := schema.GetTableName(schTable.Name, table)
+
+	sqlStr := "SELECT 1 FROM " + tableName
+	if whereClause != "" {
+		sqlStr += " WHERE " + whereClause
+	}
+	sqlStr += " " + g.RenderLimitOffset(g, 1, 0, false)
+	return sqlStr, bindWhere, nil
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// CreateIndex determines the SQL to create an index on a given table within
+// a schema. Partial indexes are rejected, since not every database supports
+// them; see CreatePartialIndex.
+func CreateIndex(g *sg.SQLGenerator, s *schema.Schema, table string, idx *schema.Index) (string, error) {
+	if idx.Where != "" {
+		return "", errors.New("dyndao: CreateIndex: partial indexes are not supported by this database adapter")
+	}
+	return renderCreateIndex(g, s, table, idx)
+}
+
+// CreatePartialIndex is CreateIndex for databases that support partial
+// indexes, rendering a WHERE clause for them.
+func CreatePartialIndex(g *sg.SQLGenerator, s *schema.Schema, table string, idx *schema.Index) (string, error) {
+	return renderCreateIndex(g, s, table, idx)
+}
+
+// DropIndex determines the SQL to drop an index of a given table within a
+// schema. Index names belong to the schema rather than the table here.
+func DropIndex(g *sg.SQLGenerator, s *schema.Schema, table string, idx *schema.Index) (string, error) {
+	tbl, ok := s.Tables[table]
+	if !ok {
+		return "", errors.New("dyndao: unknown schema for table with name " + table)
+	}
+	return "DROP INDEX " + idx.IndexName(schema.GetTableName(tbl.Name, table)), nil
+}
+
+// DropTableIndex is DropIndex for databases whose index names belong to the
+// table, which must be given with ON.
+func DropTableIndex(g *sg.SQLGenerator, s *schema.Schema, table string, idx *schema.Index) (string, error) {
+	tbl, ok := s.Tables[table]
+	if !ok {
+		return "", errors.New("dyndao: unknown schema for table with name " + table)
+	}
+	tableName := schema.GetTableName(tbl.Name, table)
+	return "DROP INDEX " + idx.IndexName(tableName) + " ON " + tableName, nil
+}
+
+func renderCreateIndex(g *sg.SQLGenerator, s *schema.Schema, table string, idx *schema.Index) (string, error) {
+	tbl, ok := s.Tables[table]
+	if !ok {
+		return "", errors.New("dyndao: unknown schema for table with name " + table)
+	}
+	if len(idx.Columns) == 0 {
+		return "", errors.New("dyndao: CreateIndex: index has no columns")
+	}
+	tableName := schema.GetTableName(tbl.Name, table)
+
+	columns := make([]string, len(idx.Columns))
+	for i, col := range idx.Columns {
+		columns[i] = tbl.GetColumnName(col)
+	}
+
+	unique := ""
+	if idx.Unique {
+		unique = "UNIQUE "
+	}
+	sql := fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)", unique, idx.IndexName(tableName), tableName, strings.Join(columns, ","))
+	if idx.Where != "" {
+		sql += " WHERE " + idx.Where
+	}
+
+	if g.Tracing {
+		fmt.Printf("dyndao: CreateIndex SQL:[%s]\n", sql)
+	}
+
+	return sql, nil
+}
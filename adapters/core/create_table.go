@@ -11,7 +11,8 @@ import (
 	sg "github.com/rbastic/dyndao/sqlgen"
 )
 
-// CreateTable determines the SQL to create a given table within a schema
+// CreateTable determines the SQL to create a given table within a schema,
+// including FOREIGN KEY constraints for the relations that declare them
 func CreateTable(g *sg.SQLGenerator, s *schema.Schema, table string) (string, error) {
 	tbl, ok := s.Tables[table]
 	if !ok {
@@ -27,6 +28,15 @@ func CreateTable(g *sg.SQLGenerator, s *schema.Schema, table string) (string, er
 		i++
 	}
 
+	// Constraints for the relations of parent tables with a ForeignKey
+	for _, ref := range s.References(table) {
+		fk, err := g.RenderForeignKey(g, s, ref)
+		if err != nil {
+			return "", err
+		}
+		sqlColumns = append(sqlColumns, fk)
+	}
+
 	sql := fmt.Sprintf(`CREATE TABLE %s (
 	%s
 )
@@ -0,0 +1,8 @@
+package core
+
+// CurrentTimestamp returns the standard SQL for the current time. Which
+// time zone it is in varies between databases, so adapters override it to
+// return UTC.
+func CurrentTimestamp() string {
+	return "CURRENT_TIMESTAMP"
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/rbastic/dyndao/object"
+	"github.com/rbastic/dyndao/query"
 	"github.com/rbastic/dyndao/schema"
 	sg "github.com/rbastic/dyndao/sqlgen"
 )
@@ -12,23 +13,28 @@ import (
 // BindingDelete generates the appropriate SQL, binding args, and binding where clause parameters
 // to execute the requested delete operation. 'obj' is not required to be a
 func BindingDelete(g *sg.SQLGenerator, sch *schema.Schema, queryVals *object.Object) (string, []interface{}, error) {
-	table := queryVals.Type
+	return g.BindingDeleteWhere(g, sch, queryVals.Type, query.FromKV(queryVals.KV))
+}
+
+// BindingDeleteWhere generates a DELETE statement for every row matching q.
+// An empty query deletes the whole table.
+func BindingDeleteWhere(g *sg.SQLGenerator, sch *schema.Schema, table string, q *query.Query) (string, []interface{}, error) {
 	schTable := sch.GetTable(table)
 	if schTable == nil {
 		return "", nil, errors.New("BindingDelete: Table map unavailable for table " + table)
 	}
 	tableName := schema.GetTableName(schTable.Name, table)
 
-	whereClause, bindArgs, err := g.RenderWhereClause(g, schTable, queryVals)
+	bindI := 1
+	whereClause, bindArgs, err := g.RenderWhereClause(g, schTable, q, &bindI)
 	if err != nil {
 		return "", nil, err
 	}
 
-	whereString := "WHERE"
-	if len(bindArgs) == 0 {
-		whereString = ""
+	sqlStr := "DELETE FROM " + tableName
+	if whereClause != "" {
+		sqlStr += " WHERE " + whereClause
 	}
-	sqlStr := fmt.Sprintf("DELETE FROM %s %s %s", tableName, whereString, whereClause)
 	if g.Tracing {
 		// TODO: logger interface
 		fmt.Println("BindingDelete sqlStr->", sqlStr, "bindArgs->", bindArgs)
@@ -0,0 +1,40 @@
+package core
+
+import (
+	"errors"
+
+	"github.com/rbastic/dyndao/adapters/common"
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// RenderForeignKey renders a FOREIGN KEY table constraint, with its
+// referential actions named as in standard SQL.
+func RenderForeignKey(g *sg.SQLGenerator, s *schema.Schema, ref *schema.Reference) (string, error) {
+	return common.RenderForeignKey(s, ref, common.FKAction)
+}
+
+// AddForeignKey renders an ALTER TABLE statement adding the FOREIGN KEY
+// constraint ref, as rendered by the generator's RenderForeignKey, to an
+// existing table.
+func AddForeignKey(g *sg.SQLGenerator, s *schema.Schema, ref *schema.Reference) (string, error) {
+	tbl, ok := s.Tables[ref.Table]
+	if !ok {
+		return "", errors.New("dyndao: unknown schema for table with name " + ref.Table)
+	}
+	constraint, err := g.RenderForeignKey(g, s, ref)
+	if err != nil {
+		return "", err
+	}
+	return "ALTER TABLE " + schema.GetTableName(tbl.Name, ref.Table) + " ADD " + constraint, nil
+}
+
+// DropForeignKey renders an ALTER TABLE statement dropping the FOREIGN KEY
+// constraint ref.
+func DropForeignKey(g *sg.SQLGenerator, s *schema.Schema, ref *schema.Reference) (string, error) {
+	tbl, ok := s.Tables[ref.Table]
+	if !ok {
+		return "", errors.New("dyndao: unknown schema for table with name " + ref.Table)
+	}
+	return "ALTER TABLE " + schema.GetTableName(tbl.Name, ref.Table) + " DROP CONSTRAINT " + ref.Name, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	sg "github.com/rbastic/dyndao/sqlgen"
 
@@ -129,6 +130,10 @@ func RenderInsertValue(bindI *int, f *schema.Column, value interface{}) (interfa
 			return int64(num), nil
 		}
 		return fmt.Sprintf("%f", num), nil
+	case time.Time:
+		return value, nil
+	case *time.Time:
+		return *value.(*time.Time), nil
 	case *object.SQLValue:
 		val := value.(*object.SQLValue)
 		return val.String(), nil
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rbastic/dyndao/adapters/common"
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// BindingInsertMany generates a single INSERT statement for many rows, with
+// binding parameter values. Every row must have the same set of keys. The
+// statement itself is rendered by the adapter's BindingInsertManySQL.
+func BindingInsertMany(g *sg.SQLGenerator, sch *schema.Schema, table string, rows []map[string]interface{}) (string, []interface{}, error) {
+	if table == "" {
+		return "", nil, errors.New("BindingInsertMany: Empty table name")
+	}
+	if len(rows) == 0 {
+		return "", nil, errors.New("BindingInsertMany: Empty data passed")
+	}
+
+	schTable := sch.GetTable(table)
+	if schTable == nil {
+		return "", nil, errors.New("BindingInsertMany: Table map unavailable for table " + table)
+	}
+	tableName := schema.GetTableName(schTable.Name, table)
+
+	// Sort the keys so that every row renders it's values in the same
+	// order as the column list.
+	keys := make([]string, 0, len(rows[0]))
+	for k := range rows[0] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fields, err := common.InsertColumns(schTable, keys)
+	if err != nil {
+		return "", nil, errors.New("BindingInsertMany: " + err.Error())
+	}
+	colNames := make([]string, len(fields))
+	for i, f := range fields {
+		colNames[i] = f.Name
+	}
+
+	var bindArgs []interface{}
+	rowBindNames := make([][]string, len(rows))
+	bindI := 1
+	for r, row := range rows {
+		if len(row) != len(keys) {
+			return "", nil, errors.New("BindingInsertMany: rows must all have the same columns for table " + table)
+		}
+		bindNames, rowArgs, err := common.RenderInsertValues(g, fields, keys, row, &bindI)
+		if err != nil {
+			return "", nil, errors.New("BindingInsertMany: " + err.Error())
+		}
+		rowBindNames[r] = bindNames
+		bindArgs = append(bindArgs, rowArgs...)
+	}
+
+	sqlStr := g.BindingInsertManySQL(schTable, tableName, colNames, rowBindNames, schTable.Primary)
+	return sqlStr, bindArgs, nil
+}
+
+// BindingInsertManySQL renders a multi-row INSERT ... VALUES statement.
+func BindingInsertManySQL(schTable *schema.Table, tableName string, colNames []string, rowBindNames [][]string, identityCol string) string {
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
+		tableName,
+		strings.Join(colNames, ","),
+		RenderValuesRows(rowBindNames))
+}
+
+// RenderValuesRows renders the row constructors of a multi-row VALUES list,
+// e.g. "(?,?),(?,?)".
+func RenderValuesRows(rowBindNames [][]string) string {
+	rows := make([]string, len(rowBindNames))
+	for i, bindNames := range rowBindNames {
+		rows[i] = "(" + strings.Join(bindNames, ",") + ")"
+	}
+	return strings.Join(rows, ",")
+}
@@ -0,0 +1,27 @@
+package core
+
+import (
+	"fmt"
+
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// noLimit is what we render when an OFFSET is requested without a LIMIT,
+// since MySQL and SQLite won't accept a bare OFFSET.
+const noLimit = "9223372036854775807"
+
+// RenderLimitOffset renders a LIMIT / OFFSET clause, as understood by SQLite,
+// MySQL and Postgres. It returns an empty string if neither is set.
+func RenderLimitOffset(g *sg.SQLGenerator, limit int, offset int, hasOrderBy bool) string {
+	if limit <= 0 && offset <= 0 {
+		return ""
+	}
+	limitStr := noLimit
+	if limit > 0 {
+		limitStr = fmt.Sprintf("%d", limit)
+	}
+	if offset <= 0 {
+		return "LIMIT " + limitStr
+	}
+	return fmt.Sprintf("LIMIT %s OFFSET %d", limitStr, offset)
+}
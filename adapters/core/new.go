@@ -14,17 +14,34 @@ func New() *sg.SQLGenerator {
 
 	g.CreateTable = sg.FnCreateTable(CreateTable)
 	g.DropTable = sg.FnDropTable(DropTable)
+	g.AlterTable = sg.FnAlterTable(AlterTable)
+	g.CreateIndex = sg.FnCreateIndex(CreateIndex)
+	g.DropIndex = sg.FnDropIndex(DropIndex)
+	g.RenderForeignKey = sg.FnRenderForeignKey(RenderForeignKey)
+	g.AddForeignKey = sg.FnAddForeignKey(AddForeignKey)
+	g.DropForeignKey = sg.FnDropForeignKey(DropForeignKey)
 	g.CoreBindingInsert = sg.FnCoreBindingInsert(CoreBindingInsert)
 	g.BindingInsert = sg.FnBindingInsert(BindingInsert)
 	g.BindingInsertSQL = sg.FnBindingInsertSQL(BindingInsertSQL)
+	g.BindingInsertMany = sg.FnBindingInsertMany(BindingInsertMany)
+	g.BindingInsertManySQL = sg.FnBindingInsertManySQL(BindingInsertManySQL)
 	g.BindingRetrieve = sg.FnBindingRetrieve(BindingRetrieve)
+	g.BindingRetrieveQuery = sg.FnBindingRetrieveQuery(BindingRetrieveQuery)
+	g.BindingAggregate = sg.FnBindingAggregate(BindingAggregate)
+	g.BindingExists = sg.FnBindingExists(BindingExists)
 	g.BindingUpdate = sg.FnBindingUpdate(BindingUpdate)
+	g.BindingUpdateWhere = sg.FnBindingUpdateWhere(BindingUpdateWhere)
+	g.BindingUpsert = sg.FnBindingUpsert(BindingUpsert)
 	g.BindingDelete = sg.FnBindingDelete(BindingDelete)
+	g.BindingDeleteWhere = sg.FnBindingDeleteWhere(BindingDeleteWhere)
 	g.GetLock = sg.FnGetLock(GetLock)
 	g.ReleaseLock = sg.FnReleaseLock(ReleaseLock)
 	g.RenderBindingValueWithInt = sg.FnRenderBindingValueWithInt(RenderBindingValueWithInt)
 	g.RenderWhereClause = sg.FnRenderWhereClause(RenderWhereClause)
+	g.RenderLimitOffset = sg.FnRenderLimitOffset(RenderLimitOffset)
+	g.RenderOrderBy = sg.FnRenderOrderBy(RenderOrderBy)
 	g.RenderInsertValue = sg.FnRenderInsertValue(RenderInsertValue)
+	g.CurrentTimestamp = sg.FnCurrentTimestamp(CurrentTimestamp)
 	g.RenderUpdateWhereClause = sg.FnRenderUpdateWhereClause(RenderUpdateWhereClause)
 	g.DynamicObjectSetter = sg.FnDynamicObjectSetter(DynamicObjectSetter)
 	g.MakeColumnPointers = sg.FnMakeColumnPointers(MakeColumnPointers)
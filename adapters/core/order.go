@@ -0,0 +1,14 @@
+package core
+
+import (
+	"github.com/rbastic/dyndao/adapters/common"
+	"github.com/rbastic/dyndao/query"
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// RenderOrderBy renders an ORDER BY clause for orders, using the native
+// NULLS FIRST / NULLS LAST syntax.
+func RenderOrderBy(g *sg.SQLGenerator, schTable *schema.Table, orders []query.Order) (string, error) {
+	return common.RenderOrderBy(schTable, orders)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/rbastic/dyndao/object"
+	"github.com/rbastic/dyndao/query"
 	"github.com/rbastic/dyndao/schema"
 	sg "github.com/rbastic/dyndao/sqlgen"
 )
@@ -74,42 +75,161 @@ func RenderUpdateWhereClause(g *sg.SQLGenerator, schTable *schema.Table, fieldsM
 
 		whereClause = strings.Join(whereKeys, " AND ")
 	}
+
+	// With optimistic locking, the row must still have the version the
+	// object was retrieved with. If the version has been advanced on the
+	// object, its previous value is the one to check.
+	if schTable.VersionColumn != "" {
+		f := schTable.GetColumn(schTable.VersionColumn)
+		if f == nil {
+			return "", nil, &emptyInt, errors.New("dyndao: RenderUpdateWhereClause: unknown version column " + schTable.VersionColumn)
+		}
+		version, ok := obj.ChangedColumns[schTable.VersionColumn]
+		if !ok {
+			version = obj.Get(schTable.VersionColumn)
+		}
+		if version == nil {
+			return "", nil, &emptyInt, errors.New("dyndao: RenderUpdateWhereClause: missing version column " + schTable.VersionColumn)
+		}
+		whereClause += fmt.Sprintf(" AND %s = %s", f.Name, g.RenderBindingValueWithInt(f, bindI))
+		bindArgs = append(bindArgs, version)
+		bindI++
+	}
 	return whereClause, bindArgs, &bindI, nil
 }
 
-func RenderWhereClause(g *sg.SQLGenerator, schTable *schema.Table, obj *object.Object) (string, []interface{}, error) {
-	var whereClause string
-
-	if len(obj.KV) == 0 {
+// RenderWhereClause renders a query.Query into a WHERE clause (without the
+// WHERE keyword) and it's binding arguments. bindI is the next binding
+// parameter index, and it is advanced past any parameters that are rendered,
+// so that the clause can be combined with other bound SQL (e.g. an UPDATE's
+// SET list). An empty query renders as an empty string.
+func RenderWhereClause(g *sg.SQLGenerator, schTable *schema.Table, q *query.Query, bindI *int) (string, []interface{}, error) {
+	if q.IsEmpty() {
 		return "", nil, nil
 	}
+	var bindArgs []interface{}
+	whereClause, err := renderQueryNode(g, schTable, q, bindI, &bindArgs)
+	if err != nil {
+		return "", nil, err
+	}
+	return whereClause, bindArgs, nil
+}
 
-	whereKeys := make([]string, len(obj.KV))
-	bindArgs := make([]interface{}, len(obj.KV))
+var comparisonOps = map[query.Op]string{
+	query.OpEq:   "=",
+	query.OpNe:   "<>",
+	query.OpLt:   "<",
+	query.OpLe:   "<=",
+	query.OpGt:   ">",
+	query.OpGe:   ">=",
+	query.OpLike: "LIKE",
+}
 
-	i := 0
-	bindI := 1
-	for k, v := range obj.KV {
-		f := schTable.GetColumn(k)
-		if f == nil {
-			return "", nil, errors.New("dyndao: RenderWhereClause: unknown field " + k + " in table " + obj.Type)
+func renderQueryNode(g *sg.SQLGenerator, schTable *schema.Table, q *query.Query, bindI *int, bindArgs *[]interface{}) (string, error) {
+	switch q.Op {
+	case query.OpAnd, query.OpOr:
+		joiner := " AND "
+		if q.Op == query.OpOr {
+			joiner = " OR "
 		}
-		sqlName := f.Name
-		whereKeys[i] = fmt.Sprintf("%s = %s", sqlName, g.RenderBindingValueWithInt(f, bindI))
-		switch v.(type) {
-		case *object.SQLValue:
-			sqlv := v.(*object.SQLValue)
-			strV := sqlv.String()
-			bindArgs[i] = strV
-		default:
-			bindArgs[i] = v
+		var parts []string
+		for _, c := range q.Children {
+			if c.IsEmpty() {
+				continue
+			}
+			s, err := renderQueryNode(g, schTable, c, bindI, bindArgs)
+			if err != nil {
+				return "", err
+			}
+			if len(c.Children) > 1 {
+				s = "(" + s + ")"
+			}
+			parts = append(parts, s)
 		}
+		return strings.Join(parts, joiner), nil
+	case query.OpAll:
+		return "1 = 1", nil
+	case query.OpNot:
+		if len(q.Children) != 1 {
+			return "", errors.New("dyndao: RenderWhereClause: NOT expects exactly one child query")
+		}
+		s, err := renderQueryNode(g, schTable, q.Children[0], bindI, bindArgs)
+		if err != nil {
+			return "", err
+		}
+		return "NOT (" + s + ")", nil
+	}
 
-		i++
-		bindI++
+	f := schTable.GetColumn(q.Column)
+	if f == nil {
+		return "", errors.New("dyndao: RenderWhereClause: unknown field " + q.Column + " in table " + schTable.Name)
 	}
-	whereClause = strings.Join(whereKeys, " AND ")
-	return whereClause, bindArgs, nil
+	sqlName := f.Name
+
+	// bind renders a single value, either as a binding parameter or, for
+	// an *object.SQLValue, as the raw unquoted SQL.
+	bind := func(v interface{}) string {
+		if sqlv, ok := v.(*object.SQLValue); ok {
+			return sqlv.String()
+		}
+		r := g.RenderBindingValueWithInt(f, *bindI)
+		*bindArgs = append(*bindArgs, v)
+		*bindI++
+		return r
+	}
+
+	switch q.Op {
+	case query.OpIsNull:
+		return fmt.Sprintf("%s IS NULL", sqlName), nil
+	case query.OpIsNotNull:
+		return fmt.Sprintf("%s IS NOT NULL", sqlName), nil
+	case query.OpBetween:
+		if len(q.Values) != 2 {
+			return "", errors.New("dyndao: RenderWhereClause: BETWEEN expects two values for " + q.Column)
+		}
+		lo := bind(q.Values[0])
+		hi := bind(q.Values[1])
+		return fmt.Sprintf("%s BETWEEN %s AND %s", sqlName, lo, hi), nil
+	case query.OpIn, query.OpNotIn:
+		// An empty IN list can't be rendered portably, so we
+		// render the equivalent constant predicate instead.
+		if len(q.Values) == 0 {
+			if q.Op == query.OpIn {
+				return "1 = 0", nil
+			}
+			return "1 = 1", nil
+		}
+		binds := make([]string, len(q.Values))
+		for i, v := range q.Values {
+			binds[i] = bind(v)
+		}
+		op := "IN"
+		if q.Op == query.OpNotIn {
+			op = "NOT IN"
+		}
+		return fmt.Sprintf("%s %s (%s)", sqlName, op, strings.Join(binds, ", ")), nil
+	}
+
+	op, ok := comparisonOps[q.Op]
+	if !ok {
+		return "", fmt.Errorf("dyndao: RenderWhereClause: unknown query operator %d", q.Op)
+	}
+	if len(q.Values) != 1 {
+		return "", errors.New("dyndao: RenderWhereClause: expected a single value for " + q.Column)
+	}
+	v := q.Values[0]
+
+	// NULL never compares equal to anything, so map the NULL SQLValue
+	// onto IS [NOT] NULL.
+	if sqlv, ok := v.(*object.SQLValue); ok && sqlv.Value == "NULL" {
+		switch q.Op {
+		case query.OpEq:
+			return fmt.Sprintf("%s IS NULL", sqlName), nil
+		case query.OpNe:
+			return fmt.Sprintf("%s IS NOT NULL", sqlName), nil
+		}
+	}
+	return fmt.Sprintf("%s %s %s", sqlName, op, bind(v)), nil
 }
 
 func RenderBindingValueWithInt(f *schema.Column, i int) string {
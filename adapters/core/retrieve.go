@@ -7,6 +7,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/rbastic/dyndao/object"
+	"github.com/rbastic/dyndao/query"
 	"github.com/rbastic/dyndao/schema"
 	sg "github.com/rbastic/dyndao/sqlgen"
 )
@@ -16,13 +17,24 @@ import (
 // binding where clause.
 // DEBUG mode may be turned on by setting an environment parameter, "DEBUG".
 func BindingRetrieve(g *sg.SQLGenerator, sch *schema.Schema, obj *object.Object) (string, []string, []interface{}, error) {
-	table := obj.Type
+	return g.BindingRetrieveQuery(g, sch, obj.Type, query.FromKV(obj.KV), nil)
+}
+
+// BindingRetrieveQuery is the query.Query equivalent of BindingRetrieve. It
+// constructs the SELECT statement for the EssentialColumns of a table, using
+// q as the WHERE clause. opts (which may be nil) supplies the ORDER BY, which
+// otherwise defaults to the table's DefaultOrder, and the LIMIT / OFFSET.
+func BindingRetrieveQuery(g *sg.SQLGenerator, sch *schema.Schema, table string, q *query.Query, opts *query.Options) (string, []string, []interface{}, error) {
 	schTable := sch.GetTable(table)
 	if schTable == nil {
 		return "", nil, nil, errors.New("BindingRetrieve: Table map unavailable for table " + table)
 	}
+	if opts == nil {
+		opts = &query.Options{}
+	}
 
-	whereClause, bindWhere, err := g.RenderWhereClause(g, schTable, obj)
+	bindI := 1
+	whereClause, bindWhere, err := g.RenderWhereClause(g, schTable, q, &bindI)
 	if err != nil {
 		return "", nil, nil, errors.Wrap(err, "BindingRetrieve")
 	}
@@ -32,12 +44,27 @@ func BindingRetrieve(g *sg.SQLGenerator, sch *schema.Schema, obj *object.Object)
 	}
 	columns := strings.Join(schTable.EssentialColumns, ",")
 
-	whereStr := ""
-	if whereClause != "" {
-		whereStr = "WHERE"
+	orders := opts.OrderBy
+	if len(orders) == 0 {
+		orders = schTable.DefaultOrder
+	}
+	orderBy, err := g.RenderOrderBy(g, schTable, orders)
+	if err != nil {
+		return "", nil, nil, errors.Wrap(err, "BindingRetrieve")
 	}
+
 	tableName := schema.GetTableName(schTable.Name, table)
 
-	sqlStr := fmt.Sprintf("SELECT %s FROM %s %s %s", columns, tableName, whereStr, whereClause)
+	sqlStr := fmt.Sprintf("SELECT %s FROM %s", columns, tableName)
+	if whereClause != "" {
+		sqlStr += " WHERE " + whereClause
+	}
+	if orderBy != "" {
+		sqlStr += " " + orderBy
+	}
+	limitOffset := g.RenderLimitOffset(g, opts.Limit, opts.Offset, orderBy != "")
+	if limitOffset != "" {
+		sqlStr += " " + limitOffset
+	}
 	return sqlStr, schTable.EssentialColumns, bindWhere, nil
 }
@@ -20,12 +20,14 @@ func (n *NullTime) Scan(src interface{}) error {
 		return nil
 	}
 
-	t, ok := src.(*time.Time)
-	if !ok {
-		return fmt.Errorf("NullTime can only be used with *time.Time, type was %v", reflect.TypeOf(src))
+	switch t := src.(type) {
+	case time.Time:
+		*n = NullTime(t)
+	case *time.Time:
+		*n = NullTime(*t)
+	default:
+		return fmt.Errorf("NullTime can only be used with time.Time, type was %v", reflect.TypeOf(src))
 	}
-	f := NullTime(*t)
-	n = &f
 	return nil
 }
 
@@ -21,6 +21,7 @@ import (
 	"database/sql"
 	"os"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 	//"fmt"
@@ -28,8 +29,11 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/pkg/errors"
+
 	"github.com/rbastic/dyndao/object"
 	"github.com/rbastic/dyndao/orm"
+	"github.com/rbastic/dyndao/query"
 	"github.com/rbastic/dyndao/schema"
 	"github.com/rbastic/dyndao/schema/test/mock"
 
@@ -263,6 +267,131 @@ func TestSuiteNested(t *testing.T, db *sql.DB) {
 		testRetrieveMany(o, t, mock.PeopleObjectType)
 	})
 
+	t.Run("RetrieveManyQuery", func(t *testing.T) {
+		// test non-equality operators against the same two rows
+		testRetrieveManyQuery(o, t, mock.PeopleObjectType)
+	})
+
+	t.Run("RetrieveManyOptions", func(t *testing.T) {
+		// test ORDER BY and LIMIT / OFFSET against the same two rows
+		testRetrieveManyOptions(o, t, mock.PeopleObjectType)
+	})
+
+	t.Run("RetrieveManyOrderBy", func(t *testing.T) {
+		// test NULLS FIRST / LAST and the schema's DefaultOrder
+		testRetrieveManyOrderBy(o, t, sch, mock.PeopleObjectType)
+	})
+
+	t.Run("RetrievePage", func(t *testing.T) {
+		// test keyset pagination over the same two rows
+		testRetrievePage(o, t, mock.PeopleObjectType)
+	})
+
+	t.Run("Iterate", func(t *testing.T) {
+		// test streaming the same two rows
+		testIterate(o, t, mock.PeopleObjectType)
+	})
+
+	t.Run("Aggregate", func(t *testing.T) {
+		// test Count, Exists and aggregates over the same two rows
+		testAggregate(o, t, mock.PeopleObjectType)
+	})
+
+	t.Run("InsertMany", func(t *testing.T) {
+		// test batched inserts, removing the rows afterwards
+		testInsertMany(o, t, mock.PeopleObjectType)
+	})
+
+	t.Run("UpdateDeleteWhere", func(t *testing.T) {
+		// test set-based updates and deletes, removing the rows afterwards
+		testUpdateDeleteWhere(o, t, mock.PeopleObjectType)
+	})
+
+	t.Run("Upsert", func(t *testing.T) {
+		// test native upserts, removing the rows afterwards
+		testUpsert(o, t, mock.PeopleObjectType)
+	})
+
+	t.Run("OptimisticLocking", func(t *testing.T) {
+		// test version checks on update and delete
+		testOptimisticLocking(o, t, mock.PeopleObjectType)
+	})
+
+	t.Run("SoftDelete", func(t *testing.T) {
+		// test soft deletion, retrieval filtering and restoring
+		testSoftDelete(o, t, mock.PeopleObjectType)
+	})
+
+	t.Run("AutoTimestamps", func(t *testing.T) {
+		// test AutoCreateTime and AutoUpdateTime columns
+		testAutoTimestamps(o, t, mock.PeopleObjectType)
+	})
+
+	t.Run("HookRegistry", func(t *testing.T) {
+		// test ordered hooks, and hooks writing inside the transaction
+		testHookRegistry(o, t, mock.PeopleObjectType)
+	})
+
+	t.Run("RetrieveHooks", func(t *testing.T) {
+		// test query rewriting and per-object retrieve hooks
+		testRetrieveHooks(o, t, mock.PeopleObjectType)
+	})
+
+	t.Run("RetrieveWithChildrenDepth", func(t *testing.T) {
+		// test loading every child row, to a depth, without looping on cycles
+		testRetrieveWithChildrenDepth(o, t, mock.PeopleObjectType)
+	})
+
+	t.Run("Preload", func(t *testing.T) {
+		// test batched loading of the children of many parents
+		testPreload(o, t, mock.PeopleObjectType)
+	})
+
+	t.Run("CompositeKeyChildren", func(t *testing.T) {
+		// test saving and loading children related by several columns
+		testCompositeKeyChildren(o, t, mock.PeopleObjectType)
+	})
+
+	t.Run("MultiKeyChildren", func(t *testing.T) {
+		// test relating children through the child table's ForeignKeys
+		testMultiKeyChildren(o, t)
+	})
+
+	t.Run("DeleteAll", func(t *testing.T) {
+		// test deleting object graphs, in memory and loaded
+		testDeleteAll(o, t, mock.PeopleObjectType)
+	})
+
+	t.Run("SyncAll", func(t *testing.T) {
+		// test saving nested objects while removing orphaned children
+		testSyncAll(o, t, mock.PeopleObjectType)
+	})
+
+	t.Run("ManyToMany", func(t *testing.T) {
+		// test linking, retrieving and unlinking through a join table
+		testManyToMany(o, t, mock.PeopleObjectType)
+	})
+
+	t.Run("SchemaDiff", func(t *testing.T) {
+		// test applying the statements rendered for a schema diff
+		testSchemaDiff(o, t)
+	})
+
+	t.Run("Indexes", func(t *testing.T) {
+		// test creating a table with composite, unique and partial indexes
+		testIndexes(o, t)
+	})
+
+	t.Run("ForeignKeys", func(t *testing.T) {
+		// test creating tables with enforced, cascading foreign keys
+		testForeignKeys(o, t)
+	})
+
+	t.Run("Migrate", func(t *testing.T) {
+		// test applying, verifying and reverting migrations
+		testMigrate(o, t)
+	})
+
 	t.Run("FleshenChildren", func(t *testing.T) {
 		// try fleshen children on person id 1
 		testFleshenChildren(o, t, mock.PeopleObjectType)
@@ -282,9 +411,12 @@ func TestSuiteNested(t *testing.T, db *sql.DB) {
 		t.Run("GetLockAndRelease", func(t *testing.T) {
 			testGetLock(o, t)
 		})
-		t.Run("GetLockForceDeadlock", func(t *testing.T) {
-			testForceDeadlock(o, t)
-		})
+		// Only Oracle refuses a lock the session already holds
+		if getSQLGen().IsORACLE {
+			t.Run("GetLockForceDeadlock", func(t *testing.T) {
+				testForceDeadlock(o, t)
+			})
+		}
 	}
 }
 
@@ -316,8 +448,16 @@ func testForceDeadlock(o *orm.ORM, t *testing.T) {
 
 func testGetLock(o *orm.ORM, t *testing.T) {
 	lockStr := "LockStringTest1"
+	// Locks belong to the session, so take and release this one on the
+	// same connection
+	tx, err := o.RawConn.BeginTx(context.Background(), nil)
+	fatalIf(err)
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
 	ctx, cancel := getLongContext()
-	locked, err := o.GetLock(ctx, nil, lockStr)
+	locked, err := o.GetLock(ctx, tx, lockStr)
 	if err != nil {
 		t.Fatalf("Unable to GetLock, err = %s", err.Error())
 	}
@@ -328,7 +468,7 @@ func testGetLock(o *orm.ORM, t *testing.T) {
 	fatalIf(err)
 
 	ctx, cancel = getLongContext()
-	unlocked, err := o.ReleaseLock(ctx, nil, lockStr)
+	unlocked, err := o.ReleaseLock(ctx, tx, lockStr)
 	if err != nil {
 		t.Fatalf("Unable to ReleaseLock, err = %s", err.Error())
 	}
@@ -518,6 +658,1969 @@ func testRetrieveMany(o *orm.ORM, t *testing.T, rootTable string) {
 	}
 }
 
+func testRetrieveManyQuery(o *orm.ORM, t *testing.T, rootTable string) {
+	cases := []struct {
+		name     string
+		q        *query.Query
+		expected int
+	}{
+		{"Like", query.Like("Name", "J%"), 2},
+		{"Ne", query.Ne(ColPersonID, globalPersonID), 1},
+		{"Gt", query.Gt(ColPersonID, globalPersonID), 1},
+		{"In", query.In(ColPersonID, globalPersonID, int64(-1)), 1},
+		{"EmptyIn", query.In(ColPersonID), 0},
+		{"Between", query.Between(ColPersonID, globalPersonID, globalPersonID), 1},
+		{"IsNull", query.IsNull("NullInt"), 2},
+		{"Not", query.Not(query.Eq(ColPersonID, globalPersonID)), 1},
+		{"Or", query.Or(query.Eq(ColPersonID, globalPersonID), query.Eq("Name", "Nobody")), 1},
+		{"And", query.And(query.Eq("Name", "Joe"), query.Le(ColPersonID, globalPersonID)), 1},
+	}
+
+	for _, c := range cases {
+		ctx, cancel := getDefaultContext()
+		objs, err := o.RetrieveManyQuery(ctx, rootTable, c.q, nil)
+		cancel()
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err.Error())
+		}
+		if len(objs) != c.expected {
+			t.Fatalf("%s: expected %d rows, got %d", c.name, c.expected, len(objs))
+		}
+	}
+}
+
+// retrieveJoeIDs returns the IDs of the people named Joe, retrieved with
+// opts. name identifies the retrieval in failures.
+func retrieveJoeIDs(o *orm.ORM, t *testing.T, rootTable string, name string, opts *query.Options) []int64 {
+	ctx, cancel := getDefaultContext()
+	objs, err := o.RetrieveManyQuery(ctx, rootTable, query.Eq("Name", "Joe"), opts)
+	cancel()
+	if err != nil {
+		t.Fatalf("%s: %s", name, err.Error())
+	}
+	ids := make([]int64, len(objs))
+	for i, obj := range objs {
+		ids[i] = obj.Get(ColPersonID).(int64)
+	}
+	return ids
+}
+
+func testRetrieveManyOptions(o *orm.ORM, t *testing.T, rootTable string) {
+	retrieveIDs := func(name string, opts *query.Options) []int64 {
+		return retrieveJoeIDs(o, t, rootTable, name, opts)
+	}
+
+	asc := retrieveIDs("Asc", &query.Options{OrderBy: []query.Order{query.Asc(ColPersonID)}})
+	if len(asc) != 2 || asc[0] != globalPersonID || asc[1] <= asc[0] {
+		t.Fatalf("Asc: unexpected ordering %v", asc)
+	}
+	desc := retrieveIDs("Desc", &query.Options{OrderBy: []query.Order{query.Desc(ColPersonID)}})
+	if len(desc) != 2 || desc[0] != asc[1] || desc[1] != asc[0] {
+		t.Fatalf("Desc: unexpected ordering %v", desc)
+	}
+
+	cases := []struct {
+		name     string
+		opts     *query.Options
+		expected []int64
+	}{
+		{"Limit", &query.Options{OrderBy: []query.Order{query.Asc(ColPersonID)}, Limit: 1}, asc[:1]},
+		{"LimitOffset", &query.Options{OrderBy: []query.Order{query.Asc(ColPersonID)}, Limit: 1, Offset: 1}, asc[1:]},
+		{"OffsetOnly", &query.Options{OrderBy: []query.Order{query.Desc(ColPersonID)}, Offset: 1}, desc[1:]},
+		{"OffsetPastEnd", &query.Options{OrderBy: []query.Order{query.Asc(ColPersonID)}, Limit: 1, Offset: 2}, []int64{}},
+		{"UnorderedLimit", &query.Options{Limit: 1}, nil},
+	}
+	for _, c := range cases {
+		ids := retrieveIDs(c.name, c.opts)
+		if c.expected == nil {
+			if len(ids) != 1 {
+				t.Fatalf("%s: expected 1 row, got %d", c.name, len(ids))
+			}
+			continue
+		}
+		if !reflect.DeepEqual(ids, c.expected) {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.expected, ids)
+		}
+	}
+
+	ctx, cancel := getDefaultContext()
+	_, err := o.RetrieveManyQuery(ctx, rootTable, nil, &query.Options{OrderBy: []query.Order{query.Asc("NoSuchColumn")}})
+	cancel()
+	if err == nil {
+		t.Fatal("expected an error ordering by an unknown column")
+	}
+}
+
+func testRetrieveManyOrderBy(o *orm.ORM, t *testing.T, sch *schema.Schema, rootTable string) {
+	retrieveIDs := func(name string, opts *query.Options) []int64 {
+		return retrieveJoeIDs(o, t, rootTable, name, opts)
+	}
+
+	asc := retrieveIDs("Asc", &query.Options{OrderBy: []query.Order{query.Asc(ColPersonID)}})
+	if len(asc) != 2 {
+		t.Fatalf("Asc: expected 2 rows, got %d", len(asc))
+	}
+	desc := []int64{asc[1], asc[0]}
+
+	// NullInt is NULL for both rows, so the tiebreaker decides
+	cases := []struct {
+		name     string
+		orders   []query.Order
+		expected []int64
+	}{
+		{"NullsFirst", []query.Order{query.Asc("NullInt").NullsFirst(), query.Desc(ColPersonID)}, desc},
+		{"NullsLast", []query.Order{query.Desc("NullInt").NullsLast(), query.Asc(ColPersonID)}, asc},
+		{"NullsLastTiebreak", []query.Order{query.Asc(ColPersonID).NullsLast()}, asc},
+	}
+	for _, c := range cases {
+		ids := retrieveIDs(c.name, &query.Options{OrderBy: c.orders})
+		if !reflect.DeepEqual(ids, c.expected) {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.expected, ids)
+		}
+	}
+
+	tbl := sch.GetTable(rootTable)
+	tbl.DefaultOrder = []query.Order{query.Desc(ColPersonID)}
+	defer func() {
+		tbl.DefaultOrder = nil
+	}()
+	ids := retrieveIDs("DefaultOrder", nil)
+	if !reflect.DeepEqual(ids, desc) {
+		t.Fatalf("DefaultOrder: expected %v, got %v", desc, ids)
+	}
+	// An explicit ordering takes precedence over the default
+	ids = retrieveIDs("DefaultOrderOverride", &query.Options{OrderBy: []query.Order{query.Asc(ColPersonID)}})
+	if !reflect.DeepEqual(ids, asc) {
+		t.Fatalf("DefaultOrderOverride: expected %v, got %v", asc, ids)
+	}
+}
+
+func testRetrievePage(o *orm.ORM, t *testing.T, rootTable string) {
+	q := query.Eq("Name", "Joe")
+
+	// Walk every page, returning the primary keys seen
+	walk := func(name string, opts *query.Options) []int64 {
+		var ids []int64
+		cursor := ""
+		for pages := 0; ; pages++ {
+			if pages > 2 {
+				t.Fatalf("%s: too many pages", name)
+			}
+			ctx, cancel := getDefaultContext()
+			objs, next, err := o.RetrievePage(ctx, rootTable, q, cursor, opts)
+			cancel()
+			if err != nil {
+				t.Fatalf("%s: %s", name, err.Error())
+			}
+			for _, obj := range objs {
+				ids = append(ids, obj.Get(ColPersonID).(int64))
+			}
+			if next == "" {
+				break
+			}
+			if len(objs) != opts.Limit {
+				t.Fatalf("%s: expected a full page before the last, got %d rows", name, len(objs))
+			}
+			cursor = next
+		}
+		return ids
+	}
+
+	asc := walk("Asc", &query.Options{Limit: 1})
+	if len(asc) != 2 || asc[0] >= asc[1] {
+		t.Fatalf("Asc: unexpected pages %v", asc)
+	}
+	desc := walk("Desc", &query.Options{OrderBy: []query.Order{query.Desc(ColPersonID)}, Limit: 1})
+	if !reflect.DeepEqual(desc, []int64{asc[1], asc[0]}) {
+		t.Fatalf("Desc: unexpected pages %v", desc)
+	}
+	// Name is the same for both rows, so the primary key breaks the tie
+	byName := walk("ByName", &query.Options{OrderBy: []query.Order{query.Asc("Name")}, Limit: 1})
+	if !reflect.DeepEqual(byName, asc) {
+		t.Fatalf("ByName: unexpected pages %v", byName)
+	}
+	all := walk("SinglePage", &query.Options{Limit: 10})
+	if !reflect.DeepEqual(all, asc) {
+		t.Fatalf("SinglePage: unexpected pages %v", all)
+	}
+
+	// A cursor is only valid for the ordering that produced it
+	ctx, cancel := getDefaultContext()
+	_, next, err := o.RetrievePage(ctx, rootTable, q, "", &query.Options{Limit: 1})
+	cancel()
+	fatalIf(err)
+	ctx, cancel = getDefaultContext()
+	_, _, err = o.RetrievePage(ctx, rootTable, q, next, &query.Options{OrderBy: []query.Order{query.Desc(ColPersonID)}, Limit: 1})
+	cancel()
+	if errors.Cause(err) != orm.ErrBadCursor {
+		t.Fatalf("expected ErrBadCursor, got %v", err)
+	}
+	ctx, cancel = getDefaultContext()
+	_, _, err = o.RetrievePage(ctx, rootTable, q, "not-a-cursor", nil)
+	cancel()
+	if errors.Cause(err) != orm.ErrBadCursor {
+		t.Fatalf("expected ErrBadCursor, got %v", err)
+	}
+
+	// Nullable columns can't be used as keyset columns
+	ctx, cancel = getDefaultContext()
+	_, _, err = o.RetrievePage(ctx, rootTable, q, "", &query.Options{OrderBy: []query.Order{query.Asc("NullInt")}})
+	cancel()
+	if err == nil {
+		t.Fatal("expected an error paging on a nullable column")
+	}
+}
+
+func testIterate(o *orm.ORM, t *testing.T, rootTable string) {
+	q := query.Eq("Name", "Joe")
+	opts := &query.Options{OrderBy: []query.Order{query.Asc(ColPersonID)}}
+
+	ctx, cancel := getDefaultContext()
+	expected, err := o.RetrieveManyQuery(ctx, rootTable, q, opts)
+	cancel()
+	fatalIf(err)
+	if len(expected) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(expected))
+	}
+
+	ctx, cancel = getDefaultContext()
+	defer cancel()
+	it, err := o.Iterate(ctx, nil, rootTable, q, opts)
+	fatalIf(err)
+	var got object.Array
+	for it.Next() {
+		got = append(got, it.Object())
+	}
+	fatalIf(it.Err())
+	fatalIf(it.Close())
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatal("Iterate: objects differ from RetrieveManyQuery")
+	}
+	if it.Next() {
+		t.Fatal("Iterate: Next should return false once closed")
+	}
+
+	// ForEach, stopping after the first object
+	count := 0
+	err = o.ForEach(ctx, nil, rootTable, q, opts, func(obj *object.Object) error {
+		count++
+		if obj.Get(ColPersonID) != expected[0].Get(ColPersonID) {
+			t.Fatal("ForEach: unexpected first object")
+		}
+		return orm.ErrStopIteration
+	})
+	fatalIf(err)
+	if count != 1 {
+		t.Fatalf("ForEach: expected 1 callback, got %d", count)
+	}
+
+	// Callback errors are passed back to the caller
+	errBoom := errors.New("boom")
+	err = o.ForEach(ctx, nil, rootTable, q, opts, func(obj *object.Object) error {
+		return errBoom
+	})
+	if err != errBoom {
+		t.Fatalf("ForEach: expected callback error, got %v", err)
+	}
+}
+
+func testAggregate(o *orm.ORM, t *testing.T, rootTable string) {
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+
+	joe := query.Eq("Name", "Joe")
+	nobody := query.Eq("Name", "Nobody")
+
+	objs, err := o.RetrieveManyQuery(ctx, rootTable, joe, &query.Options{OrderBy: []query.Order{query.Asc(ColPersonID)}})
+	fatalIf(err)
+	if len(objs) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(objs))
+	}
+	minID := objs[0].Get(ColPersonID).(int64)
+	maxID := objs[1].Get(ColPersonID).(int64)
+
+	n, err := o.Count(ctx, nil, rootTable, joe)
+	fatalIf(err)
+	if n != 2 {
+		t.Fatalf("Count: expected 2, got %d", n)
+	}
+	n, err = o.Count(ctx, nil, rootTable, nobody)
+	fatalIf(err)
+	if n != 0 {
+		t.Fatalf("Count: expected 0, got %d", n)
+	}
+
+	exists, err := o.Exists(ctx, nil, rootTable, joe)
+	fatalIf(err)
+	if !exists {
+		t.Fatal("Exists: expected true")
+	}
+	exists, err = o.Exists(ctx, nil, rootTable, nobody)
+	fatalIf(err)
+	if exists {
+		t.Fatal("Exists: expected false")
+	}
+
+	v, err := o.Min(ctx, nil, rootTable, joe, ColPersonID)
+	fatalIf(err)
+	if v != minID {
+		t.Fatalf("Min: expected %d, got %v", minID, v)
+	}
+	v, err = o.Max(ctx, nil, rootTable, joe, ColPersonID)
+	fatalIf(err)
+	if v != maxID {
+		t.Fatalf("Max: expected %d, got %v", maxID, v)
+	}
+	v, err = o.Sum(ctx, nil, rootTable, joe, ColPersonID)
+	fatalIf(err)
+	if v != minID+maxID {
+		t.Fatalf("Sum: expected %d, got %v", minID+maxID, v)
+	}
+	v, err = o.Max(ctx, nil, rootTable, joe, "NullInt")
+	fatalIf(err)
+	if v != nil {
+		t.Fatalf("Max: expected nil for all-NULL column, got %v", v)
+	}
+
+	groups, err := o.Aggregate(ctx, nil, rootTable, nil, []string{"Name"}, []query.Aggregate{query.Count("n")}, nil)
+	fatalIf(err)
+	if len(groups) != 1 || groups[0].Get("Name") != "Joe" || groups[0].Get("n") != int64(2) {
+		t.Fatalf("Aggregate: unexpected groups %v", groups)
+	}
+
+	groups, err = o.Aggregate(ctx, nil, rootTable, joe, []string{ColPersonID},
+		[]query.Aggregate{query.Count("n"), query.Max("Name", "maxname")},
+		&query.Options{OrderBy: []query.Order{query.Desc("n"), query.Desc(ColPersonID)}, Limit: 1})
+	fatalIf(err)
+	if len(groups) != 1 || groups[0].Get(ColPersonID) != maxID || groups[0].Get("n") != int64(1) || groups[0].Get("maxname") != "Joe" {
+		t.Fatalf("Aggregate: unexpected groups %v", groups)
+	}
+
+	_, err = o.Aggregate(ctx, nil, rootTable, nil, nil, []query.Aggregate{query.Count("bad alias")}, nil)
+	if err == nil {
+		t.Fatal("Aggregate: expected an error for an invalid alias")
+	}
+}
+
+func testInsertMany(o *orm.ORM, t *testing.T, rootTable string) {
+	var objs object.Array
+	for i := 0; i < 5; i++ {
+		obj := object.New(rootTable)
+		obj.Set("Name", "Bulk")
+		objs = append(objs, obj)
+	}
+	// A different column set must go in a batch of it's own
+	objs[3].Set("NullInt", int64(42))
+
+	created := 0
+	o.BeforeCreateHooks[rootTable] = func(_ *schema.Schema, obj *object.Object) error {
+		created++
+		return nil
+	}
+	o.InsertBatchSize = 2
+	defer func() {
+		delete(o.BeforeCreateHooks, rootTable)
+		o.InsertBatchSize = 0
+	}()
+
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+	rowsAff, err := o.InsertMany(ctx, nil, objs)
+	fatalIf(err)
+	if rowsAff != int64(len(objs)) {
+		t.Fatalf("InsertMany: expected %d rows affected, got %d", len(objs), rowsAff)
+	}
+	if created != len(objs) {
+		t.Fatalf("InsertMany: expected %d create hook calls, got %d", len(objs), created)
+	}
+
+	n, err := o.Count(ctx, nil, rootTable, query.Eq("Name", "Bulk"))
+	fatalIf(err)
+	if n != int64(len(objs)) {
+		t.Fatalf("InsertMany: expected %d rows, found %d", len(objs), n)
+	}
+
+	if !o.GetSQLGenerator().IsORACLE {
+		seen := make(map[int64]bool)
+		for i, obj := range objs {
+			id, ok := obj.Get(ColPersonID).(int64)
+			if !ok || seen[id] {
+				t.Fatalf("InsertMany: object %d has a missing or duplicate primary key", i)
+			}
+			seen[id] = true
+			if obj.IsDirty() {
+				t.Fatalf("InsertMany: object %d should not be dirty", i)
+			}
+
+			found, err := o.Retrieve(ctx, rootTable, map[string]interface{}{ColPersonID: id})
+			fatalIf(err)
+			if found == nil {
+				t.Fatalf("InsertMany: object %d was not found by it's primary key", i)
+			}
+			name, err := found.GetStringAlways("Name")
+			fatalIf(err)
+			if name != "Bulk" {
+				t.Fatalf("InsertMany: object %d has unexpected Name %s", i, name)
+			}
+			nullInt := found.Get("NullInt")
+			if (i == 3) != (nullInt == int64(42)) {
+				t.Fatalf("InsertMany: object %d has unexpected NullInt %v", i, nullInt)
+			}
+		}
+	}
+
+	// Supplied keys are kept, with an explicit identity value, which not
+	// every database allows
+	sg := o.GetSQLGenerator()
+	if sg.IsSQLITE || sg.IsMYSQL || sg.IsPOSTGRES {
+		supplied := object.Array{object.New(rootTable), object.New(rootTable)}
+		for i, id := range []int64{2000005, 2000001} {
+			supplied[i].Set(ColPersonID, id)
+			supplied[i].Set("Name", "Bulk")
+		}
+		_, err = o.InsertMany(ctx, nil, supplied)
+		fatalIf(err)
+		if supplied[0].Get(ColPersonID) != int64(2000005) || supplied[1].Get(ColPersonID) != int64(2000001) {
+			t.Fatalf("InsertMany: expected the supplied keys to be kept, got %v and %v", supplied[0].Get(ColPersonID), supplied[1].Get(ColPersonID))
+		}
+	}
+
+	// A single statement can't mix supplied and generated keys
+	mixed := object.Array{object.New(rootTable), object.New(rootTable)}
+	mixed[0].Set(ColPersonID, int64(2000010))
+	mixed[1].Set(ColPersonID, object.NewNULLValue())
+	for _, obj := range mixed {
+		obj.Set("Name", "Bulk")
+	}
+	if _, err := o.InsertMany(ctx, nil, mixed); err == nil {
+		t.Fatal("InsertMany: expected an error for a batch mixing supplied and generated keys")
+	}
+
+	bulk, err := o.RetrieveManyQuery(ctx, rootTable, query.Eq("Name", "Bulk"), nil)
+	fatalIf(err)
+	for _, obj := range bulk {
+		pk := object.New(rootTable)
+		pk.KV[ColPersonID] = obj.KV[ColPersonID]
+		_, err := o.Delete(ctx, nil, pk)
+		fatalIf(err)
+	}
+}
+
+func testUpdateDeleteWhere(o *orm.ORM, t *testing.T, rootTable string) {
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+
+	var objs object.Array
+	for i := 0; i < 3; i++ {
+		obj := object.New(rootTable)
+		obj.Set("Name", "SetBased")
+		obj.Set("NullInt", int64(i))
+		objs = append(objs, obj)
+	}
+	_, err := o.InsertMany(ctx, nil, objs)
+	fatalIf(err)
+
+	setBased := query.Eq("Name", "SetBased")
+
+	rowsAff, err := o.UpdateWhere(ctx, nil, rootTable, map[string]interface{}{"NullInt": int64(7)}, query.And(setBased, query.Ge("NullInt", int64(1))))
+	fatalIf(err)
+	if rowsAff != 2 {
+		t.Fatalf("UpdateWhere: expected 2 rows affected, got %d", rowsAff)
+	}
+	n, err := o.Count(ctx, nil, rootTable, query.And(setBased, query.Eq("NullInt", int64(7))))
+	fatalIf(err)
+	if n != 2 {
+		t.Fatalf("UpdateWhere: expected 2 updated rows, found %d", n)
+	}
+
+	rowsAff, err = o.UpdateWhere(ctx, nil, rootTable, map[string]interface{}{"NullInt": nil}, query.And(setBased, query.Eq("NullInt", int64(0))))
+	fatalIf(err)
+	if rowsAff != 1 {
+		t.Fatalf("UpdateWhere: expected 1 row set to NULL, got %d", rowsAff)
+	}
+
+	// Empty filters are refused
+	_, err = o.UpdateWhere(ctx, nil, rootTable, map[string]interface{}{"NullInt": int64(1)}, nil)
+	if err != orm.ErrEmptyFilter {
+		t.Fatalf("UpdateWhere: expected ErrEmptyFilter, got %v", err)
+	}
+	_, err = o.DeleteWhere(ctx, nil, rootTable, query.And())
+	if err != orm.ErrEmptyFilter {
+		t.Fatalf("DeleteWhere: expected ErrEmptyFilter, got %v", err)
+	}
+	_, err = o.Delete(ctx, nil, object.New(rootTable))
+	if err != orm.ErrEmptyFilter {
+		t.Fatalf("Delete: expected ErrEmptyFilter, got %v", err)
+	}
+
+	rowsAff, err = o.DeleteWhere(ctx, nil, rootTable, query.And(setBased, query.IsNull("NullInt")))
+	fatalIf(err)
+	if rowsAff != 1 {
+		t.Fatalf("DeleteWhere: expected 1 row affected, got %d", rowsAff)
+	}
+	rowsAff, err = o.DeleteWhere(ctx, nil, rootTable, setBased)
+	fatalIf(err)
+	if rowsAff != 2 {
+		t.Fatalf("DeleteWhere: expected 2 rows affected, got %d", rowsAff)
+	}
+}
+
+func testUpsert(o *orm.ORM, t *testing.T, rootTable string) {
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+
+	obj := object.New(rootTable)
+	obj.Set("Name", "UpsertOld")
+	_, err := o.Insert(ctx, nil, obj)
+	fatalIf(err)
+
+	// Update path: the row already exists
+	upd := object.New(rootTable)
+	upd.Set("PersonID", obj.Get("PersonID"))
+	upd.Set("Name", "Upserted")
+	upd.Set("NullInt", int64(5))
+	_, err = o.Upsert(ctx, nil, upd, []string{"PersonID"})
+	fatalIf(err)
+	if upd.IsDirty() {
+		t.Fatal("Upsert: object should not be dirty")
+	}
+	n, err := o.Count(ctx, nil, rootTable, query.And(query.Eq("Name", "Upserted"), query.Eq("NullInt", int64(5))))
+	fatalIf(err)
+	if n != 1 {
+		t.Fatalf("Upsert: expected 1 updated row, found %d", n)
+	}
+	n, err = o.Count(ctx, nil, rootTable, query.Eq("Name", "UpsertOld"))
+	fatalIf(err)
+	if n != 0 {
+		t.Fatalf("Upsert: expected no rows with the old name, found %d", n)
+	}
+
+	// Insert path, with an explicit identity value, which not every
+	// database allows.
+	sg := o.GetSQLGenerator()
+	if sg.IsSQLITE || sg.IsMYSQL || sg.IsPOSTGRES {
+		ins := object.New(rootTable)
+		ins.Set("PersonID", int64(1000000))
+		ins.Set("Name", "Upserted")
+		_, err = o.Upsert(ctx, nil, ins, []string{"PersonID"})
+		fatalIf(err)
+		n, err = o.Count(ctx, nil, rootTable, query.Eq("Name", "Upserted"))
+		fatalIf(err)
+		if n != 2 {
+			t.Fatalf("Upsert: expected 2 rows after insert, found %d", n)
+		}
+	}
+
+	// Conflict columns must be known, and have a value
+	_, err = o.Upsert(ctx, nil, upd, []string{"NoSuchColumn"})
+	if err == nil {
+		t.Fatal("Upsert: expected an error for an unknown conflict column")
+	}
+	_, err = o.Upsert(ctx, nil, upd, []string{"NullFloat"})
+	if err == nil {
+		t.Fatal("Upsert: expected an error for a conflict column without a value")
+	}
+
+	_, err = o.DeleteWhere(ctx, nil, rootTable, query.Eq("Name", "Upserted"))
+	fatalIf(err)
+
+	testUpsertSoftDeleted(o, t)
+}
+
+// testUpsertSoftDeleted checks that Upsert restores a soft deleted row it
+// conflicts with, and refuses versioned tables, using tables of its own.
+func testUpsertSoftDeleted(o *orm.ORM, t *testing.T) {
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+
+	usORM, drop := newParentChildORM(t, o, "us", func(parent *schema.Table, child *schema.Table) {
+		parent.Columns["Name"].IsUnique = true
+		parent.SoftDeleteColumn = "DeletedAt"
+		parent.Columns["DeletedAt"] = &schema.Column{Name: "DeletedAt", DBType: "timestamp", AllowNull: true}
+		parent.Columns["Version"] = &schema.Column{Name: "Version", DBType: "integer", IsNumber: true, AllowNull: true}
+	})
+	defer drop()
+
+	p := object.New("us_parent")
+	p.Set("Name", "deleted")
+	_, err := usORM.Insert(ctx, nil, p)
+	fatalIf(err)
+	_, err = usORM.Delete(ctx, nil, p)
+	fatalIf(err)
+
+	// The key is read back although the row was soft deleted
+	ups := object.New("us_parent")
+	ups.Set("Name", "deleted")
+	_, err = usORM.Upsert(ctx, nil, ups, []string{"Name"})
+	fatalIf(err)
+	if ups.Get("ParentID") != p.Get("ParentID") {
+		t.Fatalf("Upsert: expected the key %v of the soft deleted row, got %v", p.Get("ParentID"), ups.Get("ParentID"))
+	}
+	found, err := usORM.Retrieve(ctx, "us_parent", map[string]interface{}{"ParentID": p.Get("ParentID")})
+	fatalIf(err)
+	if found == nil {
+		t.Fatal("Upsert: expected the soft deleted row to be restored")
+	}
+	n, err := usORM.Count(ctx, nil, "us_parent", query.All())
+	fatalIf(err)
+	if n != 1 {
+		t.Fatalf("Upsert: expected 1 row, got %d", n)
+	}
+
+	tbl := usORM.GetSchema().GetTable("us_parent")
+	tbl.VersionColumn = "Version"
+	ver := object.New("us_parent")
+	ver.Set("Name", "deleted")
+	if _, err := usORM.Upsert(ctx, nil, ver, []string{"Name"}); err == nil {
+		t.Fatal("Upsert: expected an error for a table with a VersionColumn")
+	}
+	tbl.VersionColumn = ""
+}
+
+func testOptimisticLocking(o *orm.ORM, t *testing.T, rootTable string) {
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+
+	// Borrow NullInt as the version column
+	tbl := o.GetSchema().GetTable(rootTable)
+	tbl.VersionColumn = "NullInt"
+	defer func() {
+		tbl.VersionColumn = ""
+	}()
+
+	obj := object.New(rootTable)
+	obj.Set("Name", "Versioned")
+	_, err := o.Insert(ctx, nil, obj)
+	fatalIf(err)
+	if v := obj.Get("NullInt"); v != int64(1) {
+		t.Fatalf("Insert: expected version 1, got %v", v)
+	}
+
+	pk := map[string]interface{}{"PersonID": obj.Get("PersonID")}
+	a, err := o.Retrieve(ctx, rootTable, pk)
+	fatalIf(err)
+	b, err := o.Retrieve(ctx, rootTable, pk)
+	fatalIf(err)
+
+	a.Set("Name", "VersionedA")
+	_, err = o.Update(ctx, nil, a)
+	fatalIf(err)
+	if v, _ := a.GetIntAlways("NullInt"); v != 2 {
+		t.Fatalf("Update: expected version 2, got %v", a.Get("NullInt"))
+	}
+
+	// b was retrieved before a was saved
+	b.Set("Name", "VersionedB")
+	_, err = o.SaveAll(ctx, b)
+	if err != orm.ErrStaleObject {
+		t.Fatalf("SaveAll: expected ErrStaleObject, got %v", err)
+	}
+	if v, _ := b.GetIntAlways("NullInt"); v != 1 {
+		t.Fatalf("SaveAll: expected the stale version to be kept, got %v", b.Get("NullInt"))
+	}
+	name, err := b.GetStringAlways("Name")
+	fatalIf(err)
+	if name != "VersionedB" {
+		t.Fatalf("SaveAll: expected the stale object to keep its changes, got %s", name)
+	}
+
+	stale := object.New(rootTable)
+	stale.Set("PersonID", obj.Get("PersonID"))
+	stale.Set("NullInt", int64(1))
+	_, err = o.Delete(ctx, nil, stale)
+	if err != orm.ErrStaleObject {
+		t.Fatalf("Delete: expected ErrStaleObject, got %v", err)
+	}
+
+	current := object.New(rootTable)
+	current.Set("PersonID", obj.Get("PersonID"))
+	current.Set("NullInt", a.Get("NullInt"))
+	_, err = o.Delete(ctx, nil, current)
+	fatalIf(err)
+}
+
+func testSoftDelete(o *orm.ORM, t *testing.T, rootTable string) {
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+
+	// Borrow NullTimestamp as the soft delete column
+	tbl := o.GetSchema().GetTable(rootTable)
+	tbl.SoftDeleteColumn = "NullTimestamp"
+	defer func() {
+		tbl.SoftDeleteColumn = ""
+	}()
+
+	obj := object.New(rootTable)
+	obj.Set("Name", "SoftDeleted")
+	_, err := o.Insert(ctx, nil, obj)
+	fatalIf(err)
+	pk := map[string]interface{}{"PersonID": obj.Get("PersonID")}
+
+	del := object.New(rootTable)
+	del.Set("PersonID", obj.Get("PersonID"))
+	_, err = o.Delete(ctx, nil, del)
+	fatalIf(err)
+	if _, ok := del.Get("NullTimestamp").(time.Time); !ok {
+		t.Fatalf("Delete: expected the deletion time to be set, got %v", del.Get("NullTimestamp"))
+	}
+	// Deleting again finds nothing
+	_, err = o.Delete(ctx, nil, del)
+	if err != orm.ErrNoResult {
+		t.Fatalf("Delete: expected ErrNoResult, got %v", err)
+	}
+
+	found, err := o.Retrieve(ctx, rootTable, pk)
+	fatalIf(err)
+	if found != nil {
+		t.Fatal("Retrieve: soft deleted row should not be found")
+	}
+	n, err := o.Count(ctx, nil, rootTable, query.Eq("Name", "SoftDeleted"))
+	fatalIf(err)
+	if n != 0 {
+		t.Fatalf("Count: expected soft deleted row to be left out, got %d", n)
+	}
+
+	softDeleted := query.Eq("Name", "SoftDeleted")
+	for _, tc := range []struct {
+		deleted query.Deleted
+		want    int
+	}{
+		{query.ExcludeDeleted, 0},
+		{query.WithDeleted, 1},
+		{query.OnlyDeleted, 1},
+	} {
+		objs, err := o.RetrieveManyQuery(ctx, rootTable, softDeleted, &query.Options{Deleted: tc.deleted})
+		fatalIf(err)
+		if len(objs) != tc.want {
+			t.Fatalf("RetrieveManyQuery: expected %d rows for Deleted=%d, got %d", tc.want, tc.deleted, len(objs))
+		}
+	}
+	objs, err := o.RetrieveManyQuery(ctx, rootTable, query.Like("Name", "Joe%"), &query.Options{Deleted: query.OnlyDeleted})
+	fatalIf(err)
+	if len(objs) != 0 {
+		t.Fatalf("RetrieveManyQuery: expected no deleted rows for Joe, got %d", len(objs))
+	}
+
+	_, err = o.Restore(ctx, nil, del)
+	fatalIf(err)
+	found, err = o.Retrieve(ctx, rootTable, pk)
+	fatalIf(err)
+	if found == nil {
+		t.Fatal("Restore: restored row should be found")
+	}
+	_, err = o.Restore(ctx, nil, del)
+	if err != orm.ErrNoResult {
+		t.Fatalf("Restore: expected ErrNoResult for a row that isn't deleted, got %v", err)
+	}
+
+	// DeleteWhere removes rows outright
+	rowsAff, err := o.DeleteWhere(ctx, nil, rootTable, softDeleted)
+	fatalIf(err)
+	if rowsAff != 1 {
+		t.Fatalf("DeleteWhere: expected 1 row affected, got %d", rowsAff)
+	}
+
+	// Soft deletes check and advance the version, as updates do
+	tbl.VersionColumn = "NullInt"
+	defer func() {
+		tbl.VersionColumn = ""
+	}()
+	versioned := object.New(rootTable)
+	versioned.Set("Name", "SoftDeleted")
+	_, err = o.Insert(ctx, nil, versioned)
+	fatalIf(err)
+	stale := object.New(rootTable)
+	stale.Set("PersonID", versioned.Get("PersonID"))
+	stale.Set("NullInt", int64(0))
+	_, err = o.Delete(ctx, nil, stale)
+	if err != orm.ErrStaleObject {
+		t.Fatalf("Delete: expected ErrStaleObject for a stale version, got %v", err)
+	}
+	current := object.New(rootTable)
+	current.Set("PersonID", versioned.Get("PersonID"))
+	current.Set("NullInt", versioned.Get("NullInt"))
+	_, err = o.Delete(ctx, nil, current)
+	fatalIf(err)
+	if v := current.Get("NullInt"); v != int64(2) {
+		t.Fatalf("Delete: expected the version to advance to 2, got %v", v)
+	}
+	objs, err = o.RetrieveManyQuery(ctx, rootTable, query.And(softDeleted, query.Eq("NullInt", 2)), &query.Options{Deleted: query.OnlyDeleted})
+	fatalIf(err)
+	if len(objs) != 1 {
+		t.Fatalf("Delete: expected the stored version to advance, got %d rows", len(objs))
+	}
+	_, err = o.DeleteWhere(ctx, nil, rootTable, softDeleted)
+	fatalIf(err)
+}
+
+func testAutoTimestamps(o *orm.ORM, t *testing.T, rootTable string) {
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+
+	// Borrow NullTimestamp as the timestamp column
+	col := o.GetSchema().GetTable(rootTable).GetColumn("NullTimestamp")
+	defer func() {
+		col.AutoCreateTime = false
+		col.AutoUpdateTime = false
+	}()
+	stamped := query.And(query.Eq("Name", "Stamped"), query.IsNotNull("NullTimestamp"))
+
+	col.AutoCreateTime = true
+	obj := object.New(rootTable)
+	obj.Set("Name", "Stamped")
+	obj.Set("NullTimestamp", object.NewNULLValue())
+	_, err := o.Insert(ctx, nil, obj)
+	fatalIf(err)
+	if _, ok := obj.Get("NullTimestamp").(time.Time); !ok {
+		t.Fatalf("Insert: expected a creation time, got %v", obj.Get("NullTimestamp"))
+	}
+	n, err := o.Count(ctx, nil, rootTable, stamped)
+	fatalIf(err)
+	if n != 1 {
+		t.Fatalf("Insert: expected 1 stamped row, got %d", n)
+	}
+
+	// Creation times are left alone by updates
+	obj.Set("NullInt", int64(1))
+	_, err = o.Update(ctx, nil, obj)
+	fatalIf(err)
+	_, err = o.UpdateWhere(ctx, nil, rootTable, map[string]interface{}{"NullTimestamp": nil}, query.Eq("Name", "Stamped"))
+	fatalIf(err)
+
+	col.AutoCreateTime = false
+	col.AutoUpdateTime = true
+
+	found, err := o.Retrieve(ctx, rootTable, map[string]interface{}{"PersonID": obj.Get("PersonID")})
+	fatalIf(err)
+	found.Set("NullInt", int64(2))
+	_, err = o.Update(ctx, nil, found)
+	fatalIf(err)
+	if _, ok := found.Get("NullTimestamp").(time.Time); !ok {
+		t.Fatalf("Update: expected an update time, got %v", found.Get("NullTimestamp"))
+	}
+	n, err = o.Count(ctx, nil, rootTable, stamped)
+	fatalIf(err)
+	if n != 1 {
+		t.Fatalf("Update: expected 1 stamped row, got %d", n)
+	}
+
+	_, err = o.UpdateWhere(ctx, nil, rootTable, map[string]interface{}{"NullTimestamp": nil}, query.Eq("Name", "Stamped"))
+	fatalIf(err)
+	_, err = o.UpdateWhere(ctx, nil, rootTable, map[string]interface{}{"NullInt": int64(3)}, query.Eq("Name", "Stamped"))
+	fatalIf(err)
+	n, err = o.Count(ctx, nil, rootTable, stamped)
+	fatalIf(err)
+	if n != 1 {
+		t.Fatalf("UpdateWhere: expected 1 stamped row, got %d", n)
+	}
+
+	// Upsert stamps the update time even when the object has one already
+	old := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	upsert := object.New(rootTable)
+	upsert.Set("PersonID", obj.Get("PersonID"))
+	upsert.Set("Name", "Stamped")
+	upsert.Set("NullTimestamp", old)
+	_, err = o.Upsert(ctx, nil, upsert, []string{"PersonID"})
+	fatalIf(err)
+	if ts, ok := upsert.Get("NullTimestamp").(time.Time); !ok || !ts.After(old) {
+		t.Fatalf("Upsert: expected a new update time, got %v", upsert.Get("NullTimestamp"))
+	}
+	n, err = o.Count(ctx, nil, rootTable, query.And(query.Eq("Name", "Stamped"), query.Gt("NullTimestamp", old)))
+	fatalIf(err)
+	if n != 1 {
+		t.Fatalf("Upsert: expected 1 row stamped after %v, got %d", old, n)
+	}
+
+	// DatabaseTime stamps using the database's clock
+	o.DatabaseTime = true
+	defer func() {
+		o.DatabaseTime = false
+	}()
+	_, err = o.UpdateWhere(ctx, nil, rootTable, map[string]interface{}{"NullTimestamp": nil}, query.Eq("Name", "Stamped"))
+	fatalIf(err)
+	found.Set("NullInt", int64(4))
+	_, err = o.Update(ctx, nil, found)
+	fatalIf(err)
+	if _, ok := found.Get("NullTimestamp").(*object.SQLValue); !ok {
+		t.Fatalf("Update: expected the database's time SQL, got %v", found.Get("NullTimestamp"))
+	}
+	n, err = o.Count(ctx, nil, rootTable, stamped)
+	fatalIf(err)
+	if n != 1 {
+		t.Fatalf("Update: expected 1 row stamped by the database, got %d", n)
+	}
+
+	// Soft deletes too
+	tbl := o.GetSchema().GetTable(rootTable)
+	tbl.SoftDeleteColumn = "NullTimestamp"
+	defer func() {
+		tbl.SoftDeleteColumn = ""
+	}()
+	_, err = o.UpdateWhere(ctx, nil, rootTable, map[string]interface{}{"NullTimestamp": nil}, query.Eq("Name", "Stamped"))
+	fatalIf(err)
+	del := object.New(rootTable)
+	del.Set("PersonID", found.Get("PersonID"))
+	_, err = o.Delete(ctx, nil, del)
+	fatalIf(err)
+	if _, ok := del.Get("NullTimestamp").(*object.SQLValue); !ok {
+		t.Fatalf("Delete: expected the database's time SQL, got %v", del.Get("NullTimestamp"))
+	}
+	objs, err := o.RetrieveManyQuery(ctx, rootTable, query.Eq("Name", "Stamped"), &query.Options{Deleted: query.OnlyDeleted})
+	fatalIf(err)
+	if len(objs) != 1 {
+		t.Fatalf("Delete: expected 1 row soft deleted by the database's time, got %d", len(objs))
+	}
+
+	_, err = o.DeleteWhere(ctx, nil, rootTable, query.Eq("Name", "Stamped"))
+	fatalIf(err)
+}
+
+func testHookRegistry(o *orm.ORM, t *testing.T, rootTable string) {
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+
+	var calls []string
+	record := func(name string) orm.Hook {
+		return func(_ context.Context, _ *sql.Tx, event orm.HookEvent, _ *object.Object) error {
+			calls = append(calls, name+"/"+event.String())
+			return nil
+		}
+	}
+	removeFirst := o.RegisterHook(rootTable, orm.BeforeCreate, record("first"))
+	removeAll := o.RegisterHook(orm.AllTables, orm.BeforeCreate, record("all"))
+	removeSecond := o.RegisterHook(rootTable, orm.BeforeCreate, record("second"))
+	removeOther := o.RegisterHook("no-such-table", orm.BeforeCreate, record("other"))
+
+	// Write an audit row in the same transaction as the hooked insert
+	removeAudit := o.RegisterHook(rootTable, orm.AfterCreate, func(ctx context.Context, tx *sql.Tx, _ orm.HookEvent, obj *object.Object) error {
+		if name, _ := obj.GetStringAlways("Name"); name != "Hooked" {
+			return nil
+		}
+		if tx == nil {
+			return errors.New("expected a transaction")
+		}
+		audit := object.New(rootTable)
+		audit.Set("Name", "HookedAudit")
+		_, err := o.Insert(ctx, tx, audit)
+		return err
+	})
+	defer func() {
+		removeFirst()
+		removeAll()
+		removeSecond()
+		removeOther()
+		removeAudit()
+	}()
+
+	hooked := query.In("Name", "Hooked", "HookedAudit")
+	for _, commit := range []bool{false, true} {
+		calls = nil
+		tx, err := o.RawConn.BeginTx(ctx, nil)
+		fatalIf(err)
+		obj := object.New(rootTable)
+		obj.Set("Name", "Hooked")
+		_, err = o.Insert(ctx, tx, obj)
+		fatalIf(err)
+		if commit {
+			fatalIf(tx.Commit())
+		} else {
+			fatalIf(tx.Rollback())
+		}
+
+		// The audit insert runs the BeforeCreate hooks too
+		want := "first/BeforeCreate all/BeforeCreate second/BeforeCreate first/BeforeCreate all/BeforeCreate second/BeforeCreate"
+		if got := strings.Join(calls, " "); got != want {
+			t.Fatalf("RegisterHook: expected calls %q, got %q", want, got)
+		}
+
+		n, err := o.Count(ctx, nil, rootTable, hooked)
+		fatalIf(err)
+		want2 := int64(0)
+		if commit {
+			want2 = 2
+		}
+		if n != want2 {
+			t.Fatalf("RegisterHook: expected %d rows with commit=%v, got %d", want2, commit, n)
+		}
+	}
+
+	// Hooks can be removed, and errors abort the operation
+	removeFirst()
+	removeFail := o.RegisterHook(rootTable, orm.BeforeDelete, func(context.Context, *sql.Tx, orm.HookEvent, *object.Object) error {
+		return errors.New("refused")
+	})
+	del := object.New(rootTable)
+	del.Set("Name", "Hooked")
+	_, err := o.Delete(ctx, nil, del)
+	if err == nil || err.Error() != "refused" {
+		t.Fatalf("RegisterHook: expected the BeforeDelete hook to refuse, got %v", err)
+	}
+	removeFail()
+
+	calls = nil
+	_, err = o.DeleteWhere(ctx, nil, rootTable, hooked)
+	fatalIf(err)
+	obj := object.New(rootTable)
+	obj.Set("Name", "HookedLater")
+	_, err = o.Insert(ctx, nil, obj)
+	fatalIf(err)
+	if got := strings.Join(calls, " "); got != "all/BeforeCreate second/BeforeCreate" {
+		t.Fatalf("RegisterHook: unexpected calls after removing a hook: %q", got)
+	}
+	_, err = o.DeleteWhere(ctx, nil, rootTable, query.Eq("Name", "HookedLater"))
+	fatalIf(err)
+}
+
+func testRetrieveHooks(o *orm.ORM, t *testing.T, rootTable string) {
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+
+	other := object.New(rootTable)
+	other.Set("Name", "OtherTenant")
+	_, err := o.Insert(ctx, nil, other)
+	fatalIf(err)
+	defer func() {
+		_, err := o.DeleteWhere(ctx, nil, rootTable, query.Eq("Name", "OtherTenant"))
+		fatalIf(err)
+	}()
+
+	// Act like a tenant filter, only letting the two Joe rows through
+	removeFilter := o.RegisterBeforeRetrieveHook(rootTable, func(_ context.Context, _ *sql.Tx, _ string, q *query.Query) (*query.Query, error) {
+		return query.And(q, query.Eq("Name", "Joe")), nil
+	})
+	removeDerived := o.RegisterHook(orm.AllTables, orm.AfterRetrieve, func(_ context.Context, _ *sql.Tx, _ orm.HookEvent, obj *object.Object) error {
+		name, err := obj.GetStringAlways("Name")
+		if err != nil {
+			return err
+		}
+		obj.Set("Derived", strings.ToUpper(name))
+		return nil
+	})
+	defer func() {
+		removeFilter()
+		removeDerived()
+	}()
+
+	objs, err := o.RetrieveMany(ctx, rootTable, map[string]interface{}{})
+	fatalIf(err)
+	if len(objs) != 2 {
+		t.Fatalf("BeforeRetrieve: expected 2 rows, got %d", len(objs))
+	}
+	for _, obj := range objs {
+		if obj.Get("Derived") != "JOE" {
+			t.Fatalf("AfterRetrieve: expected a derived value, got %v", obj.Get("Derived"))
+		}
+		if obj.IsDirty() {
+			t.Fatal("AfterRetrieve: retrieved objects should not be dirty")
+		}
+	}
+
+	// AfterRetrieve hooks can use the transaction the rows are retrieved in
+	tx, err := o.RawConn.BeginTx(ctx, nil)
+	fatalIf(err)
+	removeTxHook := o.RegisterHook(rootTable, orm.AfterRetrieve, func(hookCtx context.Context, hookTx *sql.Tx, _ orm.HookEvent, _ *object.Object) error {
+		if hookTx != tx {
+			return errors.New("expected the retrieval's transaction")
+		}
+		_, err := o.Count(hookCtx, hookTx, mock.AddressesObjectType, query.All())
+		return err
+	})
+	objs, err = o.RetrieveManyTx(ctx, tx, rootTable, map[string]interface{}{})
+	removeTxHook()
+	fatalIf(tx.Rollback())
+	fatalIf(err)
+	if len(objs) != 2 {
+		t.Fatalf("AfterRetrieve: expected 2 rows in the transaction, got %d", len(objs))
+	}
+
+	n, err := o.Count(ctx, nil, rootTable, nil)
+	fatalIf(err)
+	if n != 2 {
+		t.Fatalf("BeforeRetrieve: expected Count to be filtered to 2, got %d", n)
+	}
+
+	removeFilter()
+	n, err = o.Count(ctx, nil, rootTable, nil)
+	fatalIf(err)
+	if n != 3 {
+		t.Fatalf("BeforeRetrieve: expected 3 rows without the filter, got %d", n)
+	}
+}
+
+func testRetrieveWithChildrenDepth(o *orm.ORM, t *testing.T, rootTable string) {
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+
+	person := mock.DefaultPersonWithAddress()
+	person.Set("Name", "Graph")
+	second := mock.SampleAddressObject()
+	second.Set("Address1", "Second")
+	person.Children[mock.AddressesObjectType] = append(person.Children[mock.AddressesObjectType], second)
+	_, err := o.SaveAll(ctx, person)
+	fatalIf(err)
+
+	pk := map[string]interface{}{"PersonID": person.Get("PersonID")}
+	defer func() {
+		_, err := o.DeleteWhere(ctx, nil, mock.AddressesObjectType, query.FromKV(pk))
+		fatalIf(err)
+		_, err = o.DeleteWhere(ctx, nil, rootTable, query.FromKV(pk))
+		fatalIf(err)
+	}()
+
+	obj, err := o.RetrieveWithChildren(ctx, rootTable, pk)
+	fatalIf(err)
+	if n := len(obj.Children[mock.AddressesObjectType]); n != 2 {
+		t.Fatalf("RetrieveWithChildren: expected 2 addresses, got %d", n)
+	}
+
+	obj, err = o.RetrieveWithChildrenDepth(ctx, nil, rootTable, pk, 0)
+	fatalIf(err)
+	if len(obj.Children) != 0 {
+		t.Fatal("RetrieveWithChildrenDepth: expected no children at depth 0", obj.Children)
+	}
+
+	obj, err = o.RetrieveWithChildrenDepth(ctx, nil, rootTable, map[string]interface{}{"PersonID": int64(-1)}, 1)
+	fatalIf(err)
+	if obj != nil {
+		t.Fatal("RetrieveWithChildrenDepth: expected nil for a missing row", obj)
+	}
+
+	// Make addresses refer back to their person, so the graph has a cycle
+	addrTable := o.GetSchema().GetTable(mock.AddressesObjectType)
+	back := schema.DefaultChildTable()
+	back.LocalColumn = "PersonID"
+	addrTable.Children[rootTable] = back
+	defer delete(addrTable.Children, rootTable)
+
+	obj, err = o.RetrieveWithChildrenDepth(ctx, nil, rootTable, pk, orm.UnlimitedDepth)
+	fatalIf(err)
+	addrs := obj.Children[mock.AddressesObjectType]
+	if len(addrs) != 2 {
+		t.Fatalf("RetrieveWithChildrenDepth: expected 2 addresses, got %d", len(addrs))
+	}
+	for _, addr := range addrs {
+		people := addr.Children[rootTable]
+		if len(people) != 1 || people[0].Get("PersonID") != person.Get("PersonID") {
+			t.Fatal("RetrieveWithChildrenDepth: expected each address to load its person", people)
+		}
+		if len(people[0].Children) != 0 {
+			t.Fatal("RetrieveWithChildrenDepth: expected the repeated person not to be expanded again")
+		}
+	}
+}
+
+func testPreload(o *orm.ORM, t *testing.T, rootTable string) {
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+
+	// Two addresses, one address and none
+	names := []interface{}{"PreloadA", "PreloadB", "PreloadC"}
+	counts := map[string]int{"PreloadA": 2, "PreloadB": 1, "PreloadC": 0}
+	for _, name := range names {
+		person := mock.DefaultPersonWithAddress()
+		person.Set("Name", name)
+		person.Children[mock.AddressesObjectType] = nil
+		for i := 0; i < counts[name.(string)]; i++ {
+			person.Children[mock.AddressesObjectType] = append(person.Children[mock.AddressesObjectType], mock.SampleAddressObject())
+		}
+		_, err := o.SaveAll(ctx, person)
+		fatalIf(err)
+	}
+
+	people, err := o.RetrieveManyQuery(ctx, rootTable, query.In("Name", names...), nil)
+	fatalIf(err)
+	if len(people) != 3 {
+		t.Fatalf("expected 3 people, got %d", len(people))
+	}
+	defer func() {
+		for _, p := range people {
+			pk := map[string]interface{}{"PersonID": p.Get("PersonID")}
+			_, err := o.DeleteWhere(ctx, nil, mock.AddressesObjectType, query.FromKV(pk))
+			fatalIf(err)
+			_, err = o.DeleteWhere(ctx, nil, rootTable, query.FromKV(pk))
+			fatalIf(err)
+		}
+	}()
+
+	// A batch size of 1 forces a query per parent, checking the batches are
+	// put back together
+	defer func() { o.PreloadBatchSize = 0 }()
+	for _, batchSize := range []int{1, 0} {
+		o.PreloadBatchSize = batchSize
+		err = o.Preload(ctx, people)
+		fatalIf(err)
+		for _, p := range people {
+			name, err := p.GetStringAlways("Name")
+			fatalIf(err)
+			addrs := p.Children[mock.AddressesObjectType]
+			if addrs == nil || len(addrs) != counts[name] {
+				t.Fatalf("Preload: expected %d addresses for %s, got %v", counts[name], name, addrs)
+			}
+			for _, addr := range addrs {
+				if v, _ := addr.GetIntAlways("PersonID"); v != p.Get("PersonID") {
+					t.Fatalf("Preload: address of %v attached to %s", addr.Get("PersonID"), name)
+				}
+			}
+		}
+	}
+
+	err = o.Preload(ctx, people, "nosuchtable")
+	if err == nil {
+		t.Fatal("Preload: expected an error for an unknown child table")
+	}
+}
+
+func testCompositeKeyChildren(o *orm.ORM, t *testing.T, rootTable string) {
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+
+	// Relate addresses to people by both PersonID and Name (as City)
+	peopleTable := o.GetSchema().GetTable(rootTable)
+	saved := peopleTable.Children[mock.AddressesObjectType]
+	rel := schema.DefaultChildTable()
+	rel.LocalColumns = []string{"PersonID", "Name"}
+	rel.ForeignColumns = []string{"PersonID", "City"}
+	peopleTable.Children[mock.AddressesObjectType] = rel
+	defer func() { peopleTable.Children[mock.AddressesObjectType] = saved }()
+
+	person := mock.DefaultPersonWithAddress()
+	person.Set("Name", "Composite")
+	_, err := o.SaveAll(ctx, person)
+	fatalIf(err)
+
+	pk := map[string]interface{}{"PersonID": person.Get("PersonID")}
+	defer func() {
+		_, err := o.DeleteWhere(ctx, nil, mock.AddressesObjectType, query.FromKV(pk))
+		fatalIf(err)
+		_, err = o.DeleteWhere(ctx, nil, rootTable, query.FromKV(pk))
+		fatalIf(err)
+	}()
+
+	addr := person.Children[mock.AddressesObjectType][0]
+	if addr.Get("PersonID") != person.Get("PersonID") {
+		t.Fatalf("SaveAll: expected PersonID %v in the address, got %v", person.Get("PersonID"), addr.Get("PersonID"))
+	}
+	if city, _ := addr.GetStringAlways("City"); city != "Composite" {
+		t.Fatalf("SaveAll: expected the Name to be saved as the City, got %s", city)
+	}
+
+	// Same person, but not related by the second column
+	other := mock.SampleAddressObject()
+	other.Set("PersonID", person.Get("PersonID"))
+	_, err = o.Insert(ctx, nil, other)
+	fatalIf(err)
+
+	obj, err := o.Retrieve(ctx, rootTable, pk)
+	fatalIf(err)
+	obj, err = o.FleshenChildren(ctx, obj)
+	fatalIf(err)
+	addrs := obj.Children[mock.AddressesObjectType]
+	if len(addrs) != 1 || addrs[0].Get("AddressID") != addr.Get("AddressID") {
+		t.Fatalf("FleshenChildren: expected only address %v, got %v", addr.Get("AddressID"), addrs)
+	}
+}
+
+// newParentChildORM creates the tables prefix_parent and prefix_child, with
+// ParentID and ChildID identity keys, a nullable Name on the parent and the
+// parent's key on the child, relating them as parent and child. tweak may
+// change either table before they are created, and every column ends up
+// essential. The returned function drops the tables.
+func newParentChildORM(t *testing.T, o *orm.ORM, prefix string, tweak func(parent *schema.Table, child *schema.Table)) (*orm.ORM, func()) {
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+
+	parent := schema.DefaultTable()
+	parent.Name = prefix + "_parent"
+	parent.Primary = "ParentID"
+	parent.Columns["ParentID"] = &schema.Column{Name: "ParentID", DBType: "integer", IsIdentity: true, IsNumber: true}
+	parent.Columns["Name"] = &schema.Column{Name: "Name", DBType: "varchar", Length: 32, AllowNull: true}
+
+	child := schema.DefaultTable()
+	child.Name = prefix + "_child"
+	child.Primary = "ChildID"
+	child.Columns["ChildID"] = &schema.Column{Name: "ChildID", DBType: "integer", IsIdentity: true, IsNumber: true}
+	child.Columns["ParentID"] = &schema.Column{Name: "ParentID", DBType: "integer", IsNumber: true}
+	parent.Children[child.Name] = schema.DefaultChildTable()
+
+	if tweak != nil {
+		tweak(parent, child)
+	}
+	sch := schema.DefaultSchema()
+	for _, tbl := range []*schema.Table{parent, child} {
+		tbl.EssentialColumns = nil
+		for name := range tbl.Columns {
+			tbl.EssentialColumns = append(tbl.EssentialColumns, name)
+		}
+		sort.Strings(tbl.EssentialColumns)
+		sch.Tables[tbl.Name] = tbl
+	}
+	if err := schema.Validate(sch); err != nil {
+		t.Fatal(err)
+	}
+
+	pcORM := orm.New(getSQLGen(), sch, o.RawConn)
+	if err := pcORM.CreateTables(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return pcORM, func() {
+		ctx, cancel := getDefaultContext()
+		defer cancel()
+		fatalIf(pcORM.DropTables(ctx))
+	}
+}
+
+func testMultiKeyChildren(o *orm.ORM, t *testing.T) {
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+
+	// Children related by the parent's primary key, and by Region through
+	// the child's ForeignKeys
+	mkORM, drop := newParentChildORM(t, o, "mk", func(parent *schema.Table, child *schema.Table) {
+		parent.Columns["Region"] = &schema.Column{Name: "Region", DBType: "varchar", Length: 32}
+		child.MultiKey = true
+		child.ForeignKeys = []string{"ParentID", "Region"}
+		child.Columns["Region"] = &schema.Column{Name: "Region", DBType: "varchar", Length: 32}
+	})
+	defer drop()
+
+	p := object.New("mk_parent")
+	p.Set("Region", "east")
+	c := object.New("mk_child")
+	p.Children["mk_child"] = object.NewArray(c)
+	_, err := mkORM.SaveAll(ctx, p)
+	fatalIf(err)
+	if region, _ := c.GetStringAlways("Region"); region != "east" {
+		t.Fatalf("SaveAll: expected Region to propagate to the child, got %s", region)
+	}
+
+	// Same parent, different region
+	other := object.New("mk_child")
+	other.Set("ParentID", p.Get("ParentID"))
+	other.Set("Region", "west")
+	_, err = mkORM.Insert(ctx, nil, other)
+	fatalIf(err)
+
+	found, err := mkORM.RetrieveWithChildren(ctx, "mk_parent", map[string]interface{}{"ParentID": p.Get("ParentID")})
+	fatalIf(err)
+	children := found.Children["mk_child"]
+	if len(children) != 1 || children[0].Get("ChildID") != c.Get("ChildID") {
+		t.Fatalf("RetrieveWithChildren: expected only child %v, got %v", c.Get("ChildID"), children)
+	}
+
+	fatalIf(mkORM.Preload(ctx, object.NewArray(found)))
+	if n := len(found.Children["mk_child"]); n != 1 {
+		t.Fatalf("Preload: expected 1 child, got %d", n)
+	}
+}
+
+func testDeleteAll(o *orm.ORM, t *testing.T, rootTable string) {
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+
+	countRows := func(pk map[string]interface{}) (int64, int64) {
+		people, err := o.Count(ctx, nil, rootTable, query.FromKV(pk))
+		fatalIf(err)
+		addrs, err := o.Count(ctx, nil, mock.AddressesObjectType, query.FromKV(pk))
+		fatalIf(err)
+		return people, addrs
+	}
+	saveGraph := func(name string) (*object.Object, map[string]interface{}) {
+		person := mock.DefaultPersonWithAddress()
+		person.Set("Name", name)
+		person.Children[mock.AddressesObjectType] = append(person.Children[mock.AddressesObjectType], mock.SampleAddressObject())
+		_, err := o.SaveAll(ctx, person)
+		fatalIf(err)
+		return person, map[string]interface{}{"PersonID": person.Get("PersonID")}
+	}
+
+	// The children in memory
+	person, pk := saveGraph("DeleteAllMemory")
+	rowsAff, err := o.DeleteAll(ctx, nil, person, nil)
+	fatalIf(err)
+	if rowsAff != 3 {
+		t.Fatalf("DeleteAll: expected 3 rows affected, got %d", rowsAff)
+	}
+	if people, addrs := countRows(pk); people != 0 || addrs != 0 {
+		t.Fatalf("DeleteAll: expected no rows left, got %d people and %d addresses", people, addrs)
+	}
+
+	// The children loaded from the database, rolled back and then committed
+	_, pk = saveGraph("DeleteAllLoaded")
+	for _, commit := range []bool{false, true} {
+		obj, err := o.Retrieve(ctx, rootTable, pk)
+		fatalIf(err)
+		tx, err := o.RawConn.BeginTx(ctx, nil)
+		fatalIf(err)
+		rowsAff, err = o.DeleteAll(ctx, tx, obj, &orm.DeleteAllOptions{LoadChildren: true})
+		fatalIf(err)
+		if rowsAff != 3 {
+			t.Fatalf("DeleteAll: expected 3 rows affected, got %d", rowsAff)
+		}
+		if commit {
+			fatalIf(tx.Commit())
+		} else {
+			fatalIf(tx.Rollback())
+		}
+		people, addrs := countRows(pk)
+		if commit && (people != 0 || addrs != 0) {
+			t.Fatalf("DeleteAll: expected no rows left, got %d people and %d addresses", people, addrs)
+		}
+		if !commit && (people != 1 || addrs != 2) {
+			t.Fatalf("DeleteAll: expected the rollback to keep the rows, got %d people and %d addresses", people, addrs)
+		}
+	}
+
+	// The root must be known
+	_, err = o.DeleteAll(ctx, nil, object.New(rootTable), nil)
+	if err == nil {
+		t.Fatal("DeleteAll: expected an error for an object without a primary key")
+	}
+
+	testDeleteAllSoftDeleted(o, t)
+}
+
+// testDeleteAllSoftDeleted checks that DeleteAll loads soft deleted children
+// too, leaving them as they are, using tables of its own.
+func testDeleteAllSoftDeleted(o *orm.ORM, t *testing.T) {
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+
+	sdORM, drop := newParentChildORM(t, o, "sd", func(parent *schema.Table, child *schema.Table) {
+		child.SoftDeleteColumn = "DeletedAt"
+		child.Columns["DeletedAt"] = &schema.Column{Name: "DeletedAt", DBType: "timestamp", AllowNull: true}
+		child.Columns["Version"] = &schema.Column{Name: "Version", DBType: "integer", IsNumber: true}
+	})
+	defer drop()
+	childTable := sdORM.GetSchema().GetTable("sd_child")
+	defer func() {
+		childTable.VersionColumn = ""
+	}()
+
+	for _, versioned := range []bool{false, true} {
+		if versioned {
+			childTable.VersionColumn = "Version"
+		}
+
+		p := object.New("sd_parent")
+		p.Set("Name", "SoftDeletedChildren")
+		for i := 0; i < 2; i++ {
+			c := object.New("sd_child")
+			c.Set("Version", int64(1))
+			p.Children["sd_child"] = append(p.Children["sd_child"], c)
+		}
+		_, err := sdORM.SaveAll(ctx, p)
+		fatalIf(err)
+		first := p.Children["sd_child"][0]
+		_, err = sdORM.Delete(ctx, nil, first)
+		fatalIf(err)
+
+		ofParent := query.Eq("ParentID", p.Get("ParentID"))
+		withDeleted := &query.Options{Deleted: query.WithDeleted}
+		firstQuery := query.Eq("ChildID", first.Get("ChildID"))
+		before, err := sdORM.RetrieveManyQuery(ctx, "sd_child", firstQuery, withDeleted)
+		fatalIf(err)
+
+		obj := object.New("sd_parent")
+		obj.Set("ParentID", p.Get("ParentID"))
+		_, err = sdORM.DeleteAll(ctx, nil, obj, &orm.DeleteAllOptions{LoadChildren: true})
+		fatalIf(err)
+		if n := len(obj.Children["sd_child"]); n != 2 {
+			t.Fatalf("DeleteAll: expected the soft deleted child to be loaded, got %d children", n)
+		}
+
+		n, err := sdORM.Count(ctx, nil, "sd_parent", query.Eq("ParentID", p.Get("ParentID")))
+		fatalIf(err)
+		if n != 0 {
+			t.Fatal("DeleteAll: expected the parent to be deleted")
+		}
+		deleted, err := sdORM.RetrieveManyQuery(ctx, "sd_child", ofParent, &query.Options{Deleted: query.OnlyDeleted})
+		fatalIf(err)
+		if len(deleted) != 2 {
+			t.Fatalf("DeleteAll: expected both children to be soft deleted, got %d", len(deleted))
+		}
+
+		// The child that was deleted already is untouched, while the other
+		// has its version advanced only when versioned
+		after, err := sdORM.RetrieveManyQuery(ctx, "sd_child", firstQuery, withDeleted)
+		fatalIf(err)
+		if !reflect.DeepEqual(before[0].KV, after[0].KV) {
+			t.Fatalf("DeleteAll: expected the soft deleted child to be left alone, got %v, was %v", after[0].KV, before[0].KV)
+		}
+		wantVersion := int64(1)
+		if versioned {
+			wantVersion = 2
+		}
+		atVersion, err := sdORM.RetrieveManyQuery(ctx, "sd_child", query.And(ofParent, query.Eq("Version", wantVersion)), withDeleted)
+		fatalIf(err)
+		if len(atVersion) != 2 {
+			t.Fatalf("DeleteAll: expected both children at version %d, got %d", wantVersion, len(atVersion))
+		}
+
+		_, err = sdORM.DeleteWhere(ctx, nil, "sd_child", ofParent)
+		fatalIf(err)
+	}
+}
+
+func testSyncAll(o *orm.ORM, t *testing.T, rootTable string) {
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+
+	person := mock.DefaultPersonWithAddress()
+	person.Set("Name", "Sync")
+	person.Children[mock.AddressesObjectType] = nil
+	for _, a := range []string{"First", "Second", "Third"} {
+		addr := mock.SampleAddressObject()
+		addr.Set("Address1", a)
+		person.Children[mock.AddressesObjectType] = append(person.Children[mock.AddressesObjectType], addr)
+	}
+	_, err := o.SyncAll(ctx, person)
+	fatalIf(err)
+
+	pk := map[string]interface{}{"PersonID": person.Get("PersonID")}
+	defer func() {
+		_, err := o.DeleteWhere(ctx, nil, mock.AddressesObjectType, query.FromKV(pk))
+		fatalIf(err)
+		_, err = o.DeleteWhere(ctx, nil, rootTable, query.FromKV(pk))
+		fatalIf(err)
+	}()
+
+	// As if from an API: change the first, drop the second and add a fourth
+	incoming, err := o.RetrieveWithChildren(ctx, rootTable, pk)
+	fatalIf(err)
+	var kept object.Array
+	for _, addr := range incoming.Children[mock.AddressesObjectType] {
+		a, err := addr.GetStringAlways("Address1")
+		fatalIf(err)
+		switch a {
+		case "First":
+			addr.Set("Address1", "FirstChanged")
+			kept = append(kept, addr)
+		case "Third":
+			kept = append(kept, addr)
+		}
+	}
+	fourth := mock.SampleAddressObject()
+	fourth.Set("Address1", "Fourth")
+	incoming.Children[mock.AddressesObjectType] = append(kept, fourth)
+
+	_, err = o.SyncAll(ctx, incoming)
+	fatalIf(err)
+
+	stored, err := o.RetrieveManyQuery(ctx, mock.AddressesObjectType, query.FromKV(pk), &query.Options{OrderBy: []query.Order{query.Asc("Address1")}})
+	fatalIf(err)
+	var got []string
+	for _, addr := range stored {
+		a, err := addr.GetStringAlways("Address1")
+		fatalIf(err)
+		got = append(got, a)
+	}
+	if !reflect.DeepEqual(got, []string{"FirstChanged", "Fourth", "Third"}) {
+		t.Fatalf("SyncAll: unexpected addresses after sync: %v", got)
+	}
+
+	// An empty array removes every child
+	incoming.Children[mock.AddressesObjectType] = object.NewEmptyArray()
+	_, err = o.SyncAll(ctx, incoming)
+	fatalIf(err)
+	n, err := o.Count(ctx, nil, mock.AddressesObjectType, query.FromKV(pk))
+	fatalIf(err)
+	if n != 0 {
+		t.Fatalf("SyncAll: expected no addresses left, got %d", n)
+	}
+}
+
+func testManyToMany(o *orm.ORM, t *testing.T, rootTable string) {
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+
+	var people object.Array
+	for _, name := range []string{"Alice", "Bob", "Carol"} {
+		p := object.New(rootTable)
+		p.Set("Name", name)
+		_, err := o.Insert(ctx, nil, p)
+		fatalIf(err)
+		people = append(people, p)
+	}
+	defer func() {
+		for _, p := range people {
+			pk := map[string]interface{}{"PersonID": p.Get("PersonID")}
+			_, err := o.DeleteWhere(ctx, nil, mock.FriendsObjectType, query.FromKV(pk))
+			fatalIf(err)
+			_, err = o.DeleteWhere(ctx, nil, rootTable, query.FromKV(pk))
+			fatalIf(err)
+		}
+	}()
+	alice, bob, carol := people[0], people[1], people[2]
+
+	friendNames := func(p *object.Object) []string {
+		friends, err := o.RetrieveRelated(ctx, nil, p, mock.FriendsObjectType)
+		fatalIf(err)
+		names := []string{}
+		for _, f := range friends {
+			name, err := f.GetStringAlways("Name")
+			fatalIf(err)
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	if names := friendNames(alice); len(names) != 0 {
+		t.Fatalf("RetrieveRelated: expected no friends, got %v", names)
+	}
+	for _, f := range []*object.Object{bob, carol} {
+		_, err := o.Link(ctx, nil, alice, mock.FriendsObjectType, f)
+		fatalIf(err)
+	}
+	if names := friendNames(alice); !reflect.DeepEqual(names, []string{"Bob", "Carol"}) {
+		t.Fatalf("RetrieveRelated: expected Bob and Carol, got %v", names)
+	}
+	// The relation is one-way
+	if names := friendNames(bob); len(names) != 0 {
+		t.Fatalf("RetrieveRelated: expected Bob to have no friends, got %v", names)
+	}
+
+	// Unlink calls the join table's delete hooks, as Link calls its create hooks
+	var unlinked int
+	removeHook := o.RegisterHook(mock.FriendsObjectType, orm.BeforeDelete, func(context.Context, *sql.Tx, orm.HookEvent, *object.Object) error {
+		unlinked++
+		return nil
+	})
+	_, err := o.Unlink(ctx, nil, alice, mock.FriendsObjectType, bob)
+	removeHook()
+	fatalIf(err)
+	if unlinked != 1 {
+		t.Fatalf("Unlink: expected the BeforeDelete hook to be called once, got %d", unlinked)
+	}
+	if names := friendNames(alice); !reflect.DeepEqual(names, []string{"Carol"}) {
+		t.Fatalf("RetrieveRelated: expected Carol after unlinking Bob, got %v", names)
+	}
+	_, err = o.Unlink(ctx, nil, alice, mock.FriendsObjectType, bob)
+	if err != orm.ErrNoResult {
+		t.Fatalf("Unlink: expected ErrNoResult, got %v", err)
+	}
+
+	_, err = o.Link(ctx, nil, alice, "nosuchrelation", bob)
+	if err == nil {
+		t.Fatal("Link: expected an error for an unknown relation")
+	}
+}
+
+func testSchemaDiff(o *orm.ORM, t *testing.T) {
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+
+	g := getSQLGen()
+	apply := func(old *schema.Schema, new *schema.Schema) {
+		stmts, err := sg.RenderSchemaDiff(g, schema.Diff(old, new))
+		fatalIf(err)
+		for _, stmt := range stmts {
+			_, err := o.RawConn.ExecContext(ctx, stmt)
+			fatalIf(err)
+		}
+	}
+
+	// Versions of a table: created, gaining a column, then losing it
+	tbl := schema.DefaultTable()
+	tbl.Name = "diffs"
+	tbl.Primary = "DiffID"
+	tbl.Columns["DiffID"] = &schema.Column{Name: "DiffID", DBType: "integer", IsIdentity: true, IsNumber: true}
+	tbl.Columns["Name"] = &schema.Column{Name: "Name", DBType: "text"}
+	tbl.EssentialColumns = []string{"DiffID", "Name"}
+	v1 := schema.DefaultSchema()
+	v1.Tables["diffs"] = tbl
+
+	v2 := schema.DefaultSchema()
+	tbl2 := *tbl
+	tbl2.Columns = map[string]*schema.Column{"Note": {Name: "Note", DBType: "text", AllowNull: true}}
+	for k, v := range tbl.Columns {
+		tbl2.Columns[k] = v
+	}
+	tbl2.EssentialColumns = []string{"DiffID", "Name", "Note"}
+	v2.Tables["diffs"] = &tbl2
+
+	apply(schema.DefaultSchema(), v1)
+	apply(v1, v2)
+
+	_, err := o.RawConn.ExecContext(ctx, "INSERT INTO diffs (Name, Note) VALUES ('a', 'b')")
+	fatalIf(err)
+
+	apply(v2, v1)
+	_, err = o.RawConn.ExecContext(ctx, "INSERT INTO diffs (Name, Note) VALUES ('a', 'b')")
+	if err == nil {
+		t.Fatal("expected the Note column to have been dropped")
+	}
+
+	// Gaining an index and losing it: creating it a second time only works
+	// if it was dropped
+	v3 := schema.DefaultSchema()
+	tbl3 := *tbl
+	tbl3.Indexes = []*schema.Index{{Columns: []string{"DiffID"}}}
+	v3.Tables["diffs"] = &tbl3
+	d := schema.Diff(v1, v3)
+	if len(d.ChangedTables) != 1 || len(d.ChangedTables[0].AddedIndexes) != 1 {
+		t.Fatal("Diff: expected an added index", d.ChangedTables)
+	}
+	apply(v1, v3)
+	apply(v3, v1)
+	apply(v1, v3)
+	apply(v3, v1)
+
+	apply(v1, schema.DefaultSchema())
+	_, err = o.RawConn.ExecContext(ctx, "SELECT 1 FROM diffs")
+	if err == nil {
+		t.Fatal("expected the diffs table to have been dropped")
+	}
+}
+
+func testIndexes(o *orm.ORM, t *testing.T) {
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+
+	tbl := schema.DefaultTable()
+	tbl.Name = "indexed"
+	tbl.Primary = "IndexedID"
+	tbl.Columns["IndexedID"] = &schema.Column{Name: "IndexedID", DBType: "integer", IsIdentity: true, IsNumber: true}
+	tbl.Columns["Name"] = &schema.Column{Name: "Name", DBType: "varchar", Length: 32}
+	tbl.Columns["Kind"] = &schema.Column{Name: "Kind", DBType: "varchar", Length: 32}
+	tbl.Columns["Active"] = &schema.Column{Name: "Active", DBType: "integer", IsNumber: true}
+	tbl.EssentialColumns = []string{"IndexedID", "Name", "Kind", "Active"}
+	tbl.Indexes = []*schema.Index{
+		{Columns: []string{"Kind"}},
+		{Name: "indexed_name_kind", Columns: []string{"Name", "Kind"}, Unique: true},
+	}
+	sch := schema.DefaultSchema()
+	sch.Tables["indexed"] = tbl
+
+	// Only some databases support partial indexes
+	partial := &schema.Index{Name: "indexed_active_name", Columns: []string{"Name"}, Unique: true, Where: "Active = 1"}
+	g := getSQLGen()
+	_, err := g.CreateIndex(g, sch, "indexed", partial)
+	hasPartial := err == nil
+	if hasPartial {
+		tbl.Indexes = append(tbl.Indexes, partial)
+	}
+	fatalIf(schema.Validate(sch))
+
+	fatalIf(o.CreateTable(ctx, sch, "indexed"))
+	defer func() {
+		fatalIf(o.DropTable(ctx, "indexed"))
+	}()
+
+	insert := func(name string, kind string, active string) error {
+		_, err := o.RawConn.ExecContext(ctx, "INSERT INTO indexed (Name, Kind, Active) VALUES ('"+name+"', '"+kind+"', "+active+")")
+		return err
+	}
+	fatalIf(insert("a", "x", "0"))
+	fatalIf(insert("a", "y", "0"))
+	if insert("a", "x", "0") == nil {
+		t.Fatal("expected the composite unique index to reject a duplicate")
+	}
+
+	if !hasPartial {
+		return
+	}
+	fatalIf(insert("b", "x", "1"))
+	fatalIf(insert("b", "y", "0"))
+	if insert("b", "z", "1") == nil {
+		t.Fatal("expected the partial unique index to reject a duplicate active row")
+	}
+}
+
+func testForeignKeys(o *orm.ORM, t *testing.T) {
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+
+	// fk_child sorts first, so this fails unless tables are ordered by
+	// their foreign keys
+	fkORM, drop := newParentChildORM(t, o, "fk", func(parent *schema.Table, child *schema.Table) {
+		parent.Children[child.Name].ForeignKey = &schema.ForeignKey{OnDelete: schema.FKCascade}
+	})
+	defer drop()
+	sch := fkORM.GetSchema()
+	parent := sch.GetTable("fk_parent")
+	child := sch.GetTable("fk_child")
+
+	p := object.New("fk_parent")
+	p.Set("Name", "parent")
+	_, err := fkORM.Insert(ctx, nil, p)
+	fatalIf(err)
+	parentID := p.Get("ParentID")
+
+	c := object.New("fk_child")
+	c.Set("ParentID", parentID)
+	_, err = fkORM.Insert(ctx, nil, c)
+	fatalIf(err)
+
+	orphan := object.New("fk_child")
+	orphan.Set("ParentID", 999999)
+	if _, err := fkORM.Insert(ctx, nil, orphan); err == nil {
+		t.Fatal("expected the foreign key to reject a child without a parent")
+	}
+
+	_, err = fkORM.Delete(ctx, nil, p)
+	fatalIf(err)
+	count, err := fkORM.Count(ctx, nil, "fk_child", query.All())
+	fatalIf(err)
+	if count != 0 {
+		t.Fatal("expected deleting the parent to cascade to its child, count =", count)
+	}
+
+	// Dropping the constraint from the existing table, and adding it back
+	noFK := schema.DefaultSchema()
+	noFKParent := *parent
+	noFKParent.Children = map[string]*schema.ChildTable{"fk_child": schema.DefaultChildTable()}
+	noFK.Tables["fk_parent"] = &noFKParent
+	noFK.Tables["fk_child"] = child
+	d := schema.Diff(sch, noFK)
+	if len(d.ChangedTables) != 1 || len(d.ChangedTables[0].RemovedForeignKeys) != 1 {
+		t.Fatal("Diff: expected the foreign key to be removed", d.ChangedTables)
+	}
+	g := getSQLGen()
+	for _, step := range []*schema.SchemaDiff{d, schema.Diff(noFK, sch)} {
+		stmts, err := sg.RenderSchemaDiff(g, step)
+		if g.IsSQLITE {
+			if err == nil {
+				t.Fatal("RenderSchemaDiff: expected sqlite to refuse to alter foreign keys")
+			}
+			continue
+		}
+		fatalIf(err)
+		for _, stmt := range stmts {
+			_, err := o.RawConn.ExecContext(ctx, stmt)
+			fatalIf(err)
+		}
+	}
+}
+
+func testMigrate(o *orm.ORM, t *testing.T) {
+	ctx, cancel := getDefaultContext()
+	defer cancel()
+
+	// Adapters that can't lock refuse to migrate unless told to go ahead
+	if _, err := o.Migrate(ctx, nil); err != nil {
+		if errors.Cause(err) != sg.ErrLocksUnsupported {
+			t.Fatal("Migrate: expected ErrLocksUnsupported, got", err)
+		}
+		o.MigrateWithoutLock = true
+		defer func() {
+			o.MigrateWithoutLock = false
+		}()
+	}
+	defer func() {
+		fatalIf(o.DropTable(ctx, orm.MigrationsTable))
+	}()
+
+	tableExists := func(name string) bool {
+		_, err := o.RawConn.ExecContext(ctx, "SELECT 1 FROM "+name)
+		return err == nil
+	}
+
+	tbl := schema.DefaultTable()
+	tbl.Name = "mig_b"
+	tbl.Primary = "BID"
+	tbl.Columns["BID"] = &schema.Column{Name: "BID", DBType: "integer", IsIdentity: true, IsNumber: true}
+	tbl.EssentialColumns = []string{"BID"}
+	withB := schema.DefaultSchema()
+	withB.Tables["mig_b"] = tbl
+
+	migrations := []orm.Migration{
+		{
+			ID:   "0001_mig_a",
+			Up:   orm.MigrationStep{SQL: []string{"CREATE TABLE mig_a (AID integer)"}},
+			Down: orm.MigrationStep{SQL: []string{"DROP TABLE mig_a"}},
+		},
+		{
+			ID:   "0002_mig_b",
+			Up:   orm.MigrationStep{Diff: schema.Diff(schema.DefaultSchema(), withB)},
+			Down: orm.MigrationStep{Diff: schema.Diff(withB, schema.DefaultSchema())},
+		},
+	}
+
+	applied, err := o.Migrate(ctx, migrations)
+	fatalIf(err)
+	if !reflect.DeepEqual(applied, []string{"0001_mig_a", "0002_mig_b"}) {
+		t.Fatal("Migrate: expected both migrations to be applied, got", applied)
+	}
+	if !tableExists("mig_a") || !tableExists("mig_b") {
+		t.Fatal("Migrate: expected mig_a and mig_b to exist")
+	}
+
+	applied, err = o.Migrate(ctx, migrations)
+	fatalIf(err)
+	if len(applied) != 0 {
+		t.Fatal("Migrate: expected nothing left to apply, got", applied)
+	}
+
+	// A failed migration is rolled back and not recorded
+	failing := append(migrations, orm.Migration{
+		ID: "0003_fails",
+		Up: orm.MigrationStep{SQL: []string{"CREATE TABLE mig_c (CID integer)", "NOT SQL AT ALL"}},
+	})
+	_, err = o.Migrate(ctx, failing)
+	if err == nil {
+		t.Fatal("Migrate: expected an error for invalid SQL")
+	}
+	if tableExists("mig_c") {
+		t.Fatal("Migrate: expected the failed migration to be rolled back")
+	}
+
+	changed := append([]orm.Migration{}, migrations...)
+	changed[0].Up = orm.MigrationStep{SQL: []string{"CREATE TABLE mig_a (AID integer, Extra integer)"}}
+	_, err = o.Migrate(ctx, changed)
+	if errors.Cause(err) != orm.ErrMigrationChanged {
+		t.Fatal("Migrate: expected ErrMigrationChanged, got", err)
+	}
+
+	// Only what a Diff changes is checked, not the rest of its schemas
+	unrelated := schema.DefaultTable()
+	unrelated.Name = "mig_unrelated"
+	unrelated.Primary = "UID"
+	unrelated.Columns["UID"] = &schema.Column{Name: "UID", DBType: "integer", IsIdentity: true, IsNumber: true}
+	unrelated.EssentialColumns = []string{"UID"}
+	oldSch := schema.DefaultSchema()
+	oldSch.Tables["mig_unrelated"] = unrelated
+	newSch := schema.DefaultSchema()
+	newSch.Tables["mig_unrelated"] = unrelated
+	newSch.Tables["mig_b"] = tbl
+	changed = append([]orm.Migration{}, migrations...)
+	changed[1].Up = orm.MigrationStep{Diff: schema.Diff(oldSch, newSch)}
+	applied, err = o.Migrate(ctx, changed)
+	fatalIf(err)
+	if len(applied) != 0 {
+		t.Fatal("Migrate: expected nothing left to apply, got", applied)
+	}
+
+	widerB := schema.DefaultSchema()
+	widerTbl := *tbl
+	widerTbl.Columns = map[string]*schema.Column{
+		"BID":  tbl.Columns["BID"],
+		"Note": {Name: "Note", DBType: "varchar", Length: 32, AllowNull: true},
+	}
+	widerB.Tables["mig_b"] = &widerTbl
+	changed[1].Up = orm.MigrationStep{Diff: schema.Diff(schema.DefaultSchema(), widerB)}
+	_, err = o.Migrate(ctx, changed)
+	if errors.Cause(err) != orm.ErrMigrationChanged {
+		t.Fatal("Migrate: expected ErrMigrationChanged for a changed table, got", err)
+	}
+
+	reverted, err := o.MigrateDown(ctx, migrations, 1)
+	fatalIf(err)
+	if !reflect.DeepEqual(reverted, []string{"0002_mig_b"}) || tableExists("mig_b") || !tableExists("mig_a") {
+		t.Fatal("MigrateDown: expected only mig_b to be reverted, got", reverted)
+	}
+	reverted, err = o.MigrateDown(ctx, migrations, 5)
+	fatalIf(err)
+	if !reflect.DeepEqual(reverted, []string{"0001_mig_a"}) || tableExists("mig_a") {
+		t.Fatal("MigrateDown: expected mig_a to be reverted, got", reverted)
+	}
+	var n int64
+	fatalIf(o.RawConn.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+orm.MigrationsTable).Scan(&n))
+	if n != 0 {
+		t.Fatal("MigrateDown: expected no migrations left recorded, got", n)
+	}
+}
+
 func testGetParentsViaChild(o *orm.ORM, t *testing.T) {
 	// Configure our database query
 	queryVals := make(map[string]interface{})
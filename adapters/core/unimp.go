@@ -1,14 +1,22 @@
 package core
 
 import (
+	"errors"
+
 	"github.com/rbastic/dyndao/schema"
 	sg "github.com/rbastic/dyndao/sqlgen"
 )
 
+// GetLock must be db-specific, so core returns sg.ErrLocksUnsupported.
 func GetLock(g *sg.SQLGenerator, sch *schema.Schema, lockStr string) (string, []interface{}, error) {
-	panic("core.GetLock() is not implemented. it must be db-specific")
+	return "", nil, sg.ErrLocksUnsupported
 }
 
+// ReleaseLock must be db-specific, so core returns sg.ErrLocksUnsupported.
 func ReleaseLock(g *sg.SQLGenerator, sch *schema.Schema, lockStr string) (string, []interface{}, error) {
-	panic("core.ReleaseLock() is not implemented. it must be db-specific")
+	return "", nil, sg.ErrLocksUnsupported
+}
+
+func AlterTable(g *sg.SQLGenerator, sch *schema.Schema, d *schema.TableDiff) ([]string, error) {
+	return nil, errors.New("core.AlterTable() is not implemented. it must be db-specific")
 }
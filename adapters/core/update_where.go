@@ -0,0 +1,68 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/rbastic/dyndao/object"
+	"github.com/rbastic/dyndao/query"
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// BindingUpdateWhere generates an UPDATE statement that applies set to every
+// row matching q. The SET binding parameters come first, followed by those
+// of the WHERE clause.
+func BindingUpdateWhere(g *sg.SQLGenerator, sch *schema.Schema, table string, set map[string]interface{}, q *query.Query) (string, []interface{}, error) {
+	schTable := sch.GetTable(table)
+	if schTable == nil {
+		return "", nil, errors.New("BindingUpdateWhere: Table map unavailable for table " + table)
+	}
+	if len(set) == 0 {
+		return "", nil, errors.New("BindingUpdateWhere: no values to set for table " + table)
+	}
+
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var bindArgs []interface{}
+	setAry := make([]string, len(keys))
+	bindI := 1
+	for i, k := range keys {
+		f := schTable.GetColumn(k)
+		if f == nil {
+			return "", nil, errors.New("BindingUpdateWhere: unknown field " + k + " in table " + table)
+		}
+		if f.IsIdentity {
+			return "", nil, errors.New("BindingUpdateWhere: cannot update identity field " + k + " in table " + table)
+		}
+		switch v := set[k].(type) {
+		case *object.SQLValue:
+			setAry[i] = fmt.Sprintf("%s = %s", f.Name, v.String())
+		case nil:
+			setAry[i] = fmt.Sprintf("%s = NULL", f.Name)
+		default:
+			setAry[i] = fmt.Sprintf("%s = %s", f.Name, g.RenderBindingValueWithInt(f, bindI))
+			bindArgs = append(bindArgs, v)
+			bindI++
+		}
+	}
+
+	whereClause, bindWhere, err := g.RenderWhereClause(g, schTable, q, &bindI)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "BindingUpdateWhere")
+	}
+
+	tableName := schema.GetTableName(schTable.Name, table)
+	sqlStr := fmt.Sprintf("UPDATE %s SET %s", tableName, strings.Join(setAry, ", "))
+	if whereClause != "" {
+		sqlStr += " WHERE " + whereClause
+	}
+	return sqlStr, append(bindArgs, bindWhere...), nil
+}
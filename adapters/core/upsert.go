@@ -0,0 +1,42 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rbastic/dyndao/adapters/common"
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// BindingUpsert generates an INSERT ... ON CONFLICT statement that inserts
+// data, or updates the existing row that conflicts with it on
+// conflictColumns. This is understood by Postgres, CockroachDB and SQLite
+// (3.24.0 onwards), which need a unique index over conflictColumns.
+func BindingUpsert(g *sg.SQLGenerator, sch *schema.Schema, table string, data map[string]interface{}, conflictColumns []string) (string, []interface{}, error) {
+	u, err := common.PrepareUpsert(g, sch, table, data, conflictColumns)
+	if err != nil {
+		return "", nil, err
+	}
+
+	conflict := make([]string, len(u.Conflict))
+	for i, f := range u.Conflict {
+		conflict[i] = f.Name
+	}
+	action := "DO NOTHING"
+	if len(u.Update) > 0 {
+		set := make([]string, len(u.Update))
+		for i, f := range u.Update {
+			set[i] = fmt.Sprintf("%s = excluded.%s", f.Name, f.Name)
+		}
+		action = "DO UPDATE SET " + strings.Join(set, ", ")
+	}
+
+	sqlStr := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) %s",
+		u.TableName,
+		strings.Join(u.ColumnNames(), ","),
+		strings.Join(u.BindNames, ","),
+		strings.Join(conflict, ","),
+		action)
+	return sqlStr, u.BindArgs, nil
+}
@@ -0,0 +1,26 @@
+package db2
+
+import (
+	"errors"
+
+	"github.com/rbastic/dyndao/adapters/common"
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// AlterTable renders the changes in d as ALTER TABLE statements. DB2 may
+// require the table to be reorganized (REORG TABLE) after some of them.
+// UNIQUE constraints declared inline by CREATE TABLE are given generated
+// names, so removing uniqueness isn't supported and returns an error.
+func AlterTable(g *sg.SQLGenerator, sch *schema.Schema, d *schema.TableDiff) ([]string, error) {
+	for _, cd := range d.ChangedColumns {
+		if cd.UniqueChanged && !cd.New.IsUnique {
+			return nil, errors.New("dyndao: db2 cannot remove the unnamed UNIQUE constraint of column " + cd.New.Name + " of table " + d.Name)
+		}
+	}
+	return common.AlterTable(g, d, columnType)
+}
+
+func columnType(f *schema.Column) string {
+	return common.ColumnType(f, mapType)
+}
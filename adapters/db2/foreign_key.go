@@ -0,0 +1,27 @@
+package db2
+
+import (
+	"errors"
+
+	"github.com/rbastic/dyndao/adapters/common"
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// RenderForeignKey renders a FOREIGN KEY table constraint. DB2 allows only
+// NO ACTION and RESTRICT on update, and has no SET DEFAULT action.
+func RenderForeignKey(g *sg.SQLGenerator, s *schema.Schema, ref *schema.Reference) (string, error) {
+	return common.RenderForeignKey(s, ref, fkAction)
+}
+
+func fkAction(a schema.FKAction, onUpdate bool) (string, error) {
+	switch a {
+	case schema.FKDefault, schema.FKNoAction, schema.FKRestrict:
+		return string(a), nil
+	case schema.FKCascade, schema.FKSetNull:
+		if !onUpdate {
+			return string(a), nil
+		}
+	}
+	return "", errors.New("dyndao: db2 does not support foreign key action " + string(a))
+}
@@ -0,0 +1,23 @@
+package db2
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rbastic/dyndao/adapters/core"
+	"github.com/rbastic/dyndao/schema"
+)
+
+// BindingInsertManySQL renders a multi-row INSERT. Unless the caller supplies
+// the primary key, it is wrapped in a FINAL TABLE select so that the identity
+// column of each row is returned, in the order the rows were given.
+func BindingInsertManySQL(schTable *schema.Table, tableName string, colNames []string, rowBindNames [][]string, identityCol string) string {
+	insertStr := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
+		tableName,
+		strings.Join(colNames, ","),
+		core.RenderValuesRows(rowBindNames))
+	if schTable.CallerSuppliesPK {
+		return insertStr
+	}
+	return fmt.Sprintf("SELECT %s FROM FINAL TABLE (%s) ORDER BY INPUT SEQUENCE", identityCol, insertStr)
+}
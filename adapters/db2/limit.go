@@ -0,0 +1,12 @@
+package db2
+
+import (
+	"github.com/rbastic/dyndao/adapters/common"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// RenderLimitOffset renders OFFSET ... FETCH, which requires DB2 11.1 or
+// later.
+func RenderLimitOffset(g *sg.SQLGenerator, limit int, offset int, hasOrderBy bool) string {
+	return common.RenderOffsetFetch(limit, offset)
+}
@@ -17,10 +17,16 @@ func New(g *sg.SQLGenerator) *sg.SQLGenerator {
 	g.IsFloatingType = sg.FnIsFloatingType(IsFloatingType)
 	g.IsTimestampType = sg.FnIsTimestampType(IsTimestampType)
 	g.IsLOBType = sg.FnIsLOBType(IsLOBType)
+	g.CurrentTimestamp = sg.FnCurrentTimestamp(CurrentTimestamp)
 	g.RenderCreateColumn = sg.FnRenderCreateColumn(RenderCreateColumn)
+	g.RenderForeignKey = sg.FnRenderForeignKey(RenderForeignKey)
+	g.AlterTable = sg.FnAlterTable(AlterTable)
+	g.RenderLimitOffset = sg.FnRenderLimitOffset(RenderLimitOffset)
 	g.BindingInsertSQL = sg.FnBindingInsertSQL(BindingInsertSQL)
+	g.BindingInsertManySQL = sg.FnBindingInsertManySQL(BindingInsertManySQL)
 	g.DynamicObjectSetter = sg.FnDynamicObjectSetter(DynamicObjectSetter)
 	g.MakeColumnPointers = sg.FnMakeColumnPointers(MakeColumnPointers)
 	//	g.RenderBindingValueWithInt = sg.FnRenderBindingValueWithInt(RenderBindingValueWithInt)
+	g.BindingUpsert = sg.FnBindingUpsert(BindingUpsert)
 	return g
 }
@@ -0,0 +1,38 @@
+package db2
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rbastic/dyndao/adapters/common"
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// BindingUpsert generates a MERGE statement that inserts data, or updates
+// the existing row that matches it on conflictColumns.
+func BindingUpsert(g *sg.SQLGenerator, sch *schema.Schema, table string, data map[string]interface{}, conflictColumns []string) (string, []interface{}, error) {
+	u, err := common.PrepareUpsert(g, sch, table, data, conflictColumns)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// DB2 can't infer the type of a parameter marker in a VALUES list,
+	// so each one is cast to its column's type.
+	values := make([]string, len(u.Columns))
+	for i, f := range u.Columns {
+		values[i] = u.BindNames[i]
+		if values[i] == "?" {
+			dataType := mapType(f.DBType)
+			if f.Length > 0 {
+				dataType = fmt.Sprintf("%s(%d)", dataType, f.Length)
+			}
+			values[i] = fmt.Sprintf("CAST(? AS %s)", dataType)
+		}
+	}
+	source := fmt.Sprintf("(VALUES (%s)) AS source (%s)",
+		strings.Join(values, ", "),
+		strings.Join(u.ColumnNames(), ", "))
+
+	return common.RenderMerge(u, "AS target", source), u.BindArgs, nil
+}
@@ -0,0 +1,46 @@
+package mssql
+
+import (
+	"errors"
+
+	"github.com/rbastic/dyndao/adapters/common"
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// AlterTable renders the changes in d as ALTER TABLE statements. UNIQUE
+// constraints added here are named UQ_table_column. Those declared inline
+// by CREATE TABLE are given generated names, so removing uniqueness isn't
+// supported and returns an error.
+func AlterTable(g *sg.SQLGenerator, sch *schema.Schema, d *schema.TableDiff) ([]string, error) {
+	tableName := schema.GetTableName(d.New.Name, d.Name)
+	alter := "ALTER TABLE " + tableName + " "
+
+	var stmts []string
+	for _, f := range d.AddedColumns {
+		stmts = append(stmts, alter+"ADD "+g.RenderCreateColumn(g, f))
+	}
+	for _, cd := range d.ChangedColumns {
+		if err := common.CheckIdentityUnchanged(d, cd); err != nil {
+			return nil, err
+		}
+		name := cd.New.Name
+		if cd.TypeChanged || cd.LengthChanged || cd.NullChanged {
+			notNull := "NOT NULL"
+			if cd.New.AllowNull {
+				notNull = "NULL"
+			}
+			stmts = append(stmts, alter+"ALTER COLUMN "+name+" "+common.ColumnType(cd.New, mapType)+" "+notNull)
+		}
+		if cd.UniqueChanged {
+			if !cd.New.IsUnique {
+				return nil, errors.New("dyndao: mssql cannot remove the unnamed UNIQUE constraint of column " + name + " of table " + d.Name)
+			}
+			stmts = append(stmts, alter+"ADD CONSTRAINT UQ_"+tableName+"_"+name+" UNIQUE ("+name+")")
+		}
+	}
+	for _, f := range d.RemovedColumns {
+		stmts = append(stmts, alter+"DROP COLUMN "+f.Name)
+	}
+	return stmts, nil
+}
@@ -0,0 +1,21 @@
+package mssql
+
+import (
+	"github.com/rbastic/dyndao/adapters/common"
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// RenderForeignKey renders a FOREIGN KEY table constraint. SQL Server has
+// no RESTRICT action, so NO ACTION, which differs only in when the check
+// is made, is used instead.
+func RenderForeignKey(g *sg.SQLGenerator, s *schema.Schema, ref *schema.Reference) (string, error) {
+	return common.RenderForeignKey(s, ref, fkAction)
+}
+
+func fkAction(a schema.FKAction, onUpdate bool) (string, error) {
+	if a == schema.FKRestrict {
+		return string(schema.FKNoAction), nil
+	}
+	return string(a), nil
+}
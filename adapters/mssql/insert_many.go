@@ -0,0 +1,26 @@
+package mssql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rbastic/dyndao/adapters/core"
+	"github.com/rbastic/dyndao/schema"
+)
+
+// BindingInsertManySQL renders a multi-row INSERT, with an OUTPUT clause for
+// the identity column of each row unless the caller supplies the primary
+// key. SQL Server doesn't return OUTPUT rows in any particular order, so
+// InsertMany only renders single rows here when it needs the keys. Note
+// that SQL Server accepts at most 1000 rows in a VALUES list.
+func BindingInsertManySQL(schTable *schema.Table, tableName string, colNames []string, rowBindNames [][]string, identityCol string) string {
+	output := ""
+	if !schTable.CallerSuppliesPK {
+		output = " OUTPUT INSERTED." + identityCol
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s)%s VALUES %s",
+		tableName,
+		strings.Join(colNames, ","),
+		output,
+		core.RenderValuesRows(rowBindNames))
+}
@@ -0,0 +1,17 @@
+package mssql
+
+import (
+	"github.com/rbastic/dyndao/adapters/common"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// RenderLimitOffset renders OFFSET ... FETCH. SQL Server will only accept it
+// after an ORDER BY, so we supply a no-op ordering when the caller didn't
+// ask for one.
+func RenderLimitOffset(g *sg.SQLGenerator, limit int, offset int, hasOrderBy bool) string {
+	s := common.RenderOffsetFetch(limit, offset)
+	if s != "" && !hasOrderBy {
+		s = "ORDER BY (SELECT NULL) " + s
+	}
+	return s
+}
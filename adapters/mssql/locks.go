@@ -0,0 +1,23 @@
+package mssql
+
+import (
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// GetLock takes an exclusive application lock owned by the session with
+// sp_getapplock, waiting for as long as the session's LOCK_TIMEOUT allows.
+func GetLock(g *sg.SQLGenerator, sch *schema.Schema, lockStr string) (string, []interface{}, error) {
+	sqlStr := "DECLARE @r int; " +
+		"EXEC @r = sp_getapplock @Resource = ?, @LockMode = 'Exclusive', @LockOwner = 'Session'; " +
+		"SELECT CASE WHEN @r >= 0 THEN 1 ELSE 0 END"
+	return sqlStr, []interface{}{lockStr}, nil
+}
+
+// ReleaseLock releases a lock taken by GetLock with sp_releaseapplock.
+func ReleaseLock(g *sg.SQLGenerator, sch *schema.Schema, lockStr string) (string, []interface{}, error) {
+	sqlStr := "DECLARE @r int; " +
+		"EXEC @r = sp_releaseapplock @Resource = ?, @LockOwner = 'Session'; " +
+		"SELECT CASE WHEN @r = 0 THEN 1 ELSE 0 END"
+	return sqlStr, []interface{}{lockStr}, nil
+}
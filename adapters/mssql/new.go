@@ -2,6 +2,7 @@ package mssql
 
 import (
 	//_ "github.com/denisenkom/go-mssqldb"
+	"github.com/rbastic/dyndao/adapters/core"
 	sg "github.com/rbastic/dyndao/sqlgen"
 )
 
@@ -18,6 +19,17 @@ func New(g *sg.SQLGenerator) *sg.SQLGenerator {
 	g.IsFloatingType = sg.FnIsFloatingType(IsFloatingType)
 	g.IsTimestampType = sg.FnIsTimestampType(IsTimestampType)
 	g.IsLOBType = sg.FnIsLOBType(IsLOBType)
+	g.CurrentTimestamp = sg.FnCurrentTimestamp(CurrentTimestamp)
 	g.RenderCreateColumn = sg.FnRenderCreateColumn(RenderCreateColumn)
+	g.RenderForeignKey = sg.FnRenderForeignKey(RenderForeignKey)
+	g.AlterTable = sg.FnAlterTable(AlterTable)
+	g.GetLock = sg.FnGetLock(GetLock)
+	g.ReleaseLock = sg.FnReleaseLock(ReleaseLock)
+	g.CreateIndex = sg.FnCreateIndex(core.CreatePartialIndex)
+	g.DropIndex = sg.FnDropIndex(core.DropTableIndex)
+	g.RenderLimitOffset = sg.FnRenderLimitOffset(RenderLimitOffset)
+	g.RenderOrderBy = sg.FnRenderOrderBy(RenderOrderBy)
+	g.BindingInsertManySQL = sg.FnBindingInsertManySQL(BindingInsertManySQL)
+	g.BindingUpsert = sg.FnBindingUpsert(BindingUpsert)
 	return g
 }
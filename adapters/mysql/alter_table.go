@@ -0,0 +1,49 @@
+package mysql
+
+import (
+	"github.com/rbastic/dyndao/adapters/common"
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// AlterTable renders the changes in d as ALTER TABLE statements. Columns are
+// changed with MODIFY COLUMN, which leaves their UNIQUE index in place. That
+// index is named after the column, as MySQL does when UNIQUE is declared
+// inline.
+func AlterTable(g *sg.SQLGenerator, sch *schema.Schema, d *schema.TableDiff) ([]string, error) {
+	tableName := schema.GetTableName(d.New.Name, d.Name)
+	alter := "ALTER TABLE " + tableName + " "
+
+	var stmts []string
+	for _, f := range d.AddedColumns {
+		stmts = append(stmts, alter+"ADD COLUMN "+g.RenderCreateColumn(g, f))
+	}
+	for _, cd := range d.ChangedColumns {
+		if err := common.CheckIdentityUnchanged(d, cd); err != nil {
+			return nil, err
+		}
+		name := cd.New.Name
+		if cd.TypeChanged || cd.LengthChanged || cd.NullChanged {
+			// The column keeps its PRIMARY KEY, which mustn't be repeated
+			f := *cd.New
+			f.IsUnique = false
+			identityStr := ""
+			if f.IsIdentity {
+				f.AllowNull = false
+				identityStr = "AUTO_INCREMENT"
+			}
+			stmts = append(stmts, alter+"MODIFY COLUMN "+common.RenderCreateColumn(g, &f, identityStr, mapType))
+		}
+		if cd.UniqueChanged {
+			if cd.New.IsUnique {
+				stmts = append(stmts, alter+"ADD UNIQUE "+name+" ("+name+")")
+			} else {
+				stmts = append(stmts, alter+"DROP INDEX "+name)
+			}
+		}
+	}
+	for _, f := range d.RemovedColumns {
+		stmts = append(stmts, alter+"DROP COLUMN "+f.Name)
+	}
+	return stmts, nil
+}
@@ -35,5 +35,6 @@ func GetSQLGen() *sg.SQLGenerator {
 }
 
 func TestMain(t *testing.T) {
+	test.SetTestGetLock(true)
 	test.Test(t, GetDB, GetSQLGen)
 }
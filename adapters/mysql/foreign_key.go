@@ -0,0 +1,32 @@
+package mysql
+
+import (
+	"errors"
+
+	"github.com/rbastic/dyndao/adapters/common"
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// RenderForeignKey renders a FOREIGN KEY table constraint. InnoDB rejects
+// the SET DEFAULT action.
+func RenderForeignKey(g *sg.SQLGenerator, s *schema.Schema, ref *schema.Reference) (string, error) {
+	return common.RenderForeignKey(s, ref, fkAction)
+}
+
+func fkAction(a schema.FKAction, onUpdate bool) (string, error) {
+	if a == schema.FKSetDefault {
+		return "", errors.New("dyndao: mysql does not support foreign key action " + string(a))
+	}
+	return string(a), nil
+}
+
+// DropForeignKey renders an ALTER TABLE statement dropping a FOREIGN KEY
+// constraint, which MySQL calls DROP FOREIGN KEY.
+func DropForeignKey(g *sg.SQLGenerator, s *schema.Schema, ref *schema.Reference) (string, error) {
+	tbl, ok := s.Tables[ref.Table]
+	if !ok {
+		return "", errors.New("dyndao: unknown schema for table with name " + ref.Table)
+	}
+	return "ALTER TABLE " + schema.GetTableName(tbl.Name, ref.Table) + " DROP FOREIGN KEY " + ref.Name, nil
+}
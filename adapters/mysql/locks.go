@@ -0,0 +1,18 @@
+package mysql
+
+import (
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// GetLock takes a named lock with GET_LOCK, waiting for as long as it takes.
+// The lock belongs to the session, and MySQL limits its name to 64
+// characters.
+func GetLock(g *sg.SQLGenerator, sch *schema.Schema, lockStr string) (string, []interface{}, error) {
+	return "SELECT GET_LOCK(?, -1)", []interface{}{lockStr}, nil
+}
+
+// ReleaseLock releases a lock taken by GetLock with RELEASE_LOCK.
+func ReleaseLock(g *sg.SQLGenerator, sch *schema.Schema, lockStr string) (string, []interface{}, error) {
+	return "SELECT RELEASE_LOCK(?)", []interface{}{lockStr}, nil
+}
@@ -1,6 +1,7 @@
 package mysql
 
 import (
+	"github.com/rbastic/dyndao/adapters/core"
 	sg "github.com/rbastic/dyndao/sqlgen"
 )
 
@@ -15,6 +16,15 @@ func New(g *sg.SQLGenerator) *sg.SQLGenerator {
 	g.IsFloatingType = sg.FnIsFloatingType(IsFloatingType)
 	g.IsTimestampType = sg.FnIsTimestampType(IsTimestampType)
 	g.IsLOBType = sg.FnIsLOBType(IsLOBType)
+	g.CurrentTimestamp = sg.FnCurrentTimestamp(CurrentTimestamp)
 	g.RenderCreateColumn = sg.FnRenderCreateColumn(RenderCreateColumn)
+	g.RenderForeignKey = sg.FnRenderForeignKey(RenderForeignKey)
+	g.DropForeignKey = sg.FnDropForeignKey(DropForeignKey)
+	g.AlterTable = sg.FnAlterTable(AlterTable)
+	g.DropIndex = sg.FnDropIndex(core.DropTableIndex)
+	g.GetLock = sg.FnGetLock(GetLock)
+	g.ReleaseLock = sg.FnReleaseLock(ReleaseLock)
+	g.RenderOrderBy = sg.FnRenderOrderBy(RenderOrderBy)
+	g.BindingUpsert = sg.FnBindingUpsert(BindingUpsert)
 	return g
 }
@@ -0,0 +1,14 @@
+package mysql
+
+import (
+	"github.com/rbastic/dyndao/adapters/common"
+	"github.com/rbastic/dyndao/query"
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// RenderOrderBy renders an ORDER BY clause for orders. There is no NULLS
+// FIRST / NULLS LAST syntax, so it is emulated.
+func RenderOrderBy(g *sg.SQLGenerator, schTable *schema.Table, orders []query.Order) (string, error) {
+	return common.RenderOrderByEmulatingNulls(schTable, orders)
+}
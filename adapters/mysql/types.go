@@ -1,5 +1,7 @@
 package mysql
 
+import "strings"
+
 var stringTypes = map[string]bool{
 	"VARSTRING": true,
 	"VARCHAR2":  true,
@@ -34,6 +36,31 @@ var lobTypes = map[string]bool{
 	"blob": true,
 }
 
+// typeAliases maps the other spellings MySQL accepts for column types to the
+// types it stores them as, and reports in its INFORMATION_SCHEMA.
+var typeAliases = map[string]string{
+	"INTEGER":           "INT",
+	"BOOL":              "TINYINT",
+	"BOOLEAN":           "TINYINT",
+	"DEC":               "DECIMAL",
+	"NUMERIC":           "DECIMAL",
+	"FIXED":             "DECIMAL",
+	"REAL":              "DOUBLE",
+	"DOUBLE PRECISION":  "DOUBLE",
+	"CHARACTER":         "CHAR",
+	"CHARACTER VARYING": "VARCHAR",
+}
+
+// NormalizeType returns the type, upper-cased, that MySQL stores a column
+// declared as dbType as, so that types spelled differently can be compared.
+func NormalizeType(dbType string) string {
+	t := strings.ToUpper(dbType)
+	if alias, ok := typeAliases[t]; ok {
+		return alias
+	}
+	return t
+}
+
 // IsStringType can be used to help determine whether a certain data type is a string type.
 // Note that it is case-sensitive.
 func IsStringType(k string) bool {
@@ -61,3 +88,8 @@ func IsTimestampType(k string) bool {
 func IsLOBType(k string) bool {
 	return lobTypes[k]
 }
+
+// CurrentTimestamp returns the SQL for the current UTC time.
+func CurrentTimestamp() string {
+	return "UTC_TIMESTAMP()"
+}
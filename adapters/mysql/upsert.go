@@ -0,0 +1,37 @@
+package mysql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rbastic/dyndao/adapters/common"
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// BindingUpsert generates an INSERT ... ON DUPLICATE KEY UPDATE statement.
+// MySQL can't be told which unique key to check, so conflictColumns are only
+// validated: any unique key that data collides with triggers the update.
+func BindingUpsert(g *sg.SQLGenerator, sch *schema.Schema, table string, data map[string]interface{}, conflictColumns []string) (string, []interface{}, error) {
+	u, err := common.PrepareUpsert(g, sch, table, data, conflictColumns)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var set []string
+	for _, f := range u.Update {
+		set = append(set, fmt.Sprintf("%s = VALUES(%s)", f.Name, f.Name))
+	}
+	if len(set) == 0 {
+		// Nothing to update, so make the update a no-op
+		f := u.Conflict[0]
+		set = append(set, fmt.Sprintf("%s = %s", f.Name, f.Name))
+	}
+
+	sqlStr := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON DUPLICATE KEY UPDATE %s",
+		u.TableName,
+		strings.Join(u.ColumnNames(), ","),
+		strings.Join(u.BindNames, ","),
+		strings.Join(set, ", "))
+	return sqlStr, u.BindArgs, nil
+}
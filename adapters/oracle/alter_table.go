@@ -0,0 +1,50 @@
+package oracle
+
+import (
+	"github.com/rbastic/dyndao/adapters/common"
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// AlterTable renders the changes in d as ALTER TABLE statements.
+func AlterTable(g *sg.SQLGenerator, sch *schema.Schema, d *schema.TableDiff) ([]string, error) {
+	tableName := schema.GetTableName(d.New.Name, d.Name)
+	alter := "ALTER TABLE " + tableName + " "
+
+	var stmts []string
+	for _, f := range d.AddedColumns {
+		stmts = append(stmts, alter+"ADD ("+g.RenderCreateColumn(g, f)+")")
+	}
+	for _, cd := range d.ChangedColumns {
+		if err := common.CheckIdentityUnchanged(d, cd); err != nil {
+			return nil, err
+		}
+		name := cd.New.Name
+		if cd.TypeChanged || cd.LengthChanged || cd.NullChanged {
+			modify := name
+			if cd.TypeChanged || cd.LengthChanged {
+				modify += " " + common.ColumnType(cd.New, mapType)
+			}
+			// Oracle rejects a MODIFY that doesn't change the nullability
+			if cd.NullChanged {
+				if cd.New.AllowNull {
+					modify += " NULL"
+				} else {
+					modify += " NOT NULL"
+				}
+			}
+			stmts = append(stmts, alter+"MODIFY ("+modify+")")
+		}
+		if cd.UniqueChanged {
+			if cd.New.IsUnique {
+				stmts = append(stmts, alter+"ADD UNIQUE ("+name+")")
+			} else {
+				stmts = append(stmts, alter+"DROP UNIQUE ("+name+")")
+			}
+		}
+	}
+	for _, f := range d.RemovedColumns {
+		stmts = append(stmts, alter+"DROP COLUMN "+f.Name)
+	}
+	return stmts, nil
+}
@@ -43,8 +43,12 @@ func RenderCreateColumn(sg *sg.SQLGenerator, f *schema.Column) string {
 
 func mapType(s string) string {
 	switch s {
+	case "INTEGER":
+		fallthrough
 	case "integer":
 		return "NUMBER"
+	case "TEXT":
+		fallthrough
 	case "text":
 		return "CLOB"
 	case "VARCHAR":
@@ -0,0 +1,28 @@
+package oracle
+
+import (
+	"errors"
+
+	"github.com/rbastic/dyndao/adapters/common"
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// RenderForeignKey renders a FOREIGN KEY table constraint. Oracle has no ON
+// UPDATE actions, and only CASCADE and SET NULL on delete; NO ACTION and
+// RESTRICT are what it does by default, so they are left out.
+func RenderForeignKey(g *sg.SQLGenerator, s *schema.Schema, ref *schema.Reference) (string, error) {
+	return common.RenderForeignKey(s, ref, fkAction)
+}
+
+func fkAction(a schema.FKAction, onUpdate bool) (string, error) {
+	switch a {
+	case schema.FKDefault, schema.FKNoAction, schema.FKRestrict:
+		return "", nil
+	case schema.FKCascade, schema.FKSetNull:
+		if !onUpdate {
+			return string(a), nil
+		}
+	}
+	return "", errors.New("dyndao: oracle does not support foreign key action " + string(a))
+}
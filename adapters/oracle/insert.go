@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/rbastic/dyndao/object"
 	"github.com/rbastic/dyndao/schema"
@@ -62,6 +63,10 @@ func RenderInsertValue(bindI *int, f *schema.Column, value interface{}) (interfa
 		}
 		// TODO: when we support more than regular integers, we'll need to care about this more
 		return sql.Named(fName, fmt.Sprintf("%f", num)), nil
+	case time.Time:
+		return sql.Named(fName, value), nil
+	case *time.Time:
+		return sql.Named(fName, *value.(*time.Time)), nil
 	case *object.SQLValue:
 		val := value.(*object.SQLValue)
 		return sql.Named(fName, val.String()), nil
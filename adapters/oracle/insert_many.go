@@ -0,0 +1,20 @@
+package oracle
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rbastic/dyndao/schema"
+)
+
+// BindingInsertManySQL renders an INSERT ALL statement, since Oracle has no
+// multi-row VALUES. There is no RETURNING INTO for INSERT ALL, so identity
+// values are not reported back.
+func BindingInsertManySQL(schTable *schema.Table, tableName string, colNames []string, rowBindNames [][]string, identityCol string) string {
+	cols := strings.Join(colNames, ",")
+	intos := make([]string, len(rowBindNames))
+	for i, bindNames := range rowBindNames {
+		intos[i] = fmt.Sprintf("INTO %s (%s) VALUES (%s)", tableName, cols, strings.Join(bindNames, ","))
+	}
+	return fmt.Sprintf("INSERT ALL %s SELECT 1 FROM DUAL", strings.Join(intos, " "))
+}
@@ -19,14 +19,20 @@ func New(g *sg.SQLGenerator) *sg.SQLGenerator {
 	g.IsFloatingType = sg.FnIsFloatingType(IsFloatingType)
 	g.IsTimestampType = sg.FnIsTimestampType(IsTimestampType)
 	g.IsLOBType = sg.FnIsLOBType(IsLOBType)
+	g.CurrentTimestamp = sg.FnCurrentTimestamp(CurrentTimestamp)
 	g.DynamicObjectSetter = sg.FnDynamicObjectSetter(DynamicObjectSetter)
 	g.MakeColumnPointers = sg.FnMakeColumnPointers(MakeColumnPointers)
 	g.RenderCreateColumn = sg.FnRenderCreateColumn(RenderCreateColumn)
+	g.RenderForeignKey = sg.FnRenderForeignKey(RenderForeignKey)
+	g.AlterTable = sg.FnAlterTable(AlterTable)
 	g.RenderInsertValue = sg.FnRenderInsertValue(RenderInsertValue)
 	g.BindingInsertSQL = sg.FnBindingInsertSQL(BindingInsertSQL)
+	g.BindingInsertManySQL = sg.FnBindingInsertManySQL(BindingInsertManySQL)
 	g.RenderBindingValueWithInt = sg.FnRenderBindingValueWithInt(RenderBindingValueWithInt)
+	g.RenderLimitOffset = sg.FnRenderLimitOffset(RenderLimitOffset)
 
 	g.GetLock = sg.FnGetLock(GetLock)
 	g.ReleaseLock = sg.FnReleaseLock(ReleaseLock)
+	g.BindingUpsert = sg.FnBindingUpsert(BindingUpsert)
 	return g
 }
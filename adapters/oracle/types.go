@@ -55,3 +55,8 @@ func IsTimestampType(k string) bool {
 func IsLOBType(k string) bool {
 	return lobTypes[k]
 }
+
+// CurrentTimestamp returns the SQL for the current UTC time.
+func CurrentTimestamp() string {
+	return "SYS_EXTRACT_UTC(SYSTIMESTAMP)"
+}
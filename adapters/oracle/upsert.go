@@ -0,0 +1,26 @@
+package oracle
+
+import (
+	"strings"
+
+	"github.com/rbastic/dyndao/adapters/common"
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// BindingUpsert generates a MERGE statement that inserts data, or updates
+// the existing row that matches it on conflictColumns.
+func BindingUpsert(g *sg.SQLGenerator, sch *schema.Schema, table string, data map[string]interface{}, conflictColumns []string) (string, []interface{}, error) {
+	u, err := common.PrepareUpsert(g, sch, table, data, conflictColumns)
+	if err != nil {
+		return "", nil, err
+	}
+
+	cols := make([]string, len(u.Columns))
+	for i, f := range u.Columns {
+		cols[i] = u.BindNames[i] + " " + f.Name
+	}
+	source := "(SELECT " + strings.Join(cols, ", ") + " FROM DUAL) source"
+
+	return common.RenderMerge(u, "target", source), u.BindArgs, nil
+}
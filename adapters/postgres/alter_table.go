@@ -0,0 +1,16 @@
+package postgres
+
+import (
+	"github.com/rbastic/dyndao/adapters/common"
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// AlterTable renders the changes in d as ALTER TABLE statements.
+func AlterTable(g *sg.SQLGenerator, sch *schema.Schema, d *schema.TableDiff) ([]string, error) {
+	return common.AlterTable(g, d, columnType)
+}
+
+func columnType(f *schema.Column) string {
+	return common.ColumnType(f, mapType)
+}
@@ -45,5 +45,6 @@ func GetSQLGen() *sg.SQLGenerator {
 }
 
 func TestMain(t *testing.T) {
+	test.SetTestGetLock(true)
 	test.Test(t, GetDB, GetSQLGen)
 }
@@ -0,0 +1,22 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rbastic/dyndao/adapters/core"
+	"github.com/rbastic/dyndao/schema"
+)
+
+// BindingInsertManySQL renders a multi-row INSERT, RETURNING the identity
+// column of each row unless the caller supplies the primary key.
+func BindingInsertManySQL(schTable *schema.Table, tableName string, colNames []string, rowBindNames [][]string, identityCol string) string {
+	sqlStr := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
+		tableName,
+		strings.Join(colNames, ","),
+		core.RenderValuesRows(rowBindNames))
+	if !schTable.CallerSuppliesPK {
+		sqlStr += " RETURNING " + identityCol
+	}
+	return sqlStr
+}
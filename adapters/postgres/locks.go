@@ -0,0 +1,26 @@
+package postgres
+
+import (
+	"hash/fnv"
+
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// GetLock takes a session level advisory lock with pg_advisory_lock, keyed
+// by a hash of lockStr, waiting for as long as it takes.
+func GetLock(g *sg.SQLGenerator, sch *schema.Schema, lockStr string) (string, []interface{}, error) {
+	return "SELECT COUNT(*) FROM (SELECT pg_advisory_lock($1)) AS dyndao_lock", []interface{}{lockKey(lockStr)}, nil
+}
+
+// ReleaseLock releases a lock taken by GetLock with pg_advisory_unlock.
+func ReleaseLock(g *sg.SQLGenerator, sch *schema.Schema, lockStr string) (string, []interface{}, error) {
+	return "SELECT CASE WHEN pg_advisory_unlock($1) THEN 1 ELSE 0 END", []interface{}{lockKey(lockStr)}, nil
+}
+
+// lockKey maps a lock name to the bigint key advisory locks are taken on.
+func lockKey(lockStr string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(lockStr))
+	return int64(h.Sum64())
+}
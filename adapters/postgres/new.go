@@ -2,6 +2,7 @@ package postgres
 
 import (
 	//_ "github.com/denisenkom/go-postgresdb"
+	"github.com/rbastic/dyndao/adapters/core"
 	sg "github.com/rbastic/dyndao/sqlgen"
 )
 
@@ -17,8 +18,14 @@ func New(g *sg.SQLGenerator) *sg.SQLGenerator {
 	g.IsFloatingType = sg.FnIsFloatingType(IsFloatingType)
 	g.IsTimestampType = sg.FnIsTimestampType(IsTimestampType)
 	g.IsLOBType = sg.FnIsLOBType(IsLOBType)
+	g.CurrentTimestamp = sg.FnCurrentTimestamp(CurrentTimestamp)
 	g.BindingInsertSQL = sg.FnBindingInsertSQL(BindingInsertSQL)
+	g.BindingInsertManySQL = sg.FnBindingInsertManySQL(BindingInsertManySQL)
 	g.RenderCreateColumn = sg.FnRenderCreateColumn(RenderCreateColumn)
+	g.AlterTable = sg.FnAlterTable(AlterTable)
+	g.GetLock = sg.FnGetLock(GetLock)
+	g.ReleaseLock = sg.FnReleaseLock(ReleaseLock)
+	g.CreateIndex = sg.FnCreateIndex(core.CreatePartialIndex)
 	g.RenderBindingValueWithInt = sg.FnRenderBindingValueWithInt(RenderBindingValueWithInt)
 	g.BindingUpdate = sg.FnBindingUpdate(BindingUpdate)
 	g.DynamicObjectSetter = sg.FnDynamicObjectSetter(DynamicObjectSetter)
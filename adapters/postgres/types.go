@@ -93,3 +93,8 @@ func IsTimestampType(k string) bool {
 func IsLOBType(k string) bool {
 	return lobTypes[k]
 }
+
+// CurrentTimestamp returns the SQL for the current UTC time.
+func CurrentTimestamp() string {
+	return "(now() AT TIME ZONE 'utc')"
+}
@@ -0,0 +1,33 @@
+package sqlite
+
+import (
+	"errors"
+
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// AlterTable renders the changes in d as ALTER TABLE statements. SQLite can
+// only add and (since 3.35.0) drop columns, and can't add columns that are
+// UNIQUE, identity or NOT NULL without a default, so an error is returned
+// for those and for any change to an existing column. Such tables must be
+// rebuilt instead.
+func AlterTable(g *sg.SQLGenerator, sch *schema.Schema, d *schema.TableDiff) ([]string, error) {
+	tableName := schema.GetTableName(d.New.Name, d.Name)
+	alter := "ALTER TABLE " + tableName + " "
+
+	var stmts []string
+	for _, f := range d.AddedColumns {
+		if f.IsUnique || f.IsIdentity || !f.AllowNull {
+			return nil, errors.New("dyndao: sqlite cannot add UNIQUE, identity or NOT NULL column " + f.Name + " to table " + d.Name)
+		}
+		stmts = append(stmts, alter+"ADD COLUMN "+g.RenderCreateColumn(g, f))
+	}
+	if len(d.ChangedColumns) > 0 {
+		return nil, errors.New("dyndao: sqlite cannot alter column " + d.ChangedColumns[0].New.Name + " of table " + d.Name)
+	}
+	for _, f := range d.RemovedColumns {
+		stmts = append(stmts, alter+"DROP COLUMN "+f.Name)
+	}
+	return stmts, nil
+}
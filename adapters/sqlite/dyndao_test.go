@@ -14,7 +14,9 @@ import (
 
 var (
 	// TODO: refactor this so it's available from somewhere else
-	defaultDSN = "file::memory:?mode=memory&cache=shared"
+	// SQLite only enforces FOREIGN KEY constraints when asked to, for each
+	// connection, which _foreign_keys=1 does.
+	defaultDSN = "file::memory:?mode=memory&cache=shared&_foreign_keys=1"
 )
 
 func GetDB() *sql.DB {
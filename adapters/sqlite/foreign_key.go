@@ -0,0 +1,20 @@
+package sqlite
+
+import (
+	"errors"
+
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// AddForeignKey returns an error, since SQLite can't add a FOREIGN KEY
+// constraint to an existing table. The table must be rebuilt instead.
+func AddForeignKey(g *sg.SQLGenerator, s *schema.Schema, ref *schema.Reference) (string, error) {
+	return "", errors.New("dyndao: sqlite cannot add foreign key " + ref.Name + " to table " + ref.Table)
+}
+
+// DropForeignKey returns an error, since SQLite can't drop a FOREIGN KEY
+// constraint from an existing table. The table must be rebuilt instead.
+func DropForeignKey(g *sg.SQLGenerator, s *schema.Schema, ref *schema.Reference) (string, error) {
+	return "", errors.New("dyndao: sqlite cannot drop foreign key " + ref.Name + " from table " + ref.Table)
+}
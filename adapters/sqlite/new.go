@@ -1,13 +1,16 @@
 package sqlite
 
 import (
+	"github.com/rbastic/dyndao/adapters/core"
 	sg "github.com/rbastic/dyndao/sqlgen"
 )
 
 // New shows off a sort of inheritance/composition-using-vtables approach.
 // It receives the SQLGenerator composed by Core and then overrides any
 // methods that it needs to. In some instances, this could be all methods,
-// or hardly any.
+// or hardly any. SQLite only enforces the FOREIGN KEY constraints
+// CreateTable renders on connections where they've been enabled, with
+// PRAGMA foreign_keys = ON, or _foreign_keys=1 in a go-sqlite3 DSN.
 func New(g *sg.SQLGenerator) *sg.SQLGenerator {
 	// Oracle SQLGenerator uses Core for anything commented out.
 	g.IsSQLITE = true
@@ -17,6 +20,11 @@ func New(g *sg.SQLGenerator) *sg.SQLGenerator {
 	g.IsFloatingType = sg.FnIsFloatingType(IsFloatingType)
 	g.IsTimestampType = sg.FnIsTimestampType(IsTimestampType)
 	g.IsLOBType = sg.FnIsLOBType(IsLOBType)
+	g.CurrentTimestamp = sg.FnCurrentTimestamp(CurrentTimestamp)
 	g.RenderCreateColumn = sg.FnRenderCreateColumn(RenderCreateColumn)
+	g.AlterTable = sg.FnAlterTable(AlterTable)
+	g.AddForeignKey = sg.FnAddForeignKey(AddForeignKey)
+	g.DropForeignKey = sg.FnDropForeignKey(DropForeignKey)
+	g.CreateIndex = sg.FnCreateIndex(core.CreatePartialIndex)
 	return g
 }
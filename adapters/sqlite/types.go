@@ -97,3 +97,9 @@ func IsTimestampType(k string) bool {
 func IsLOBType(k string) bool {
 	return lobTypes[k]
 }
+
+// CurrentTimestamp returns the SQL for the current UTC time, which is what
+// SQLite's CURRENT_TIMESTAMP gives.
+func CurrentTimestamp() string {
+	return "CURRENT_TIMESTAMP"
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	// Database drivers for the adapters below
+	_ "github.com/denisenkom/go-mssqldb"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/rbastic/dyndao/adapters/cockroach"
+	"github.com/rbastic/dyndao/adapters/core"
+	"github.com/rbastic/dyndao/adapters/db2"
+	"github.com/rbastic/dyndao/adapters/mssql"
+	"github.com/rbastic/dyndao/adapters/mysql"
+	"github.com/rbastic/dyndao/adapters/postgres"
+	"github.com/rbastic/dyndao/adapters/sqlite"
+	"github.com/rbastic/dyndao/schema"
+	"github.com/rbastic/dyndao/schema/parser/infoschema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+// adapter ties a dyndao adapter to its database/sql driver and, where there
+// is one, the parser that can introspect its databases, along with the
+// function that spells column types the way the parser reports them.
+type adapter struct {
+	driver      string
+	newFn       func(g *sg.SQLGenerator) *sg.SQLGenerator
+	loadFn      func(ctx context.Context, db *sql.DB, dbName string) (*schema.Schema, error)
+	normalizeFn func(dbType string) string
+}
+
+// DB2 needs the odbc driver, which is only built in with the db2 build tag.
+// The Oracle adapter needs the goracle driver, so it is only available with
+// the oracle build tag (see adapters_oracle.go). The infoschema parser reads
+// MySQL's INFORMATION_SCHEMA, so it isn't used for the other databases that
+// have one.
+var adapters = map[string]adapter{
+	"sqlite":    {driver: "sqlite3", newFn: sqlite.New},
+	"mysql":     {driver: "mysql", newFn: mysql.New, loadFn: infoschema.LoadSchema, normalizeFn: mysql.NormalizeType},
+	"postgres":  {driver: "postgres", newFn: postgres.New},
+	"cockroach": {driver: "postgres", newFn: cockroach.New},
+	"mssql":     {driver: "mssql", newFn: mssql.New},
+	"db2":       {driver: "odbc", newFn: db2.New},
+}
+
+func getAdapter(name string) (adapter, error) {
+	a, ok := adapters[name]
+	if !ok {
+		return adapter{}, errors.New("unknown adapter " + name)
+	}
+	return a, nil
+}
+
+// sqlGen returns the adapter's SQL generator, composed over core's.
+func (a adapter) sqlGen() *sg.SQLGenerator {
+	g := a.newFn(core.New())
+	sg.PanicIfInvalid(g)
+	return g
+}
+
+func (a adapter) open(dsn string) (*sql.DB, error) {
+	if dsn == "" {
+		return nil, errors.New("a -dsn is required")
+	}
+	return sql.Open(a.driver, dsn)
+}
+
+func (a adapter) load(ctx context.Context, db *sql.DB, dbName string) (*schema.Schema, error) {
+	if a.loadFn == nil {
+		return nil, errors.New("introspection isn't supported for " + a.driver + " databases")
+	}
+	return a.loadFn(ctx, db, dbName)
+}
+
+// normalize rewrites the column types of sch, in place, as the adapter's
+// parser would report them, so that an introspected schema can be compared
+// with one written by hand.
+func (a adapter) normalize(sch *schema.Schema) {
+	if a.normalizeFn == nil {
+		return
+	}
+	for _, tbl := range sch.Tables {
+		for _, col := range tbl.Columns {
+			col.DBType = a.normalizeFn(col.DBType)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build db2
+// +build db2
+
+package main
+
+import (
+	// The odbc driver requires cgo and the unixODBC headers
+	_ "github.com/alexbrainman/odbc"
+)
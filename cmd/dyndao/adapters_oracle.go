@@ -0,0 +1,16 @@
+//go:build oracle
+// +build oracle
+
+package main
+
+import (
+	// The goracle driver requires cgo and the Oracle client libraries
+	_ "gopkg.in/goracle.v2"
+
+	"github.com/rbastic/dyndao/adapters/oracle"
+	oracleParser "github.com/rbastic/dyndao/schema/parser/oracle"
+)
+
+func init() {
+	adapters["oracle"] = adapter{driver: "goracle", newFn: oracle.New, loadFn: oracleParser.LoadSchema}
+}
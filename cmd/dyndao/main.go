@@ -0,0 +1,272 @@
+// Command dyndao works with dyndao schemas across databases. It can
+// introspect a live database into schema JSON, translate a schema's column
+// types from one adapter to another, print or apply the DDL for a schema,
+// and print or apply the statements that bring a live database in line with
+// a schema.
+//
+// Usage:
+//
+//	dyndao introspect -adapter mysql -dsn DSN -db NAME > schema.json
+//	dyndao translate -schema schema.json -from oracle -to mysql [-typemap FILE]
+//	dyndao ddl -schema schema.json -adapter mysql [-drop] [-apply -dsn DSN]
+//	dyndao diff -schema schema.json -adapter mysql -dsn DSN -db NAME [-drop] [-apply]
+//
+// Schemas are read from the file given with -schema, or from standard input
+// if it is "-". diff leaves tables that aren't in the schema alone unless
+// -drop is given. The oracle and db2 adapters need drivers that use cgo, so
+// they are only built in with the build tags of the same names. translate
+// works with the types of either without them.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+var commands = map[string]func(args []string) error{
+	"introspect": introspect,
+	"translate":  translate,
+	"ddl":        ddl,
+	"diff":       diff,
+}
+
+func usage() {
+	names := make([]string, 0, len(adapters))
+	for name := range adapters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(os.Stderr, "usage: dyndao introspect|translate|ddl|diff [flags]")
+	fmt.Fprintln(os.Stderr, "adapters: "+strings.Join(names, ", "))
+	fmt.Fprintln(os.Stderr, "run 'dyndao COMMAND -h' for the flags of a command")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "dyndao "+os.Args[1]+":", err)
+		os.Exit(1)
+	}
+}
+
+func introspect(args []string) error {
+	fs := flag.NewFlagSet("introspect", flag.ExitOnError)
+	adapterName := fs.String("adapter", "", "adapter of the database")
+	dsn := fs.String("dsn", "", "data source name of the database")
+	dbName := fs.String("db", "", "database (schema or tablespace) to introspect")
+	_ = fs.Parse(args)
+
+	a, err := getAdapter(*adapterName)
+	if err != nil {
+		return err
+	}
+	db, err := a.open(*dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	sch, err := a.load(context.Background(), db, *dbName)
+	if err != nil {
+		return err
+	}
+	return writeSchema(sch)
+}
+
+func translate(args []string) error {
+	fs := flag.NewFlagSet("translate", flag.ExitOnError)
+	schemaPath := fs.String("schema", "-", "schema JSON file")
+	from := fs.String("from", "", "adapter the schema's types are for")
+	to := fs.String("to", "", "adapter to translate the types for")
+	typeMapPath := fs.String("typemap", "", "JSON file of type rules to add to the defaults")
+	_ = fs.Parse(args)
+
+	tm, err := loadTypeMap(*typeMapPath)
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{*from, *to} {
+		if _, ok := adapters[name]; !ok && tm[name] == nil {
+			return fmt.Errorf("unknown adapter %s", name)
+		}
+	}
+	sch, err := readSchema(*schemaPath)
+	if err != nil {
+		return err
+	}
+	tm.Translate(sch, *from, *to)
+	return writeSchema(sch)
+}
+
+func ddl(args []string) error {
+	fs := flag.NewFlagSet("ddl", flag.ExitOnError)
+	schemaPath := fs.String("schema", "-", "schema JSON file")
+	adapterName := fs.String("adapter", "", "adapter to render the DDL for")
+	drop := fs.Bool("drop", false, "drop the tables before creating them")
+	apply := fs.Bool("apply", false, "execute the DDL rather than printing it")
+	dsn := fs.String("dsn", "", "data source name of the database, with -apply")
+	_ = fs.Parse(args)
+
+	a, err := getAdapter(*adapterName)
+	if err != nil {
+		return err
+	}
+	sch, err := readSchema(*schemaPath)
+	if err != nil {
+		return err
+	}
+	stmts, err := ddlStatements(a, sch, *drop)
+	if err != nil {
+		return err
+	}
+	return output(a, *apply, *dsn, stmts)
+}
+
+// ddlStatements renders the statements that create the tables of sch,
+// dropping them first if drop is set.
+func ddlStatements(a adapter, sch *schema.Schema, drop bool) ([]string, error) {
+	g := a.sqlGen()
+	names, err := sch.CreationOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	var stmts []string
+	if drop {
+		for i := len(names) - 1; i >= 0; i-- {
+			stmts = append(stmts, g.DropTable(schema.GetTableName(sch.Tables[names[i]].Name, names[i])))
+		}
+	}
+	for _, name := range names {
+		sqlStr, err := g.CreateTable(g, sch, name)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, sqlStr)
+
+		indexes, err := sg.RenderCreateIndexes(g, sch, name)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, indexes...)
+	}
+	return stmts, nil
+}
+
+func diff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	schemaPath := fs.String("schema", "-", "schema JSON file describing the database wanted")
+	adapterName := fs.String("adapter", "", "adapter of the database")
+	dsn := fs.String("dsn", "", "data source name of the database")
+	dbName := fs.String("db", "", "database (schema or tablespace) to compare")
+	drop := fs.Bool("drop", false, "drop the tables that aren't in the schema")
+	apply := fs.Bool("apply", false, "execute the statements rather than printing them")
+	_ = fs.Parse(args)
+
+	a, err := getAdapter(*adapterName)
+	if err != nil {
+		return err
+	}
+	sch, err := readSchema(*schemaPath)
+	if err != nil {
+		return err
+	}
+	db, err := a.open(*dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	stmts, err := diffStatements(context.Background(), a, db, *dbName, sch, *drop)
+	if err != nil {
+		return err
+	}
+	return outputTo(db, *apply, stmts)
+}
+
+// diffStatements renders the statements that bring the database dbName in
+// line with sch, dropping the tables that aren't in sch if drop is set. The
+// column types of both are normalized first, so that a type spelled
+// differently from the way the database reports it isn't taken as a change.
+func diffStatements(ctx context.Context, a adapter, db *sql.DB, dbName string, sch *schema.Schema, drop bool) ([]string, error) {
+	live, err := a.load(ctx, db, dbName)
+	if err != nil {
+		return nil, err
+	}
+	a.normalize(live)
+	a.normalize(sch)
+
+	d := schema.Diff(live, sch)
+	if !drop {
+		d.RemovedTables = nil
+	}
+	return sg.RenderSchemaDiff(a.sqlGen(), d)
+}
+
+// output prints stmts, or executes them on the database at dsn if apply is
+// set.
+func output(a adapter, apply bool, dsn string, stmts []string) error {
+	if !apply {
+		return outputTo(nil, false, stmts)
+	}
+	db, err := a.open(dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return outputTo(db, true, stmts)
+}
+
+func outputTo(db *sql.DB, apply bool, stmts []string) error {
+	for _, stmt := range stmts {
+		if !apply {
+			fmt.Println(strings.TrimSpace(stmt) + ";")
+			continue
+		}
+		if _, err := db.ExecContext(context.Background(), stmt); err != nil {
+			return fmt.Errorf("%s: %v", stmt, err)
+		}
+	}
+	return nil
+}
+
+func readSchema(path string) (*schema.Schema, error) {
+	var buf []byte
+	var err error
+	if path == "-" {
+		buf, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		buf, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return schema.FromJSONBytes(buf)
+}
+
+func writeSchema(sch *schema.Schema) error {
+	buf, err := json.MarshalIndent(sch, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Println(string(buf))
+	return err
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/rbastic/dyndao/schema"
+)
+
+// ddlDiffSchema returns a schema with a column of each kind that MySQL
+// reports differently from the way it is declared.
+func ddlDiffSchema() *schema.Schema {
+	people := schema.DefaultTable()
+	people.Name = "ddl_diff_people"
+	people.Primary = "PersonID"
+	people.Columns["PersonID"] = &schema.Column{Name: "PersonID", DBType: "integer", IsIdentity: true, IsNumber: true}
+	people.Columns["Name"] = &schema.Column{Name: "Name", DBType: "varchar", Length: 64}
+	people.Columns["Email"] = &schema.Column{Name: "Email", DBType: "VARCHAR", Length: 128, IsUnique: true}
+	people.Columns["Bio"] = &schema.Column{Name: "Bio", DBType: "text", AllowNull: true}
+	people.Columns["Active"] = &schema.Column{Name: "Active", DBType: "boolean", AllowNull: true}
+	people.Indexes = []*schema.Index{{Name: "ddl_diff_people_name", Columns: []string{"Name"}}}
+	people.EssentialColumns = []string{"PersonID", "Name", "Email", "Bio", "Active"}
+
+	addresses := schema.DefaultTable()
+	addresses.Name = "ddl_diff_addresses"
+	addresses.Primary = "AddressID"
+	addresses.Columns["AddressID"] = &schema.Column{Name: "AddressID", DBType: "integer", IsIdentity: true, IsNumber: true}
+	addresses.Columns["PersonID"] = &schema.Column{Name: "PersonID", DBType: "int", IsNumber: true}
+	addresses.Columns["City"] = &schema.Column{Name: "City", DBType: "character varying", Length: 32, AllowNull: true}
+	addresses.EssentialColumns = []string{"AddressID", "PersonID", "City"}
+
+	rel := schema.DefaultChildTable()
+	rel.ParentTable = people.Name
+	rel.ForeignKey = &schema.ForeignKey{Name: "ddl_diff_addresses_person", OnDelete: schema.FKCascade, OnUpdate: schema.FKCascade}
+	people.Children[addresses.Name] = rel
+	addresses.ParentTables = []string{people.Name}
+
+	sch := schema.DefaultSchema()
+	sch.Tables[people.Name] = people
+	sch.Tables[addresses.Name] = addresses
+	return sch
+}
+
+func TestDDLThenDiff(t *testing.T) {
+	dsn := os.Getenv("MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("MYSQL_DSN is not set")
+	}
+	ctx := context.Background()
+
+	a, err := getAdapter("mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := a.open(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var dbName string
+	if err := db.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&dbName); err != nil {
+		t.Fatal(err)
+	}
+
+	stmts, err := ddlStatements(a, ddlDiffSchema(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, name := range []string{"ddl_diff_addresses", "ddl_diff_people"} {
+			_, _ = db.ExecContext(ctx, "DROP TABLE "+name)
+		}
+	}()
+	if err := outputTo(db, true, stmts); err != nil {
+		t.Fatal(err)
+	}
+
+	stmts, err = diffStatements(ctx, a, db, dbName, ddlDiffSchema(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 0 {
+		t.Fatal("expected no statements after ddl, got", stmts)
+	}
+}
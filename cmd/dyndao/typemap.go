@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+
+	"github.com/rbastic/dyndao/schema"
+)
+
+// TypeRule says what a column type becomes in another database.
+type TypeRule struct {
+	Type       string `json:"Type"`
+	KeepLength bool   `json:"KeepLength"`
+}
+
+// TypeMap holds the rules for translating column types, by source adapter,
+// then target adapter, then upper-cased source type. Types without a rule
+// are kept as they are.
+type TypeMap map[string]map[string]map[string]TypeRule
+
+// defaultTypeMap covers the common types of the adapters the tool can
+// introspect. It can be extended or overridden with -typemap, which takes
+// a JSON file in the same format.
+var defaultTypeMap = TypeMap{
+	"oracle": {
+		"mysql": {
+			"VARCHAR2":  {Type: "VARCHAR", KeepLength: true},
+			"NUMBER":    {Type: "INTEGER"},
+			"TIMESTAMP": {Type: "DATETIME"},
+			"CLOB":      {Type: "TEXT"},
+		},
+		"postgres": {
+			"VARCHAR2": {Type: "VARCHAR", KeepLength: true},
+			"NUMBER":   {Type: "INTEGER"},
+			"CLOB":     {Type: "TEXT"},
+			"BLOB":     {Type: "BYTEA"},
+		},
+	},
+	"mysql": {
+		"oracle": {
+			"VARCHAR":  {Type: "VARCHAR2", KeepLength: true},
+			"INT":      {Type: "NUMBER"},
+			"INTEGER":  {Type: "NUMBER"},
+			"TEXT":     {Type: "CLOB"},
+			"DATETIME": {Type: "TIMESTAMP"},
+		},
+		"postgres": {
+			"INT":      {Type: "INTEGER"},
+			"DATETIME": {Type: "TIMESTAMP"},
+			"BLOB":     {Type: "BYTEA"},
+		},
+	},
+	"postgres": {
+		"mysql": {
+			"CHARACTER VARYING":           {Type: "VARCHAR", KeepLength: true},
+			"TIMESTAMP WITHOUT TIME ZONE": {Type: "DATETIME"},
+			"BYTEA":                       {Type: "BLOB"},
+		},
+		"oracle": {
+			"CHARACTER VARYING": {Type: "VARCHAR2", KeepLength: true},
+			"VARCHAR":           {Type: "VARCHAR2", KeepLength: true},
+			"INTEGER":           {Type: "NUMBER"},
+			"TEXT":              {Type: "CLOB"},
+			"BYTEA":             {Type: "BLOB"},
+		},
+	},
+}
+
+// loadTypeMap reads a TypeMap from a JSON file, and merges it over the
+// default rules.
+func loadTypeMap(path string) (TypeMap, error) {
+	tm := TypeMap{}
+	tm.merge(defaultTypeMap)
+	if path == "" {
+		return tm, nil
+	}
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var extra TypeMap
+	if err := json.Unmarshal(buf, &extra); err != nil {
+		return nil, err
+	}
+	tm.merge(extra)
+	return tm, nil
+}
+
+func (tm TypeMap) merge(other TypeMap) {
+	for from, targets := range other {
+		if tm[from] == nil {
+			tm[from] = make(map[string]map[string]TypeRule)
+		}
+		for to, rules := range targets {
+			if tm[from][to] == nil {
+				tm[from][to] = make(map[string]TypeRule)
+			}
+			for typ, rule := range rules {
+				tm[from][to][strings.ToUpper(typ)] = rule
+			}
+		}
+	}
+}
+
+// Translate rewrites the column types of sch, in place, from those of the
+// from adapter to those of the to adapter.
+func (tm TypeMap) Translate(sch *schema.Schema, from string, to string) {
+	rules := tm[from][to]
+	for _, tbl := range sch.Tables {
+		for _, col := range tbl.Columns {
+			rule, ok := rules[strings.ToUpper(col.DBType)]
+			if !ok {
+				continue
+			}
+			col.DBType = rule.Type
+			if !rule.KeepLength {
+				col.Length = 0
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/rbastic/dyndao/schema"
+)
+
+func TestTranslate(t *testing.T) {
+	tbl := schema.DefaultTable()
+	tbl.Columns["Name"] = &schema.Column{Name: "Name", DBType: "varchar2", Length: 40}
+	tbl.Columns["Created"] = &schema.Column{Name: "Created", DBType: "TIMESTAMP", Length: 6}
+	tbl.Columns["Score"] = &schema.Column{Name: "Score", DBType: "FLOAT"}
+	sch := schema.DefaultSchema()
+	sch.Tables["t"] = tbl
+
+	f, err := ioutil.TempFile("", "typemap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`{"oracle": {"mysql": {"float": {"Type": "DOUBLE"}}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	tm, err := loadTypeMap(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm.Translate(sch, "oracle", "mysql")
+
+	if c := tbl.Columns["Name"]; c.DBType != "VARCHAR" || c.Length != 40 {
+		t.Fatal("expected VARCHAR(40), got", c.DBType, c.Length)
+	}
+	if c := tbl.Columns["Created"]; c.DBType != "DATETIME" || c.Length != 0 {
+		t.Fatal("expected DATETIME without a length, got", c.DBType, c.Length)
+	}
+	if c := tbl.Columns["Score"]; c.DBType != "DOUBLE" {
+		t.Fatal("expected the rule from the file to apply, got", c.DBType)
+	}
+
+	// No rules for this pair
+	tm.Translate(sch, "sqlite", "mysql")
+	if c := tbl.Columns["Score"]; c.DBType != "DOUBLE" {
+		t.Fatal("expected types without rules to be kept, got", c.DBType)
+	}
+}
@@ -0,0 +1,241 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/rbastic/dyndao/object"
+	"github.com/rbastic/dyndao/query"
+	"github.com/rbastic/dyndao/schema"
+)
+
+// Count returns the number of rows in table matching q, leaving out soft
+// deleted rows. tx may be nil.
+func (o *ORM) Count(ctx context.Context, tx *sql.Tx, table string, q *query.Query) (int64, error) {
+	objs, err := o.Aggregate(ctx, tx, table, q, nil, []query.Aggregate{query.Count("n")}, nil)
+	if err != nil {
+		return 0, err
+	}
+	if len(objs) == 0 {
+		return 0, nil
+	}
+	n, ok := objs[0].Get("n").(int64)
+	if !ok {
+		return 0, errors.New("Count: unexpected result type")
+	}
+	return n, nil
+}
+
+// Exists reports whether any row in table matches q, leaving out soft deleted
+// rows. It stops at the first matching row, so it is usually cheaper than
+// Count. tx may be nil.
+func (o *ORM) Exists(ctx context.Context, tx *sql.Tx, table string, q *query.Query) (bool, error) {
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	default:
+	}
+	objTable := o.s.GetTable(table)
+	if objTable == nil {
+		return false, errors.New("Exists: unknown object table " + table)
+	}
+	q, err := o.runBeforeRetrieveHooks(ctx, tx, table, q)
+	if err != nil {
+		return false, err
+	}
+	q = deletedFilter(objTable, q, nil)
+
+	sg := o.sqlGen
+	sqlStr, bindArgs, err := sg.BindingExists(sg, o.s, table, q)
+	if sg.Tracing {
+		fmt.Println("Exists/sqlStr=", sqlStr, "bindArgs=", bindArgs)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	stmt, err := stmtFromDbOrTx(ctx, o, tx, sqlStr)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		err := stmt.Close()
+		if err != nil {
+			fmt.Println(err) // TODO logger implementation
+		}
+	}()
+
+	var one int64
+	err = stmt.QueryRowContext(ctx, bindArgs...).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Sum returns the sum of col over the rows matching q, or nil if no
+// non-NULL values matched. tx may be nil.
+func (o *ORM) Sum(ctx context.Context, tx *sql.Tx, table string, q *query.Query, col string) (interface{}, error) {
+	return o.aggregateValue(ctx, tx, table, q, query.Sum(col, "v"))
+}
+
+// Min returns the smallest value of col over the rows matching q, or nil if
+// no non-NULL values matched. tx may be nil.
+func (o *ORM) Min(ctx context.Context, tx *sql.Tx, table string, q *query.Query, col string) (interface{}, error) {
+	return o.aggregateValue(ctx, tx, table, q, query.Min(col, "v"))
+}
+
+// Max returns the largest value of col over the rows matching q, or nil if
+// no non-NULL values matched. tx may be nil.
+func (o *ORM) Max(ctx context.Context, tx *sql.Tx, table string, q *query.Query, col string) (interface{}, error) {
+	return o.aggregateValue(ctx, tx, table, q, query.Max(col, "v"))
+}
+
+func (o *ORM) aggregateValue(ctx context.Context, tx *sql.Tx, table string, q *query.Query, agg query.Aggregate) (interface{}, error) {
+	objs, err := o.Aggregate(ctx, tx, table, q, nil, []query.Aggregate{agg}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(objs) == 0 || objs[0].ValueIsNULL(objs[0].Get(agg.Alias)) {
+		return nil, nil
+	}
+	return objs[0].Get(agg.Alias), nil
+}
+
+// Aggregate computes aggs over the rows of table matching q, grouped by the
+// groupBy columns. One object is returned per group, keyed by the groupBy
+// column names and the aggregate aliases. With no groupBy columns, a single
+// object is returned for the whole table. NULL results are set as NULL
+// values (see object.NewNULLValue). For example:
+//
+//	objs, err := o.Aggregate(ctx, nil, "orders", query.Gt("Total", 0),
+//		[]string{"CustomerID"},
+//		[]query.Aggregate{query.Count("n"), query.Sum("Total", "total")},
+//		&query.Options{OrderBy: []query.Order{query.Desc("total")}, Limit: 10})
+//
+// opts may be nil. tx may be nil.
+func (o *ORM) Aggregate(ctx context.Context, tx *sql.Tx, table string, q *query.Query, groupBy []string, aggs []query.Aggregate, opts *query.Options) (object.Array, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+	objTable := o.s.GetTable(table)
+	if objTable == nil {
+		return nil, errors.New("Aggregate: unknown object table " + table)
+	}
+	q, err := o.runBeforeRetrieveHooks(ctx, tx, table, q)
+	if err != nil {
+		return nil, err
+	}
+	q = deletedFilter(objTable, q, opts)
+
+	sg := o.sqlGen
+	sqlStr, bindArgs, err := sg.BindingAggregate(sg, o.s, table, q, groupBy, aggs, opts)
+	if sg.Tracing {
+		fmt.Println("Aggregate/sqlStr=", sqlStr, "bindArgs=", bindArgs)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Result columns are the group columns, then the aggregates
+	keys := make([]string, 0, len(groupBy)+len(aggs))
+	var dests []interface{}
+	for _, name := range groupBy {
+		keys = append(keys, name)
+		dests = append(dests, o.aggregateScanDest(objTable.GetColumn(name), ""))
+	}
+	for _, agg := range aggs {
+		keys = append(keys, agg.Alias)
+		dests = append(dests, o.aggregateScanDest(objTable.GetColumn(agg.Column), agg.Func))
+	}
+
+	stmt, err := stmtFromDbOrTx(ctx, o, tx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err := stmt.Close()
+		if err != nil {
+			fmt.Println(err) // TODO logger implementation
+		}
+	}()
+
+	res, err := stmt.QueryContext(ctx, bindArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err := res.Close()
+		if err != nil {
+			fmt.Println(err) // TODO logger implementation
+		}
+	}()
+
+	var objs object.Array
+	for res.Next() {
+		if err := res.Scan(dests...); err != nil {
+			return nil, err
+		}
+		obj := object.New(table)
+		for i, k := range keys {
+			obj.Set(k, aggregateResult(dests[i]))
+		}
+		obj.MarkDirty(false)
+		obj.ResetChangedColumns()
+		objs = append(objs, obj)
+	}
+	if err := res.Err(); err != nil {
+		return nil, err
+	}
+	return objs, nil
+}
+
+// aggregateScanDest picks a scan destination for an aggregate (or, with an
+// empty fn, a group column), so that results come back with the same Go
+// types as a regular retrieval: int64, float64 or string.
+func (o *ORM) aggregateScanDest(col *schema.Column, fn query.AggFunc) interface{} {
+	switch fn {
+	case query.AggCount:
+		return new(sql.NullInt64)
+	case query.AggAvg:
+		return new(sql.NullFloat64)
+	}
+	if col != nil {
+		if o.sqlGen.IsFloatingType(col.DBType) {
+			return new(sql.NullFloat64)
+		}
+		if col.IsNumber || o.sqlGen.IsNumberType(col.DBType) {
+			return new(sql.NullInt64)
+		}
+	}
+	return new(interface{})
+}
+
+func aggregateResult(dest interface{}) interface{} {
+	switch v := dest.(type) {
+	case *sql.NullInt64:
+		if v.Valid {
+			return v.Int64
+		}
+	case *sql.NullFloat64:
+		if v.Valid {
+			return v.Float64
+		}
+	case *interface{}:
+		if b, ok := (*v).([]byte); ok {
+			return string(b)
+		}
+		if *v != nil {
+			return *v
+		}
+	}
+	return object.NewNULLValue()
+}
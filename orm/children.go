@@ -0,0 +1,174 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/rbastic/dyndao/object"
+	"github.com/rbastic/dyndao/query"
+	"github.com/rbastic/dyndao/schema"
+)
+
+// UnlimitedDepth can be passed to RetrieveWithChildrenDepth to load the
+// entire object graph.
+const UnlimitedDepth = -1
+
+// RetrieveWithChildrenDepth retrieves the object matching pkValues along
+// with its children, their children, and so on for depth levels (a depth of
+// 1 loads just the object's own children, and UnlimitedDepth loads
+// everything that is reachable). Every child row is loaded into
+// obj.Children, following each table's schema.ChildTable relations, and
+// matching the child table's ForeignKeys too when it is MultiKey. A row
+// that has already been loaded (the object graph has a cycle) is attached
+// again but not expanded a second time, so loading always terminates. Nil is
+// returned for both the object and the error if there is no matching row. tx
+// may be nil.
+func (o *ORM) RetrieveWithChildrenDepth(ctx context.Context, tx *sql.Tx, table string, pkValues map[string]interface{}, depth int) (*object.Object, error) {
+	obj, err := o.retrieveCore(ctx, tx, table, pkValues)
+	if err != nil {
+		return nil, errors.Wrap(err, "RetrieveWithChildren/Retrieve")
+	}
+	if obj == nil {
+		return nil, nil
+	}
+	err = o.loadChildren(ctx, tx, obj, depth, make(map[string]bool), nil)
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// loadChildren replaces the children of obj with those in the database, and
+// recurses into them while depth allows. seen records the rows that have
+// been expanded already. opts, which may be nil, selects the soft deleted
+// children to include.
+func (o *ORM) loadChildren(ctx context.Context, tx *sql.Tx, obj *object.Object, depth int, seen map[string]bool, opts *query.Options) error {
+	if depth == 0 {
+		return nil
+	}
+	objTable := o.s.GetTable(obj.Type)
+	if objTable == nil {
+		return errors.New("RetrieveWithChildren: unknown object table " + obj.Type)
+	}
+
+	key := rowKey(objTable, obj)
+	if seen[key] {
+		return nil
+	}
+	seen[key] = true
+
+	// Load children in a consistent order
+	names := make([]string, 0, len(objTable.Children))
+	for name := range objTable.Children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		childTable := o.s.GetTable(name)
+		if childTable == nil {
+			return fmt.Errorf("RetrieveWithChildren: unknown object table for child type %s", name)
+		}
+		local, foreign := childKeys(objTable, childTable, objTable.Children[name])
+		if len(local) != len(foreign) {
+			return fmt.Errorf("RetrieveWithChildren: mismatched key columns for child type %s", name)
+		}
+
+		// A parent without values for the key has no children
+		vals := keyValues(obj, local)
+		if vals == nil {
+			obj.Children[name] = object.NewEmptyArray()
+			continue
+		}
+		queryVals := make(map[string]interface{}, len(foreign))
+		for i, v := range vals {
+			queryVals[foreign[i]] = v
+		}
+
+		children, err := o.retrieveManyCore(ctx, tx, name, query.FromKV(queryVals), opts)
+		if err != nil {
+			return errors.Wrap(err, "RetrieveWithChildren/RetrieveMany("+name+")")
+		}
+		if children == nil {
+			children = object.NewEmptyArray()
+		}
+		obj.Children[name] = children
+
+		for _, child := range children {
+			if err := o.loadChildren(ctx, tx, child, depth-1, seen, opts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// childKeys returns the columns that relate a row of parentTable to rows of
+// childTable: those of rel (see schema.ChildTable.Keys), or the parent's
+// primary key when rel is nil, followed by the child's ForeignKeys that the
+// parent also has, when the child is MultiKey.
+func childKeys(parentTable *schema.Table, childTable *schema.Table, rel *schema.ChildTable) (local []string, foreign []string) {
+	if rel != nil {
+		local, foreign = rel.Keys(parentTable)
+	} else {
+		local, foreign = []string{parentTable.Primary}, []string{parentTable.Primary}
+	}
+	if !childTable.MultiKey {
+		return local, foreign
+	}
+
+	// Copy rather than appending to the relation's own slices
+	local = append([]string(nil), local...)
+	foreign = append([]string(nil), foreign...)
+	for _, fk := range childTable.ForeignKeys {
+		if _, ok := parentTable.Columns[fk]; !ok {
+			continue
+		}
+		dup := false
+		for _, f := range foreign {
+			if f == fk {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			local = append(local, fk)
+			foreign = append(foreign, fk)
+		}
+	}
+	return local, foreign
+}
+
+// rowKey identifies obj's row by its table and primary key values.
+func rowKey(objTable *schema.Table, obj *object.Object) string {
+	key := fmt.Sprintf("%s\x00%v", objTable.Name, obj.Get(objTable.Primary))
+	if objTable.MultiKey {
+		for _, fk := range objTable.ForeignKeys {
+			key += fmt.Sprintf("\x00%v", obj.Get(fk))
+		}
+	}
+	return key
+}
+
+// keyValues returns obj's values for cols, as plain driver values, or nil if
+// any of them is unset, NULL or raw SQL, in which case obj can't be related
+// to other rows.
+func keyValues(obj *object.Object, cols []string) []interface{} {
+	vals := make([]interface{}, len(cols))
+	for i, col := range cols {
+		v := obj.Get(col)
+		if obj.ValueIsNULL(v) {
+			return nil
+		}
+		v, err := cursorValue(v)
+		if err != nil || v == nil {
+			return nil
+		}
+		vals[i] = v
+	}
+	return vals
+}
@@ -9,10 +9,23 @@ import (
 
 	"github.com/inconshreveable/log15"
 	"github.com/rbastic/dyndao/object"
+	"github.com/rbastic/dyndao/query"
 )
 
-// Delete function will DELETE a record ...
+// Delete function will DELETE a record ... The object's KV is used as an
+// equality filter, so it must not be empty (ErrEmptyFilter is returned). For
+// tables with a VersionColumn, ErrStaleObject is returned rather than
+// ErrNoResult when the object has a version and no row matched it. For
+// tables with a SoftDeleteColumn, the row is kept and the column is set to
+// the current time instead (see Restore and ORM.DatabaseTime), advancing the
+// object's version as Update does.
 func (o *ORM) Delete(ctx context.Context, tx *sql.Tx, obj *object.Object) (int64, error) {
+	return o.deleteCore(ctx, tx, obj, obj.KV)
+}
+
+// deleteCore deletes the rows matching filter, which is usually obj's own
+// KV, calling obj's hooks.
+func (o *ORM) deleteCore(ctx context.Context, tx *sql.Tx, obj *object.Object, filter map[string]interface{}) (int64, error) {
 	sg := o.sqlGen
 	tracing := sg.Tracing
 	errorString := "Delete error"
@@ -27,8 +40,12 @@ func (o *ORM) Delete(ctx context.Context, tx *sql.Tx, obj *object.Object) (int64
 	if objTable == nil {
 		return 0, errors.New("Delete: unknown object table " + obj.Type)
 	}
+	// An empty filter would render no WHERE clause at all
+	if len(filter) == 0 {
+		return 0, ErrEmptyFilter
+	}
 
-	err := o.CallBeforeDeleteHookIfNeeded(obj)
+	err := o.runHooks(ctx, tx, BeforeDelete, obj)
 	if err != nil {
 		if tracing {
 			log15.Error(errorString, "BeforeUpdateHookError", err)
@@ -36,7 +53,44 @@ func (o *ORM) Delete(ctx context.Context, tx *sql.Tx, obj *object.Object) (int64
 		return 0, err
 	}
 
-	sqlStr, bindWhere, err := sg.BindingDelete(o.sqlGen, o.s, obj)
+	var sqlStr string
+	var bindWhere []interface{}
+	var nextVersion interface{}
+	deletedAt := o.stampTime()
+	if col := objTable.SoftDeleteColumn; col != "" {
+		// The object's own soft delete value isn't useful as a filter
+		queryVals := make(map[string]interface{}, len(filter))
+		for k, v := range filter {
+			if k != col {
+				queryVals[k] = v
+			}
+		}
+		if len(queryVals) == 0 {
+			return 0, ErrEmptyFilter
+		}
+		set := map[string]interface{}{col: deletedAt}
+		// Like Update, match the object's version and advance it
+		if vc := objTable.VersionColumn; vc != "" {
+			if cur := obj.Get(vc); cur != nil && !obj.ValueIsNULL(cur) {
+				vcol := objTable.GetColumn(vc)
+				if vcol == nil {
+					return 0, errors.New("dyndao: unknown version column " + vc + " for table " + objTable.Name)
+				}
+				nextVersion, err = o.nextVersion(vcol, cur)
+				if err != nil {
+					return 0, err
+				}
+				queryVals[vc] = cur
+				set[vc] = nextVersion
+			}
+		}
+		q := query.And(query.FromKV(queryVals), query.IsNull(col))
+		sqlStr, bindWhere, err = sg.BindingUpdateWhere(sg, o.s, obj.Type, set, q)
+	} else {
+		filterObj := object.New(obj.Type)
+		filterObj.KV = filter
+		sqlStr, bindWhere, err = sg.BindingDelete(sg, o.s, filterObj)
+	}
 	if err != nil {
 		return 0, err
 	}
@@ -69,10 +123,13 @@ func (o *ORM) Delete(ctx context.Context, tx *sql.Tx, obj *object.Object) (int64
 	}
 
 	if rowsAff == 0 {
+		if objTable.VersionColumn != "" && obj.Get(objTable.VersionColumn) != nil {
+			return 0, ErrStaleObject
+		}
 		return 0, ErrNoResult
 	}
 
-	err = o.CallAfterDeleteHookIfNeeded(obj)
+	err = o.runHooks(ctx, tx, AfterDelete, obj)
 	if err != nil {
 		if tracing {
 			log15.Error(errorString, "BeforeAfterUpdateHookError", err)
@@ -80,9 +137,68 @@ func (o *ORM) Delete(ctx context.Context, tx *sql.Tx, obj *object.Object) (int64
 		return 0, err
 	}
 
+	if objTable.SoftDeleteColumn != "" {
+		obj.SetCore(objTable.SoftDeleteColumn, deletedAt)
+		if nextVersion != nil {
+			obj.SetCore(objTable.VersionColumn, nextVersion)
+		}
+	}
+
 	obj.MarkDirty(false)      // Flag that the object has been recently saved
 	obj.ResetChangedColumns() // Reset the 'changed fields', if any
 
 	return rowsAff, nil
 
 }
+
+// DeleteWhere deletes every row of table matching q in a single statement,
+// returning the number of rows affected. No hooks are called, as no objects
+// are involved. Rows are always removed outright, even for tables with a
+// SoftDeleteColumn (UpdateWhere can soft delete in bulk). An empty q returns
+// ErrEmptyFilter; pass query.All() to delete every row deliberately. tx may
+// be nil.
+func (o *ORM) DeleteWhere(ctx context.Context, tx *sql.Tx, table string, q *query.Query) (int64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+	if q.IsEmpty() {
+		return 0, ErrEmptyFilter
+	}
+
+	sg := o.sqlGen
+	sqlStr, bindArgs, err := sg.BindingDeleteWhere(sg, o.s, table, q)
+	if err != nil {
+		return 0, err
+	}
+	if sg.Tracing {
+		fmt.Println("DeleteWhere/sqlStr=", sqlStr, "bindArgs=", bindArgs)
+	}
+	rowsAff, err := o.execRowsAffected(ctx, tx, sqlStr, bindArgs)
+	if err != nil {
+		return 0, errors.Wrap(err, "DeleteWhere")
+	}
+	return rowsAff, nil
+}
+
+// execRowsAffected prepares and executes sqlStr, returning the number of rows
+// affected.
+func (o *ORM) execRowsAffected(ctx context.Context, tx *sql.Tx, sqlStr string, bindArgs []interface{}) (int64, error) {
+	stmt, err := stmtFromDbOrTx(ctx, o, tx, sqlStr)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		stmtErr := stmt.Close()
+		if stmtErr != nil {
+			fmt.Println(stmtErr) // TODO: logger implementation
+		}
+	}()
+
+	res, err := stmt.ExecContext(ctx, bindArgs...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,149 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/rbastic/dyndao/object"
+	"github.com/rbastic/dyndao/query"
+	"github.com/rbastic/dyndao/schema"
+)
+
+// DeleteAllOptions controls how DeleteAll finds the children to delete.
+type DeleteAllOptions struct {
+	// LoadChildren retrieves every child row of each object being deleted,
+	// soft deleted or not, following the schema's Children definitions,
+	// rather than deleting only the children already in obj.Children. The
+	// loaded rows replace the Children of obj and of each descendant, so
+	// afterwards they reflect what was deleted.
+	LoadChildren bool
+}
+
+// DeleteAll is the counterpart of SaveAll: it deletes obj along with its
+// children, their children, and so on, leaf first, so that no child row is
+// left referring to a deleted parent. This is useful where the database
+// doesn't cascade deletes itself. Each object is deleted by its primary key
+// (and version, for tables with a VersionColumn), calling its delete hooks.
+// Children in memory that have no primary key are taken not to have been
+// saved and are skipped, as are children whose row is already gone. Rows
+// that are already soft deleted are left as they are, keeping their deletion
+// time and version, but their own children are still deleted.
+//
+// opts may be nil, in which case only the children in obj.Children are
+// deleted. If tx is nil, a transaction is begun and committed or rolled back
+// here; otherwise, rolling back tx on error is left to the caller. The total
+// number of rows affected is returned.
+func (o *ORM) DeleteAll(ctx context.Context, tx *sql.Tx, obj *object.Object, opts *DeleteAllOptions) (int64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+	if opts == nil {
+		opts = &DeleteAllOptions{}
+	}
+
+	if tx != nil {
+		return o.recurseAndDelete(ctx, tx, obj, opts, make(map[string]bool), true)
+	}
+
+	tx, err := o.RawConn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	rowsAff, err := o.recurseAndDelete(ctx, tx, obj, opts, make(map[string]bool), true)
+	if err != nil {
+		rollErr := tx.Rollback()
+		if rollErr != nil {
+			return 0, errors.Wrap(err, rollErr.Error())
+		}
+		return 0, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return rowsAff, nil
+}
+
+// recurseAndDelete deletes obj's children and then obj itself. seen records
+// the rows that have been visited already, in case the graph has a cycle.
+func (o *ORM) recurseAndDelete(ctx context.Context, tx *sql.Tx, obj *object.Object, opts *DeleteAllOptions, seen map[string]bool, root bool) (int64, error) {
+	objTable := o.s.GetTable(obj.Type)
+	if objTable == nil {
+		return 0, errors.New("DeleteAll: unknown object table " + obj.Type)
+	}
+
+	filter := deleteAllFilter(objTable, obj)
+	if filter == nil && root {
+		return 0, errors.New("DeleteAll: missing primary key for table " + obj.Type)
+	}
+	if filter != nil {
+		key := rowKey(objTable, obj)
+		if seen[key] {
+			return 0, nil
+		}
+		seen[key] = true
+
+		if opts.LoadChildren {
+			// Soft deleted children are loaded too, so that their own
+			// children are found
+			err := o.loadChildren(ctx, tx, obj, 1, make(map[string]bool), &query.Options{Deleted: query.WithDeleted})
+			if err != nil {
+				return 0, errors.Wrap(err, "DeleteAll")
+			}
+		}
+	}
+
+	// Delete children in a consistent order
+	names := make([]string, 0, len(obj.Children))
+	for name := range obj.Children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var rowsAff int64
+	for _, name := range names {
+		for _, child := range obj.Children[name] {
+			aff, err := o.recurseAndDelete(ctx, tx, child, opts, seen, false)
+			rowsAff += aff
+			if err != nil {
+				return rowsAff, err
+			}
+		}
+	}
+
+	if filter == nil || (!root && isSoftDeleted(objTable, obj)) {
+		return rowsAff, nil
+	}
+	aff, err := o.deleteCore(ctx, tx, obj, filter)
+	if err == ErrNoResult && !root {
+		err = nil
+	}
+	rowsAff += aff
+	return rowsAff, err
+}
+
+// deleteAllFilter returns the values identifying obj's row, or nil if obj
+// has no primary key value.
+func deleteAllFilter(objTable *schema.Table, obj *object.Object) map[string]interface{} {
+	keys := []string{objTable.Primary}
+	if objTable.MultiKey {
+		keys = append(keys, objTable.ForeignKeys...)
+	}
+	if objTable.VersionColumn != "" && obj.Get(objTable.VersionColumn) != nil {
+		keys = append(keys, objTable.VersionColumn)
+	}
+	filter := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		v := obj.Get(k)
+		if v == nil {
+			return nil
+		}
+		filter[k] = v
+	}
+	return filter
+}
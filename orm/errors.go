@@ -6,4 +6,13 @@ import (
 
 var (
 	ErrNoResult = errors.New("dyndao: operation had no result")
+
+	// ErrEmptyFilter is returned by operations that would otherwise affect
+	// every row of a table. Use query.All() to do so deliberately.
+	ErrEmptyFilter = errors.New("dyndao: refusing to operate on an entire table without a filter")
+
+	// ErrStaleObject is returned when updating or deleting an object from a
+	// table with a VersionColumn, and the row no longer has the version
+	// the object was retrieved with (or no longer exists).
+	ErrStaleObject = errors.New("dyndao: object has been modified or deleted since it was retrieved")
 )
@@ -1,7 +1,11 @@
 package orm
 
 import (
+	"context"
+	"database/sql"
+
 	"github.com/rbastic/dyndao/object"
+	"github.com/rbastic/dyndao/query"
 	"github.com/rbastic/dyndao/schema"
 )
 
@@ -16,52 +20,224 @@ func makeEmptyHookMap() map[string]HookFunction {
 	return make(map[string]HookFunction)
 }
 
-// Software trigger functions
-func (o *ORM) CallBeforeCreateHookIfNeeded(obj *object.Object) error {
-	hookFunc, ok := o.BeforeCreateHooks[o.s.GetTableName(obj.Type)]
-	if ok {
-		return hookFunc(o.s, obj)
+// HookEvent identifies the point in an ORM operation at which a Hook is
+// called.
+type HookEvent int
+
+// Supported hook events
+const (
+	BeforeCreate HookEvent = iota
+	AfterCreate
+	BeforeUpdate
+	AfterUpdate
+	BeforeDelete
+	AfterDelete
+
+	// AfterRetrieve is called for every object loaded by a retrieval,
+	// e.g. to decrypt fields or compute derived values. The object is
+	// marked as unchanged afterwards.
+	AfterRetrieve
+)
+
+func (e HookEvent) String() string {
+	switch e {
+	case BeforeCreate:
+		return "BeforeCreate"
+	case AfterCreate:
+		return "AfterCreate"
+	case BeforeUpdate:
+		return "BeforeUpdate"
+	case AfterUpdate:
+		return "AfterUpdate"
+	case BeforeDelete:
+		return "BeforeDelete"
+	case AfterDelete:
+		return "AfterDelete"
+	case AfterRetrieve:
+		return "AfterRetrieve"
 	}
-	return nil
+	return "HookEvent(?)"
 }
 
-func (o *ORM) CallAfterCreateHookIfNeeded(obj *object.Object) error {
-	hookFunc, ok := o.AfterCreateHooks[o.s.GetTableName(obj.Type)]
-	if ok {
-		return hookFunc(o.s, obj)
+// AllTables can be passed to RegisterHook in place of a table name, to
+// register a hook that is called for every table.
+const AllTables = "*"
+
+// Hook is a software-based trigger that is registered with RegisterHook.
+// It's given the context and the transaction (nil if there is none) of the
+// operation that it's called for, so that it can, for instance, write an
+// audit row atomically with the change. Returning an error aborts the
+// operation.
+type Hook func(ctx context.Context, tx *sql.Tx, event HookEvent, obj *object.Object) error
+
+// BeforeRetrieveHook is called before a retrieval with the table and the
+// query (which may be nil) that it's going to run, and returns the query to
+// run instead, e.g. with a tenant filter added. It is registered with
+// RegisterBeforeRetrieveHook.
+type BeforeRetrieveHook func(ctx context.Context, tx *sql.Tx, table string, q *query.Query) (*query.Query, error)
+
+type registeredHook struct {
+	id    int
+	table string
+	hook  Hook
+}
+
+type registeredRetrieveHook struct {
+	id    int
+	table string
+	hook  BeforeRetrieveHook
+}
+
+// RegisterHook registers hook to be called for event on table, or on every
+// table for AllTables. Any number of hooks may be registered for the same
+// table and event, and they are called in the order that they were
+// registered, whether they're for AllTables or not. A HookFunction set in
+// one of the older per-table maps (BeforeCreateHooks, etc.) is called before
+// all of them. The returned function unregisters the hook.
+func (o *ORM) RegisterHook(table string, event HookEvent, hook Hook) func() {
+	if table != AllTables {
+		table = o.s.GetTableName(table)
 	}
-	return nil
 
+	o.hooksMu.Lock()
+	defer o.hooksMu.Unlock()
+	if o.hooks == nil {
+		o.hooks = make(map[HookEvent][]registeredHook)
+	}
+	o.nextHookID++
+	id := o.nextHookID
+	o.hooks[event] = append(o.hooks[event], registeredHook{id: id, table: table, hook: hook})
+
+	return func() {
+		o.hooksMu.Lock()
+		defer o.hooksMu.Unlock()
+		// Build a new slice, as runHooks may be iterating the old one
+		var kept []registeredHook
+		for _, h := range o.hooks[event] {
+			if h.id != id {
+				kept = append(kept, h)
+			}
+		}
+		o.hooks[event] = kept
+	}
 }
 
-func (o *ORM) CallBeforeUpdateHookIfNeeded(obj *object.Object) error {
-	hookFunc, ok := o.BeforeUpdateHooks[o.s.GetTableName(obj.Type)]
-	if ok {
-		return hookFunc(o.s, obj)
+// RegisterBeforeRetrieveHook registers hook to rewrite the query of every
+// retrieval from table, or from every table for AllTables. This covers
+// everything that takes a query.Query or query values (Retrieve,
+// RetrieveMany, Iterate, Count, Aggregate, ...), but not
+// RetrieveManyFromCustomSQL. Hooks are called in the order that they were
+// registered, each receiving the query returned by the last. The returned
+// function unregisters the hook.
+func (o *ORM) RegisterBeforeRetrieveHook(table string, hook BeforeRetrieveHook) func() {
+	if table != AllTables {
+		table = o.s.GetTableName(table)
+	}
+
+	o.hooksMu.Lock()
+	defer o.hooksMu.Unlock()
+	o.nextHookID++
+	id := o.nextHookID
+	o.retrieveHooks = append(o.retrieveHooks, registeredRetrieveHook{id: id, table: table, hook: hook})
+
+	return func() {
+		o.hooksMu.Lock()
+		defer o.hooksMu.Unlock()
+		var kept []registeredRetrieveHook
+		for _, h := range o.retrieveHooks {
+			if h.id != id {
+				kept = append(kept, h)
+			}
+		}
+		o.retrieveHooks = kept
 	}
-	return nil
 }
 
-func (o *ORM) CallAfterUpdateHookIfNeeded(obj *object.Object) error {
-	hookFunc, ok := o.AfterUpdateHooks[o.s.GetTableName(obj.Type)]
-	if ok {
-		return hookFunc(o.s, obj)
+// runBeforeRetrieveHooks passes q through every BeforeRetrieveHook for
+// table, returning the query to run.
+func (o *ORM) runBeforeRetrieveHooks(ctx context.Context, tx *sql.Tx, table string, q *query.Query) (*query.Query, error) {
+	tableName := o.s.GetTableName(table)
+
+	o.hooksMu.RLock()
+	hooks := o.retrieveHooks
+	o.hooksMu.RUnlock()
+	for _, h := range hooks {
+		if h.table != AllTables && h.table != tableName {
+			continue
+		}
+		var err error
+		q, err = h.hook(ctx, tx, table, q)
+		if err != nil {
+			return nil, err
+		}
 	}
-	return nil
+	return q, nil
 }
 
-func (o *ORM) CallBeforeDeleteHookIfNeeded(obj *object.Object) error {
-	hookFunc, ok := o.BeforeDeleteHooks[o.s.GetTableName(obj.Type)]
-	if ok {
-		return hookFunc(o.s, obj)
+func (o *ORM) legacyHooks(event HookEvent) map[string]HookFunction {
+	switch event {
+	case BeforeCreate:
+		return o.BeforeCreateHooks
+	case AfterCreate:
+		return o.AfterCreateHooks
+	case BeforeUpdate:
+		return o.BeforeUpdateHooks
+	case AfterUpdate:
+		return o.AfterUpdateHooks
+	case BeforeDelete:
+		return o.BeforeDeleteHooks
+	case AfterDelete:
+		return o.AfterDeleteHooks
 	}
 	return nil
 }
 
-func (o *ORM) CallAfterDeleteHookIfNeeded(obj *object.Object) error {
-	hookFunc, ok := o.AfterDeleteHooks[o.s.GetTableName(obj.Type)]
-	if ok {
-		return hookFunc(o.s, obj)
+// runHooks calls every hook for event on obj's table, stopping at the first
+// error.
+func (o *ORM) runHooks(ctx context.Context, tx *sql.Tx, event HookEvent, obj *object.Object) error {
+	table := o.s.GetTableName(obj.Type)
+	if hookFunc, ok := o.legacyHooks(event)[table]; ok {
+		if err := hookFunc(o.s, obj); err != nil {
+			return err
+		}
+	}
+
+	o.hooksMu.RLock()
+	hooks := o.hooks[event]
+	o.hooksMu.RUnlock()
+	for _, h := range hooks {
+		if h.table != AllTables && h.table != table {
+			continue
+		}
+		if err := h.hook(ctx, tx, event, obj); err != nil {
+			return err
+		}
 	}
 	return nil
 }
+
+// Software trigger functions. These call the hooks for an event without a
+// context or transaction; the ORM's own operations supply them.
+func (o *ORM) CallBeforeCreateHookIfNeeded(obj *object.Object) error {
+	return o.runHooks(context.Background(), nil, BeforeCreate, obj)
+}
+
+func (o *ORM) CallAfterCreateHookIfNeeded(obj *object.Object) error {
+	return o.runHooks(context.Background(), nil, AfterCreate, obj)
+}
+
+func (o *ORM) CallBeforeUpdateHookIfNeeded(obj *object.Object) error {
+	return o.runHooks(context.Background(), nil, BeforeUpdate, obj)
+}
+
+func (o *ORM) CallAfterUpdateHookIfNeeded(obj *object.Object) error {
+	return o.runHooks(context.Background(), nil, AfterUpdate, obj)
+}
+
+func (o *ORM) CallBeforeDeleteHookIfNeeded(obj *object.Object) error {
+	return o.runHooks(context.Background(), nil, BeforeDelete, obj)
+}
+
+func (o *ORM) CallAfterDeleteHookIfNeeded(obj *object.Object) error {
+	return o.runHooks(context.Background(), nil, AfterDelete, obj)
+}
@@ -13,6 +13,8 @@ import (
 
 // Insert function will INSERT a record, given an optional transaction and an object.
 // It returns the number of rows affected (int64) and any error that may have occurred.
+// AutoCreateTime and AutoUpdateTime columns without a value are set to the current
+// UTC time.
 func (o *ORM) Insert(ctx context.Context, tx *sql.Tx, obj *object.Object) (int64, error) {
 	sg := o.sqlGen
 	tracing := sg.Tracing
@@ -37,7 +39,7 @@ func (o *ORM) Insert(ctx context.Context, tx *sql.Tx, obj *object.Object) (int64
 	callerSuppliesPK := objTable.CallerSuppliesPK
 
 	// Call any before create hooks
-	err := o.CallBeforeCreateHookIfNeeded(obj)
+	err := o.runHooks(ctx, tx, BeforeCreate, obj)
 	if err != nil {
 		if tracing {
 			log15.Error(errorString, "BeforeCreateHookError", err)
@@ -45,6 +47,14 @@ func (o *ORM) Insert(ctx context.Context, tx *sql.Tx, obj *object.Object) (int64
 		return 0, err
 	}
 
+	stampCreateTimes(objTable, obj, o.stampTime())
+
+	// Tables with optimistic locking start each row at its first version
+	err = o.initVersion(objTable, obj)
+	if err != nil {
+		return 0, errors.Wrap(err, "Insert")
+	}
+
 	// Prepare our binding insert SQL statement and the binding parameters
 	sqlStr, bindArgs, err := sg.BindingInsert(sg, o.s, obj.Type, obj.KV)
 	if err != nil {
@@ -82,12 +92,12 @@ func (o *ORM) Insert(ctx context.Context, tx *sql.Tx, obj *object.Object) (int64
 	}
 
 	if sg.IsPOSTGRES || sg.IsDB2 {
-		return o.postgreInsertHelper(ctx, stmt, bindArgs, obj, callerSuppliesPK, tracing, objTable)
+		return o.postgreInsertHelper(ctx, tx, stmt, bindArgs, obj, callerSuppliesPK, tracing, objTable)
 	}
-	return o.insertHelper(ctx, stmt, bindArgs, obj, callerSuppliesPK, tracing, objTable, &lastID)
+	return o.insertHelper(ctx, tx, stmt, bindArgs, obj, callerSuppliesPK, tracing, objTable, &lastID)
 }
 
-func (o *ORM) insertHelper(ctx context.Context, stmt *sql.Stmt, bindArgs []interface{}, obj *object.Object, callerSuppliesPK bool, tracing bool, objTable *schema.Table, lastID *int64) (int64, error) {
+func (o *ORM) insertHelper(ctx context.Context, tx *sql.Tx, stmt *sql.Stmt, bindArgs []interface{}, obj *object.Object, callerSuppliesPK bool, tracing bool, objTable *schema.Table, lastID *int64) (int64, error) {
 	errorString := "Insert error"
 	var err error
 	defer func() {
@@ -146,7 +156,7 @@ func (o *ORM) insertHelper(ctx context.Context, stmt *sql.Stmt, bindArgs []inter
 	}
 
 	// Call after create hook
-	err = o.CallAfterCreateHookIfNeeded(obj)
+	err = o.runHooks(ctx, tx, AfterCreate, obj)
 	if err != nil {
 		if tracing {
 			log15.Error(errorString, "BeforeAfterCreateHookError", err)
@@ -0,0 +1,248 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+
+	"github.com/rbastic/dyndao/object"
+)
+
+const (
+	// DefaultInsertBatchSize is the number of rows InsertMany puts in a
+	// single INSERT statement, unless ORM.InsertBatchSize says otherwise.
+	DefaultInsertBatchSize = 100
+
+	// maxBindArgs keeps a statement under the smallest binding
+	// parameter limit we support (SQLite, before 3.32.0). Both InsertMany
+	// and Preload size their batches by it.
+	maxBindArgs = 999
+
+	// maxInsertManyRows is SQL Server's limit on rows in a VALUES list.
+	maxInsertManyRows = 1000
+)
+
+// InsertMany inserts objs using as few statements as possible, batching
+// rows into multi-row INSERTs (INSERT ALL on Oracle). Consecutive objects
+// of the same type that set the same columns are batched together, up to
+// ORM.InsertBatchSize rows at a time. It returns the total number of rows
+// inserted. tx may be nil, though a transaction is recommended so that a
+// failure part way through doesn't leave some batches inserted.
+//
+// The create hooks are called for every object. Unless the table's
+// CallerSuppliesPK is set, the new primary key is set in each object that
+// has no primary key value on Postgres, CockroachDB, SQL Server and DB2
+// (which return them from the INSERT itself), and on SQLite and MySQL
+// (derived from LastInsertId). DB2
+// returns the keys in the order the rows were given. Postgres and
+// CockroachDB don't promise any order, so the keys are sorted, and SQLite
+// and MySQL report only one of them; these assume that the rows of a single
+// statement were given increasing keys in the order listed, as is the case
+// with the default sequence and auto-increment settings. SQL Server makes
+// no such promise, so there each object needing a key is inserted by a
+// statement of its own. Oracle doesn't report them back for INSERT ALL, so
+// the primary keys remain unset there. Objects batched together must either
+// all have primary key values or all lack them, as which rows were given
+// generated keys can't be told apart otherwise; an error is returned for a
+// batch that mixes the two.
+func (o *ORM) InsertMany(ctx context.Context, tx *sql.Tx, objs object.Array) (int64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	now := o.stampTime()
+	for _, obj := range objs {
+		if o.s.GetTable(obj.Type) == nil {
+			return 0, errors.New("InsertMany: unknown object table " + obj.Type)
+		}
+		if len(obj.KV) == 0 {
+			return 0, errors.New("InsertMany: no values to insert for table " + obj.Type)
+		}
+		if err := o.runHooks(ctx, tx, BeforeCreate, obj); err != nil {
+			return 0, err
+		}
+		stampCreateTimes(o.s.GetTable(obj.Type), obj, now)
+		if err := o.initVersion(o.s.GetTable(obj.Type), obj); err != nil {
+			return 0, errors.Wrap(err, "InsertMany")
+		}
+	}
+
+	batchSize := o.InsertBatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultInsertBatchSize
+	}
+	if batchSize > maxInsertManyRows {
+		batchSize = maxInsertManyRows
+	}
+
+	var total int64
+	for start := 0; start < len(objs); {
+		// Extend the batch while the type and columns stay the same
+		sig := insertManySignature(objs[start])
+		limit := batchSize
+		if perRow := maxBindArgs / len(objs[start].KV); perRow < limit {
+			limit = perRow
+		}
+		if limit < 1 || (o.sqlGen.IsMSSQL && !o.s.GetTable(objs[start].Type).CallerSuppliesPK) {
+			limit = 1
+		}
+		end := start + 1
+		for end < len(objs) && end-start < limit && insertManySignature(objs[end]) == sig {
+			end++
+		}
+
+		rowsAff, err := o.insertBatch(ctx, tx, objs[start:end])
+		total += rowsAff
+		if err != nil {
+			return total, err
+		}
+		start = end
+	}
+	return total, nil
+}
+
+// insertManySignature identifies the table and column set of an object, as
+// only objects that share them can be inserted by the same statement.
+func insertManySignature(obj *object.Object) string {
+	keys := make([]string, 0, len(obj.KV))
+	for k := range obj.KV {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return obj.Type + "\x00" + strings.Join(keys, "\x00")
+}
+
+func (o *ORM) insertBatch(ctx context.Context, tx *sql.Tx, batch object.Array) (int64, error) {
+	sg := o.sqlGen
+	tracing := sg.Tracing
+	errorString := "InsertMany error"
+
+	table := batch[0].Type
+	objTable := o.s.GetTable(table)
+
+	// Keys are only set in objects without one
+	generated := 0
+	for _, obj := range batch {
+		if v := obj.Get(objTable.Primary); v == nil || obj.ValueIsNULL(v) {
+			generated++
+		}
+	}
+	if generated != 0 && generated != len(batch) {
+		return 0, errors.New("InsertMany: batch mixes supplied and generated primary keys for table " + table)
+	}
+	setKeys := !objTable.CallerSuppliesPK && generated > 0
+
+	rows := make([]map[string]interface{}, len(batch))
+	for i, obj := range batch {
+		rows[i] = obj.KV
+	}
+	sqlStr, bindArgs, err := sg.BindingInsertMany(sg, o.s, table, rows)
+	if err != nil {
+		if tracing {
+			log15.Error(errorString, "BindingInsertMany_error", err)
+		}
+		return 0, err
+	}
+	if tracing {
+		fmt.Println("InsertMany/sqlStr=", sqlStr, "bindArgs=", bindArgs)
+	}
+
+	stmt, err := stmtFromDbOrTx(ctx, o, tx, sqlStr)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		err := stmt.Close()
+		if err != nil {
+			fmt.Println(err) // TODO logger implementation
+		}
+	}()
+
+	var rowsAff int64
+	returnsIDs := !objTable.CallerSuppliesPK && (sg.IsPOSTGRES || sg.IsMSSQL || sg.IsDB2)
+	if returnsIDs {
+		ids, err := queryInsertedIDs(ctx, stmt, bindArgs)
+		if err != nil {
+			return 0, errors.Wrap(err, "InsertMany/QueryContext")
+		}
+		if len(ids) != len(batch) {
+			return 0, fmt.Errorf("InsertMany: expected %d new keys, received %d", len(batch), len(ids))
+		}
+		if !sg.IsDB2 {
+			sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		}
+		if setKeys {
+			for i, obj := range batch {
+				obj.SetCore(objTable.Primary, ids[i])
+			}
+		}
+		rowsAff = int64(len(ids))
+	} else {
+		res, err := stmt.ExecContext(ctx, bindArgs...)
+		if err != nil {
+			return 0, errors.Wrap(err, "InsertMany/ExecContext")
+		}
+		rowsAff, err = res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		if setKeys && (sg.IsSQLITE || sg.IsMYSQL) {
+			lastID, err := res.LastInsertId()
+			if err != nil {
+				return 0, err
+			}
+			// MySQL reports the first key generated by the
+			// statement, SQLite reports the last.
+			firstID := lastID
+			if sg.IsSQLITE {
+				firstID = lastID - int64(len(batch)) + 1
+			}
+			for i, obj := range batch {
+				obj.SetCore(objTable.Primary, firstID+int64(i))
+			}
+		}
+	}
+
+	if rowsAff == 0 {
+		return 0, ErrNoResult
+	}
+
+	for _, obj := range batch {
+		if err := o.runHooks(ctx, tx, AfterCreate, obj); err != nil {
+			return rowsAff, err
+		}
+		obj.MarkDirty(false)      // Note that the object has been recently saved
+		obj.ResetChangedColumns() // Reset the 'changed fields', if any
+	}
+	return rowsAff, nil
+}
+
+func queryInsertedIDs(ctx context.Context, stmt *sql.Stmt, bindArgs []interface{}) ([]int64, error) {
+	res, err := stmt.QueryContext(ctx, bindArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err := res.Close()
+		if err != nil {
+			fmt.Println(err) // TODO logger implementation
+		}
+	}()
+
+	var ids []int64
+	for res.Next() {
+		var id int64
+		if err := res.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, res.Err()
+}
@@ -10,7 +10,7 @@ import (
 	"github.com/rbastic/dyndao/schema"
 )
 
-func (o *ORM) postgreInsertHelper(ctx context.Context, stmt *sql.Stmt, bindArgs []interface{}, obj *object.Object, callerSuppliesPK bool, tracing bool, objTable *schema.Table) (int64, error) {
+func (o *ORM) postgreInsertHelper(ctx context.Context, tx *sql.Tx, stmt *sql.Stmt, bindArgs []interface{}, obj *object.Object, callerSuppliesPK bool, tracing bool, objTable *schema.Table) (int64, error) {
 	errorString := "Insert error"
 	var err error
 	var lastID int64
@@ -44,7 +44,7 @@ func (o *ORM) postgreInsertHelper(ctx context.Context, stmt *sql.Stmt, bindArgs
 	}
 
 	// Call after create hook
-	err = o.CallAfterCreateHookIfNeeded(obj)
+	err = o.runHooks(ctx, tx, AfterCreate, obj)
 	if err != nil {
 		if tracing {
 			log15.Error(errorString, "BeforeAfterCreateHookError", err)
@@ -0,0 +1,236 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/rbastic/dyndao/object"
+	"github.com/rbastic/dyndao/query"
+	"github.com/rbastic/dyndao/schema"
+)
+
+// ErrStopIteration may be returned from a ForEach callback to stop iterating
+// early. ForEach will then return nil rather than the error.
+var ErrStopIteration = errors.New("dyndao: stop iteration")
+
+// Iterator streams the results of a retrieval one object at a time, rather
+// than materialising them all in an object.Array. It must always be closed.
+// Typical use is:
+//
+//	it, err := o.Iterate(ctx, nil, "people", query.Gt("PersonID", 100), nil)
+//	if err != nil {
+//		return err
+//	}
+//	defer it.Close()
+//	for it.Next() {
+//		obj := it.Object()
+//		// ...
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+//
+// AfterRetrieve hooks are called by Next, while the result set is still
+// open, so they must not use the transaction being iterated in. Not every
+// driver can run another statement on a connection before its rows have
+// been read (MySQL's fails with "busy buffer"). The Retrieve functions call
+// the hooks once every row has been read, so hooks may use the transaction
+// there.
+type Iterator struct {
+	o        *ORM
+	ctx      context.Context
+	tx       *sql.Tx
+	table    string
+	objTable *schema.Table
+
+	stmt *sql.Stmt
+	res  *sql.Rows
+
+	columnNames    []string
+	columnTypes    []*sql.ColumnType
+	columnPointers []interface{}
+
+	obj    *object.Object
+	err    error
+	closed bool
+
+	// deferHooks leaves the AfterRetrieve hooks to the caller
+	deferHooks bool
+}
+
+// Iterate executes a retrieval for the objects matching q and returns an
+// Iterator over the results. tx may be nil, and opts may be nil.
+func (o *ORM) Iterate(ctx context.Context, tx *sql.Tx, table string, q *query.Query, opts *query.Options) (*Iterator, error) {
+	// Check for timeout
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	// Check to make sure that the table arg is a valid table name We will
+	// need objTable later.
+	objTable := o.s.GetTable(table)
+	if objTable == nil {
+		return nil, errors.New("Iterate: unknown object table " + table)
+	}
+	if objTable.Name == "" {
+		return nil, errors.New("Iterate: schema table object has unset 'Name' property")
+	}
+	q, err := o.runBeforeRetrieveHooks(ctx, tx, table, q)
+	if err != nil {
+		return nil, err
+	}
+	q = deletedFilter(objTable, q, opts)
+
+	// Generate a sql string, the column names, and the binding parameter
+	// arguments from the schema and the query
+	sg := o.sqlGen
+	sqlStr, columnNames, bindArgs, err := sg.BindingRetrieveQuery(sg, o.s, table, q, opts)
+
+	if sg.Tracing {
+		fmt.Println("Iterate/sqlStr=", sqlStr, "columnNames=", columnNames, "bindArgs=", bindArgs)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Determines whether we are running inside a transaction or not,
+	// returning stmt either way
+	stmt, err := stmtFromDbOrTx(ctx, o, tx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	it := &Iterator{
+		o:           o,
+		ctx:         ctx,
+		tx:          tx,
+		table:       table,
+		objTable:    objTable,
+		stmt:        stmt,
+		columnNames: columnNames,
+	}
+
+	it.res, err = stmt.QueryContext(ctx, bindArgs...)
+	if err != nil {
+		it.Close()
+		return nil, err
+	}
+
+	it.columnTypes, err = it.res.ColumnTypes()
+	if err != nil {
+		it.Close()
+		return nil, err
+	}
+
+	it.columnPointers, err = sg.MakeColumnPointers(sg, objTable, columnNames, it.columnTypes)
+	if err != nil {
+		it.Close()
+		return nil, err
+	}
+
+	return it, nil
+}
+
+// Next advances the Iterator to the next object, returning false when there
+// are no more objects or an error occurred (see Err). The Iterator is closed
+// automatically once Next returns false.
+func (it *Iterator) Next() bool {
+	it.obj = nil
+	if it.closed || it.err != nil {
+		return false
+	}
+	if !it.res.Next() {
+		it.err = it.res.Err()
+		it.Close()
+		return false
+	}
+
+	if err := it.res.Scan(it.columnPointers...); err != nil {
+		it.err = err
+		it.Close()
+		return false
+	}
+
+	sg := it.o.sqlGen
+	obj := object.New(it.table)
+	err := sg.DynamicObjectSetter(sg, it.objTable, it.columnNames, it.columnPointers, it.columnTypes, obj)
+	if err != nil {
+		it.err = err
+		it.Close()
+		return false
+	}
+	if !it.deferHooks {
+		if err := it.o.runHooks(it.ctx, it.tx, AfterRetrieve, obj); err != nil {
+			it.err = err
+			it.Close()
+			return false
+		}
+	}
+
+	obj.MarkDirty(false)
+	obj.ResetChangedColumns()
+	it.obj = obj
+	return true
+}
+
+// Object returns the object that the last call to Next advanced to.
+func (it *Iterator) Object() *object.Object {
+	return it.obj
+}
+
+// Err returns the error, if any, that stopped the iteration.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Close releases the underlying result set and statement. It is safe to
+// call more than once.
+func (it *Iterator) Close() error {
+	if it.closed {
+		return nil
+	}
+	it.closed = true
+
+	var err error
+	if it.res != nil {
+		err = it.res.Close()
+	}
+	if stmtErr := it.stmt.Close(); err == nil {
+		err = stmtErr
+	}
+	return err
+}
+
+// ForEach calls fn for every object matching q, without materialising them
+// all in memory. Iteration stops at the first error returned by fn, which is
+// then returned, unless that error is ErrStopIteration. As for Iterator,
+// neither fn nor the AfterRetrieve hooks may use tx. tx may be nil, and opts
+// may be nil.
+func (o *ORM) ForEach(ctx context.Context, tx *sql.Tx, table string, q *query.Query, opts *query.Options, fn func(*object.Object) error) error {
+	it, err := o.Iterate(ctx, tx, table, q, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := it.Close()
+		if err != nil {
+			fmt.Println(err) // TODO logger implementation
+		}
+	}()
+
+	for it.Next() {
+		if err := fn(it.Object()); err != nil {
+			if err == ErrStopIteration {
+				return nil
+			}
+			return err
+		}
+	}
+	return it.Err()
+}
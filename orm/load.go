@@ -19,7 +19,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/rbastic/dyndao/object"
-	"github.com/rbastic/dyndao/schema"
+	"github.com/rbastic/dyndao/query"
 )
 
 // GetParentsViaChild retrieves all direct (one-level 'up') parents for a given child object.
@@ -64,150 +64,67 @@ func (o *ORM) GetParentsViaChild(ctx context.Context, childObj *object.Object) (
 	return parentObjs, nil
 }
 
-// RetrieveWithChildren function will fleshen an *entire* object structure, given some primary keys
-// By entire, we mean that we also retrieve any relevant children objects. However, we do not call RetrieveWithChildren
-// when fleshening the children structures -- when retrieving the children, we do a single-level retrieve, ignoring
-// any child structures that may be configured at two levels of depth.
+// RetrieveWithChildren function will fleshen an object structure, given some primary keys,
+// along with every one of its children rows. Children of the children are not retrieved;
+// use RetrieveWithChildrenDepth to load deeper object graphs.
 func (o *ORM) RetrieveWithChildren(ctx context.Context, table string, pkValues map[string]interface{}) (*object.Object, error) {
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
 	}
-	// Retrieve schema.Table object
-	objTable := o.s.GetTable(table)
-	if objTable == nil {
-		return nil, errors.New("RetrieveWithChildren: unknown object table " + table)
-	}
-
-	// Retrieve single object from database
-	obj, err := o.Retrieve(ctx, table, pkValues)
-	if err != nil {
-		return nil, errors.Wrap(err, "RetrieveWithChildren/Retrieve")
-	}
-
-	// Iterate the configured 'children' for this particular object type
-	for name := range objTable.Children {
-		childPkValues := make(map[string]interface{})
-
-		// Retrieve the active 'schema table' for this child
-		childSchemaTable := o.s.GetTable(name)
-		if childSchemaTable == nil {
-			return nil, fmt.Errorf("RetrieveWithChildren: unknown object table for child type %s", name)
-		}
-
-		// (For each child...) Propagate the 'primary key value' from the parent object if needed.
-		childPkName := childSchemaTable.Primary
-		pVal, ok := pkValues[childPkName]
-		if ok {
-			childPkValues[childPkName] = pVal
-		}
-
-		// Propagate foreign key values for retrieval
-		if childSchemaTable.MultiKey && childSchemaTable.ForeignKeys != nil {
-			for _, fk := range childSchemaTable.ForeignKeys {
-				// TODO: Check that value exists before we
-				// attempt to set?
-				/*
-					v, ok := pkValues[fk]
-					if ok {
-						childPkValues[fk] = v
-					} else {
-						// TODO: is this an error condition?
-					}
-				*/
-
-				childPkValues[fk] = pkValues[fk]
-			}
-		}
-
-		// Retrieve a single child (TODO: Implement RetrieveMany options as well)
-		childObj, err := o.Retrieve(ctx, name, childPkValues)
-		if err != nil {
-			return nil, errors.Wrap(err, "RetrieveWithChildren/Retrieve("+name+")")
-		}
-		// Populate single child inside parent
-		if obj.Children[name] == nil {
-			obj.Children[name] = make(object.Array, 1)
-		}
-		obj.Children[name][0] = childObj
-	}
-
-	return obj, nil
+	return o.RetrieveWithChildrenDepth(ctx, nil, table, pkValues, 1)
 }
 
 // retrieveCore function will fleshen an object structure, given some primary keys.
-// Technically, we call RetrieveMany internally. Since we do not have LIMIT implemented yet,
-// it's just a cheap implementation that returns the zeroeth value. Nil will be returned
-// for both the object and the error if a row is unable to be matched by the underlying
-// datastore.
-// TODO: Implement LIMIT so that we can improve this.
+// Only a single row is requested from the database. If more than one row matches, which
+// one is returned is up to the database. Nil will be returned for both the object and the
+// error if a row is unable to be matched by the underlying datastore.
 func (o *ORM) retrieveCore(ctx context.Context, tx *sql.Tx, table string, queryVals map[string]interface{}) (*object.Object, error) {
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
 	}
-	objAry, err := o.retrieveManyCore(ctx, tx, table, queryVals)
+	objAry, err := o.retrieveManyCore(ctx, tx, table, query.FromKV(queryVals), &query.Options{Limit: 1})
 	if err != nil {
 		return nil, err
 	}
 	// We return nil, nil to indicate a lack of result.
-	if objAry == nil {
+	if len(objAry) == 0 {
 		return nil, nil
 	}
 	return objAry[0], nil
 }
 
 // RetrieveTx function will fleshen an object structure, given some primary keys.
-// Technically, we call RetrieveMany internally. Since we do not have LIMIT implemented yet,
-// it's just a cheap implementation that returns the zeroeth value. Nil will be returned
-// for both the object and the error if a row is unable to be matched by the underlying
-// datastore.
-// TODO: Implement LIMIT so that we can improve this.
+// Only a single row is requested from the database. If more than one row matches, which
+// one is returned is up to the database. Nil will be returned for both the object and the
+// error if a row is unable to be matched by the underlying datastore.
 func (o *ORM) RetrieveTx(ctx context.Context, tx *sql.Tx, table string, queryVals map[string]interface{}) (*object.Object, error) {
 	return o.retrieveCore(ctx, tx, table, queryVals)
 }
 
 // Retrieve function will fleshen an object structure, given some primary keys.
-// Technically, we call RetrieveMany internally. Since we do not have LIMIT implemented yet,
-// it's just a cheap implementation that returns the zeroeth value. Nil will be returned
-// for both the object and the error if a row is unable to be matched by the underlying
-// datastore.
-// TODO: Implement LIMIT so that we can improve this.
+// Only a single row is requested from the database. If more than one row matches, which
+// one is returned is up to the database. Nil will be returned for both the object and the
+// error if a row is unable to be matched by the underlying datastore.
 func (o *ORM) Retrieve(ctx context.Context, table string, queryVals map[string]interface{}) (*object.Object, error) {
 	return o.retrieveCore(ctx, nil, table, queryVals)
 }
 
-// FleshenChildren function accepts an object and resets it's children.
+// FleshenChildren function accepts an object and resets its children, loading every child
+// row for each of its configured child tables. Children are related as for
+// RetrieveWithChildrenDepth.
 func (o *ORM) FleshenChildren(ctx context.Context, obj *object.Object) (*object.Object, error) {
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
 	}
-	// Retrieve schema configuration for this object type (schema.Table)
-	schemaTable := o.s.GetTable(obj.Type)
-
-	// Retrieve primary key
-	pkKey := schemaTable.Primary
-	pkVal := obj.Get(pkKey)
-
-	// If this table is configured with child tables, then we iterate over
-	// them and call RetrieveMany using the singular primary key value.
-	// FIXME: We need to support multikey in this instance if we are going
-	// to consider this complete.
-	if len(schemaTable.Children) > 0 {
-		for childTableName := range schemaTable.Children {
-			// TODO: multi-key support here...
-			m := map[string]interface{}{}
-			m[pkKey] = pkVal
-			childObjs, err := o.RetrieveMany(ctx, childTableName, m)
-			if err != nil {
-				return nil, err
-			}
-			obj.Children[childTableName] = childObjs
-		}
+	err := o.loadChildren(ctx, nil, obj, 1, make(map[string]bool), nil)
+	if err != nil {
+		return nil, err
 	}
 	return obj, nil
 }
@@ -215,6 +132,7 @@ func (o *ORM) FleshenChildren(ctx context.Context, obj *object.Object) (*object.
 // RetrieveManyFromCustomSQL will fleshen an object structure, given a custom SQL string. It must still be told
 // the column names and the binding arguments in addition to the SQL string, so that it can dynamically map
 // the column types accordingly to the destination object. (Mainly, so we know the array length..)
+// AfterRetrieve hooks are called for each object, but BeforeRetrieve hooks are not.
 func (o *ORM) RetrieveManyFromCustomSQL(ctx context.Context, table string, sqlStr string, columnNames []string, bindArgs []interface{}) (object.Array, error) {
 	sg := o.sqlGen
 
@@ -277,6 +195,10 @@ func (o *ORM) RetrieveManyFromCustomSQL(ctx context.Context, table string, sqlSt
 		if err != nil {
 			return nil, err
 		}
+		err = o.runHooks(ctx, nil, AfterRetrieve, obj)
+		if err != nil {
+			return nil, err
+		}
 
 		obj.MarkDirty(false)
 		obj.ResetChangedColumns()
@@ -291,123 +213,67 @@ func (o *ORM) RetrieveManyFromCustomSQL(ctx context.Context, table string, sqlSt
 	return objectArray, nil
 }
 
-func (o *ORM) makeQueryObj(objTable *schema.Table, queryVals map[string]interface{}) *object.Object {
-	queryObj := object.New(objTable.Name)
-
-	if objTable.ColumnAliases == nil {
-		queryObj.KV = queryVals
-		return queryObj
-	}
-	for k, v := range queryVals {
-		realName := objTable.GetColumnName(k)
-		queryObj.KV[realName] = v
-	}
-	return queryObj
-}
-
-func (o *ORM) retrieveManyCore(ctx context.Context, tx *sql.Tx, table string, queryVals map[string]interface{}) (object.Array, error) {
-	// Check for timeout
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	default:
-	}
-
-	// Check to make sure that the table arg is a valid table name We will
-	// need objTable later.
-	objTable := o.s.GetTable(table)
-	if objTable == nil {
-		return nil, errors.New("RetrieveMany: unknown object table " + table)
-	}
-	if objTable.Name == "" {
-		return nil, errors.New("RetrieveMany: schema table object has unset 'Name' property")
-	}
-
-	var objectArray object.Array
-
-	// Construct a dyndao object from our queryVals
-	queryObj := o.makeQueryObj(objTable, queryVals)
-
-	// Generate a sql string, the column names, and the binding parameter
-	// arguments from the schema and the query object
-	sg := o.sqlGen
-	sqlStr, columnNames, bindArgs, err := sg.BindingRetrieve(sg, o.s, queryObj)
-
-	if sg.Tracing {
-		fmt.Println("RetrieveMany/sqlStr=", sqlStr, "columnNames=", columnNames, "bindArgs=", bindArgs)
-	}
-
-	if err != nil {
-		return nil, err
-	}
-
-	// Determines whether we are running inside a transaction or not,
-	// returning stmt either way
-	stmt, err := stmtFromDbOrTx(ctx, o, tx, sqlStr)
+func (o *ORM) retrieveManyCore(ctx context.Context, tx *sql.Tx, table string, q *query.Query, opts *query.Options) (object.Array, error) {
+	it, err := o.Iterate(ctx, tx, table, q, opts)
 	if err != nil {
 		return nil, err
 	}
-
 	defer func() {
-		err := stmt.Close()
+		err := it.Close()
 		if err != nil {
 			fmt.Println(err) // TODO logger implementation
 		}
 	}()
 
-	res, err := stmt.QueryContext(ctx, bindArgs...)
-	if err != nil {
-		return nil, err
-	}
+	// The hooks are called once every row has been read, so that they can
+	// use tx (see Iterator)
+	it.deferHooks = true
 
-	defer func() {
-		err := res.Close()
-		if err != nil {
-			fmt.Println(err) // TODO logger implementation
-		}
-	}()
-
-	columnTypes, err := res.ColumnTypes()
-	if err != nil {
-		return nil, err
+	var objectArray object.Array
+	for it.Next() {
+		objectArray = append(objectArray, it.Object())
 	}
-
-	columnPointers, err := sg.MakeColumnPointers(sg, objTable, columnNames, columnTypes)
-	if err != nil {
+	if err := it.Err(); err != nil {
 		return nil, err
 	}
-
-	for res.Next() {
-		obj := object.New(table)
-		if err := res.Scan(columnPointers...); err != nil {
+	for _, obj := range objectArray {
+		if err := o.runHooks(ctx, tx, AfterRetrieve, obj); err != nil {
 			return nil, err
 		}
-
-		err = sg.DynamicObjectSetter(sg, objTable, columnNames, columnPointers, columnTypes, obj)
-		if err != nil {
-			return nil, err
-		}
-
 		obj.MarkDirty(false)
 		obj.ResetChangedColumns()
-		objectArray = append(objectArray, obj)
-	}
-
-	err = res.Err()
-	if err != nil {
-		return nil, err
 	}
-
 	return objectArray, nil
 }
 
 // RetrieveManyTx function will fleshen a top-level object structure, given some primary keys. And
 // it's transactional!
 func (o *ORM) RetrieveManyTx(ctx context.Context, tx *sql.Tx, table string, queryVals map[string]interface{}) (object.Array, error) {
-	return o.retrieveManyCore(ctx, tx, table, queryVals)
+	return o.retrieveManyCore(ctx, tx, table, query.FromKV(queryVals), nil)
 }
 
 // RetrieveMany function will fleshen a top-level object structure, given some primary keys
 func (o *ORM) RetrieveMany(ctx context.Context, table string, queryVals map[string]interface{}) (object.Array, error) {
-	return o.retrieveManyCore(ctx, nil, table, queryVals)
+	return o.retrieveManyCore(ctx, nil, table, query.FromKV(queryVals), nil)
+}
+
+// RetrieveManyQueryTx is RetrieveManyTx for an arbitrary query.Query rather
+// than a map of equality values. opts may be nil.
+func (o *ORM) RetrieveManyQueryTx(ctx context.Context, tx *sql.Tx, table string, q *query.Query, opts *query.Options) (object.Array, error) {
+	return o.retrieveManyCore(ctx, tx, table, q, opts)
+}
+
+// RetrieveManyQuery will fleshen every object matching q. opts may be nil, or
+// may be used to order and paginate the results. For example:
+//
+//	objs, err := o.RetrieveManyQuery(ctx, "people", query.And(
+//		query.Like("Name", "J%"),
+//		query.Or(query.IsNull("NullInt"), query.Gt("NullInt", 5)),
+//	), &query.Options{
+//		OrderBy: []query.Order{query.Desc("PersonID")},
+//		Limit:   10,
+//		Offset:  20,
+//	})
+func (o *ORM) RetrieveManyQuery(ctx context.Context, table string, q *query.Query, opts *query.Options) (object.Array, error) {
+	return o.retrieveManyCore(ctx, nil, table, q, opts)
 }
@@ -7,6 +7,12 @@ import (
 	"github.com/pkg/errors"
 )
 
+// GetLock takes the named lock lockStr, returning true if it was acquired.
+// Locks are held by the database session, so tx should be given if the lock
+// is to be released, or held, reliably. Adapters render a statement that
+// returns 1 once the lock is held, except Oracle, whose PL/SQL block returns
+// nothing. sqlgen.ErrLocksUnsupported is returned for adapters that can't
+// take named locks.
 func (o *ORM) GetLock(ctx context.Context, tx *sql.Tx, lockStr string) (bool, error) {
 	sg := o.sqlGen
 	//tracing := sg.Tracing
@@ -39,6 +45,14 @@ func (o *ORM) GetLock(ctx context.Context, tx *sql.Tx, lockStr string) (bool, er
 		}
 	}()
 
+	if !sg.IsORACLE {
+		ok, err := lockStatus(ctx, stmt, bindWhere)
+		if err != nil {
+			return false, errors.Wrap(err, "GetLock")
+		}
+		return ok, nil
+	}
+
 	res, err := stmt.ExecContext(ctx, bindWhere...)
 	if err != nil {
 		return false, errors.Wrap(err, "GetLock")
@@ -56,6 +70,8 @@ func (o *ORM) GetLock(ctx context.Context, tx *sql.Tx, lockStr string) (bool, er
 	return true, nil
 }
 
+// ReleaseLock releases the named lock lockStr, taken by GetLock in the same
+// session, returning true if it was released.
 func (o *ORM) ReleaseLock(ctx context.Context, tx *sql.Tx, lockStr string) (bool, error) {
 	sg := o.sqlGen
 
@@ -86,6 +102,14 @@ func (o *ORM) ReleaseLock(ctx context.Context, tx *sql.Tx, lockStr string) (bool
 		}
 	}()
 
+	if !sg.IsORACLE {
+		ok, err := lockStatus(ctx, stmt, bindWhere)
+		if err != nil {
+			return false, errors.Wrap(err, "ReleaseLock")
+		}
+		return ok, nil
+	}
+
 	res, err := stmt.ExecContext(ctx, bindWhere...)
 	if err != nil {
 		return false, errors.Wrap(err, "ReleaseLock/ExecContext")
@@ -102,3 +126,16 @@ func (o *ORM) ReleaseLock(ctx context.Context, tx *sql.Tx, lockStr string) (bool
 
 	return true, nil
 }
+
+// lockStatus queries a lock statement's status, which is 1 on success.
+// ErrNoResult is returned for any other status, including NULL.
+func lockStatus(ctx context.Context, stmt *sql.Stmt, bindArgs []interface{}) (bool, error) {
+	var status sql.NullInt64
+	if err := stmt.QueryRowContext(ctx, bindArgs...).Scan(&status); err != nil {
+		return false, err
+	}
+	if !status.Valid || status.Int64 != 1 {
+		return false, ErrNoResult
+	}
+	return true, nil
+}
@@ -0,0 +1,192 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/rbastic/dyndao/object"
+	"github.com/rbastic/dyndao/query"
+	"github.com/rbastic/dyndao/schema"
+)
+
+// manyToManyKeys is a many-to-many relation with its defaults filled in.
+type manyToManyKeys struct {
+	rel                                    *schema.ManyToMany
+	related                                *schema.Table
+	local, joinLocal, joinForeign, foreign []string
+}
+
+// manyToMany looks up the named many-to-many relation of obj's table.
+func (o *ORM) manyToMany(obj *object.Object, relation string) (*manyToManyKeys, error) {
+	objTable := o.s.GetTable(obj.Type)
+	if objTable == nil {
+		return nil, errors.New("dyndao: unknown object table " + obj.Type)
+	}
+	rel, ok := objTable.ManyToMany[relation]
+	if !ok {
+		return nil, fmt.Errorf("dyndao: table %s has no many-to-many relation %s", obj.Type, relation)
+	}
+	if o.s.GetTable(rel.JoinTable) == nil {
+		return nil, errors.New("dyndao: unknown join table " + rel.JoinTable)
+	}
+	related := o.s.GetTable(rel.Table)
+	if related == nil {
+		return nil, errors.New("dyndao: unknown object table " + rel.Table)
+	}
+	k := &manyToManyKeys{rel: rel, related: related}
+	k.local, k.joinLocal, k.joinForeign, k.foreign = rel.Keys(objTable, related)
+	if len(k.local) != len(k.joinLocal) || len(k.foreign) != len(k.joinForeign) {
+		return nil, fmt.Errorf("dyndao: mismatched key columns for many-to-many relation %s", relation)
+	}
+	return k, nil
+}
+
+// joinValues returns the join table row relating obj to related.
+func (k *manyToManyKeys) joinValues(obj *object.Object, related *object.Object) (map[string]interface{}, error) {
+	localVals := keyValues(obj, k.local)
+	if localVals == nil {
+		return nil, errors.New("dyndao: missing key values for table " + obj.Type)
+	}
+	if related.Type != k.rel.Table {
+		return nil, fmt.Errorf("dyndao: expected an object of type %s, got %s", k.rel.Table, related.Type)
+	}
+	foreignVals := keyValues(related, k.foreign)
+	if foreignVals == nil {
+		return nil, errors.New("dyndao: missing key values for table " + related.Type)
+	}
+	vals := make(map[string]interface{}, len(k.joinLocal)+len(k.joinForeign))
+	for i, v := range localVals {
+		vals[k.joinLocal[i]] = v
+	}
+	for i, v := range foreignVals {
+		vals[k.joinForeign[i]] = v
+	}
+	return vals, nil
+}
+
+// RetrieveRelated retrieves the objects related to obj through the named
+// many-to-many relation of its table, using one query for the join table
+// rows and another for the related rows (more if there are over
+// ORM.PreloadBatchSize of them). An object related more than once is only
+// returned once. tx may be nil.
+func (o *ORM) RetrieveRelated(ctx context.Context, tx *sql.Tx, obj *object.Object, relation string) (object.Array, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+	k, err := o.manyToMany(obj, relation)
+	if err != nil {
+		return nil, errors.Wrap(err, "RetrieveRelated")
+	}
+	localVals := keyValues(obj, k.local)
+	if localVals == nil {
+		return object.NewEmptyArray(), nil
+	}
+
+	queryVals := make(map[string]interface{}, len(k.joinLocal))
+	for i, v := range localVals {
+		queryVals[k.joinLocal[i]] = v
+	}
+	joins, err := o.retrieveManyCore(ctx, tx, k.rel.JoinTable, query.FromKV(queryVals), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "RetrieveRelated/RetrieveMany("+k.rel.JoinTable+")")
+	}
+
+	var keys [][]interface{}
+	seen := make(map[string]bool)
+	for _, j := range joins {
+		vals := keyValues(j, k.joinForeign)
+		if vals == nil {
+			continue
+		}
+		if key := preloadKey(vals); !seen[key] {
+			seen[key] = true
+			keys = append(keys, vals)
+		}
+	}
+	if len(keys) == 0 {
+		return object.NewEmptyArray(), nil
+	}
+
+	related, err := o.preloadChildren(ctx, tx, k.rel.Table, k.foreign, keys)
+	if err != nil {
+		return nil, errors.Wrap(err, "RetrieveRelated")
+	}
+	return related, nil
+}
+
+// Link relates obj to related through the named many-to-many relation of
+// obj's table, by inserting a join table row. The create hooks are called
+// for the join table row. Both objects must have their key values set. tx
+// may be nil.
+func (o *ORM) Link(ctx context.Context, tx *sql.Tx, obj *object.Object, relation string, related *object.Object) (int64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+	k, err := o.manyToMany(obj, relation)
+	if err != nil {
+		return 0, errors.Wrap(err, "Link")
+	}
+	vals, err := k.joinValues(obj, related)
+	if err != nil {
+		return 0, errors.Wrap(err, "Link")
+	}
+	join := object.New(k.rel.JoinTable)
+	for col, v := range vals {
+		join.Set(col, v)
+	}
+	return o.Insert(ctx, tx, join)
+}
+
+// Unlink removes the relation between obj and related through the named
+// many-to-many relation of obj's table, by retrieving their join table rows
+// and deleting each as Delete does, so the delete hooks are called and a
+// join table with a SoftDeleteColumn keeps its rows. ErrNoResult is returned
+// if they weren't related. tx may be nil.
+func (o *ORM) Unlink(ctx context.Context, tx *sql.Tx, obj *object.Object, relation string, related *object.Object) (int64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+	k, err := o.manyToMany(obj, relation)
+	if err != nil {
+		return 0, errors.Wrap(err, "Unlink")
+	}
+	vals, err := k.joinValues(obj, related)
+	if err != nil {
+		return 0, errors.Wrap(err, "Unlink")
+	}
+	joinTable := o.s.GetTable(k.rel.JoinTable)
+	if joinTable == nil {
+		return 0, errors.New("Unlink: unknown join table " + k.rel.JoinTable)
+	}
+	rows, err := o.retrieveManyCore(ctx, tx, k.rel.JoinTable, query.FromKV(vals), nil)
+	if err != nil {
+		return 0, errors.Wrap(err, "Unlink")
+	}
+	if len(rows) == 0 {
+		return 0, ErrNoResult
+	}
+
+	var rowsAff int64
+	for _, row := range rows {
+		// Rows are deleted by primary key where the join table has one
+		filter := deleteAllFilter(joinTable, row)
+		if filter == nil {
+			filter = vals
+		}
+		aff, err := o.deleteCore(ctx, tx, row, filter)
+		rowsAff += aff
+		if err != nil {
+			return rowsAff, err
+		}
+	}
+	return rowsAff, nil
+}
@@ -0,0 +1,328 @@
+package orm
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/rbastic/dyndao/object"
+	"github.com/rbastic/dyndao/query"
+	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
+)
+
+const (
+	// MigrationsTable is the table in which Migrate records the migrations
+	// that have been applied. It is created when first needed.
+	MigrationsTable = "dyndao_migrations"
+
+	// migrationLock is the named lock held while migrating.
+	migrationLock = "dyndao_migrations"
+)
+
+// ErrMigrationChanged is returned by Migrate when a migration that has been
+// applied no longer has the checksum it was applied with.
+var ErrMigrationChanged = errors.New("dyndao: an applied migration has been changed")
+
+// Migration is a single versioned change to a database. Its ID identifies
+// it in MigrationsTable, so it must never change once the migration has
+// been applied anywhere.
+type Migration struct {
+	ID   string
+	Up   MigrationStep
+	Down MigrationStep
+}
+
+// MigrationStep is the work done by one direction of a migration: the
+// statements rendered for Diff, if set, followed by the raw SQL statements.
+type MigrationStep struct {
+	Diff *schema.SchemaDiff
+	SQL  []string
+}
+
+// IsEmpty reports whether the step has nothing to do.
+func (s MigrationStep) IsEmpty() bool {
+	return s.Diff == nil && len(s.SQL) == 0
+}
+
+func (s MigrationStep) statements(g *sg.SQLGenerator) ([]string, error) {
+	var stmts []string
+	if s.Diff != nil {
+		rendered, err := sg.RenderSchemaDiff(g, s.Diff)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, rendered...)
+	}
+	return append(stmts, s.SQL...), nil
+}
+
+// checksum identifies what a migration's Up step does, so that changes to
+// applied migrations can be detected: the tables its Diff adds and removes,
+// the columns, indexes and FOREIGN KEY constraints it adds, removes or
+// changes, and its SQL. The rest of the Diff's schemas isn't covered, so
+// that a migration built from a shared schema keeps its checksum as other
+// tables change. Neither is the adapter's rendering, which may change from
+// one release to the next.
+func (m Migration) checksum() string {
+	h := sha256.New()
+	if d := m.Up.Diff; d != nil {
+		for _, name := range d.AddedTables {
+			tbl := d.New.GetTable(name)
+			fmt.Fprintf(h, "add table %q primary %q\n", name, tbl.Primary)
+			keys := make([]string, 0, len(tbl.Columns))
+			for k := range tbl.Columns {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				writeColumnDef(h, "column", tbl.Columns[k])
+			}
+			for _, idx := range tbl.Indexes {
+				writeIndexDef(h, "index", idx)
+			}
+			for _, ref := range d.New.References(name) {
+				writeReferenceDef(h, "foreign key", ref)
+			}
+		}
+		for _, name := range d.RemovedTables {
+			fmt.Fprintf(h, "remove table %q\n", name)
+		}
+		for _, td := range d.ChangedTables {
+			fmt.Fprintf(h, "change table %q\n", td.Name)
+			for _, col := range td.AddedColumns {
+				writeColumnDef(h, "add column", col)
+			}
+			for _, col := range td.RemovedColumns {
+				fmt.Fprintf(h, "remove column %q\n", col.Name)
+			}
+			for _, cd := range td.ChangedColumns {
+				writeColumnDef(h, "change column from", cd.Old)
+				writeColumnDef(h, "change column to", cd.New)
+			}
+			for _, idx := range td.AddedIndexes {
+				writeIndexDef(h, "add index", idx)
+			}
+			for _, idx := range td.RemovedIndexes {
+				writeIndexDef(h, "remove index", idx)
+			}
+			for _, ref := range td.AddedForeignKeys {
+				writeReferenceDef(h, "add foreign key", ref)
+			}
+			for _, ref := range td.RemovedForeignKeys {
+				writeReferenceDef(h, "remove foreign key", ref)
+			}
+		}
+	}
+	for _, stmt := range m.Up.SQL {
+		fmt.Fprintf(h, "sql %q\n", stmt)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeColumnDef(w io.Writer, what string, col *schema.Column) {
+	fmt.Fprintf(w, "%s %q %q %d null=%t unique=%t identity=%t\n", what, col.Name, col.DBType, col.Length, col.AllowNull, col.IsUnique, col.IsIdentity)
+}
+
+func writeIndexDef(w io.Writer, what string, idx *schema.Index) {
+	fmt.Fprintf(w, "%s %q %q unique=%t where=%q\n", what, idx.Name, idx.Columns, idx.Unique, idx.Where)
+}
+
+func writeReferenceDef(w io.Writer, what string, ref *schema.Reference) {
+	fmt.Fprintf(w, "%s %q %q %q %q %q delete=%q update=%q\n", what, ref.Name, ref.Table, ref.Columns, ref.RefTable, ref.RefColumns, ref.OnDelete, ref.OnUpdate)
+}
+
+// migrationsSchema describes MigrationsTable.
+func migrationsSchema() *schema.Schema {
+	tbl := schema.DefaultTable()
+	tbl.Name = MigrationsTable
+	tbl.Primary = "MigrationID"
+	tbl.CallerSuppliesPK = true
+	tbl.Columns["MigrationID"] = &schema.Column{Name: "MigrationID", DBType: "varchar", Length: 255, IsUnique: true}
+	tbl.Columns["Checksum"] = &schema.Column{Name: "Checksum", DBType: "varchar", Length: 64}
+	tbl.Columns["AppliedAt"] = &schema.Column{Name: "AppliedAt", DBType: "timestamp"}
+	tbl.EssentialColumns = []string{"MigrationID", "Checksum", "AppliedAt"}
+
+	sch := schema.DefaultSchema()
+	sch.Tables[MigrationsTable] = tbl
+	return sch
+}
+
+// Migrate applies the migrations that haven't been applied yet, in the order
+// given, recording each in MigrationsTable. The IDs of the migrations
+// applied are returned. ErrMigrationChanged is returned, before anything is
+// applied, if an applied migration's Up step has changed since.
+//
+// Each migration is applied and recorded in a transaction of its own, except
+// on MySQL and Oracle, where DDL statements commit implicitly. A failed
+// migration there may need to be cleaned up by hand. A named lock is held
+// throughout, so that only one process migrates at a time. For adapters that
+// don't support GetLock, sqlgen.ErrLocksUnsupported is returned unless
+// ORM.MigrateWithoutLock is set.
+func (o *ORM) Migrate(ctx context.Context, migrations []Migration) ([]string, error) {
+	var applied []string
+	err := o.withMigrations(ctx, func(hist *ORM, done map[string]string) error {
+		checksums := make([]string, len(migrations))
+		for i, m := range migrations {
+			sum := m.checksum()
+			if prev, ok := done[m.ID]; ok && prev != sum {
+				return errors.Wrap(ErrMigrationChanged, m.ID)
+			}
+			checksums[i] = sum
+		}
+
+		for i, m := range migrations {
+			if _, ok := done[m.ID]; ok {
+				continue
+			}
+			err := o.runMigrationStep(ctx, m.Up, func(tx *sql.Tx) error {
+				rec := object.New(MigrationsTable)
+				rec.Set("MigrationID", m.ID)
+				rec.Set("Checksum", checksums[i])
+				rec.Set("AppliedAt", autoTime())
+				_, err := hist.Insert(ctx, tx, rec)
+				return err
+			})
+			if err != nil {
+				return errors.Wrap(err, "Migrate("+m.ID+")")
+			}
+			applied = append(applied, m.ID)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// MigrateDown reverts the last n applied migrations, taking migrations to be
+// in the order they were applied, by running their Down steps and removing
+// them from MigrationsTable. The IDs of the migrations reverted are
+// returned. Transactions and locking are as for Migrate.
+func (o *ORM) MigrateDown(ctx context.Context, migrations []Migration, n int) ([]string, error) {
+	var reverted []string
+	err := o.withMigrations(ctx, func(hist *ORM, done map[string]string) error {
+		for i := len(migrations) - 1; i >= 0 && len(reverted) < n; i-- {
+			m := migrations[i]
+			if _, ok := done[m.ID]; !ok {
+				continue
+			}
+			if m.Down.IsEmpty() {
+				return errors.New("MigrateDown: migration " + m.ID + " has no Down step")
+			}
+			err := o.runMigrationStep(ctx, m.Down, func(tx *sql.Tx) error {
+				_, err := hist.DeleteWhere(ctx, tx, MigrationsTable, query.Eq("MigrationID", m.ID))
+				return err
+			})
+			if err != nil {
+				return errors.Wrap(err, "MigrateDown("+m.ID+")")
+			}
+			reverted = append(reverted, m.ID)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// withMigrations takes the migration lock, ensures MigrationsTable exists
+// and calls fn with an ORM for it, and the checksums of the migrations
+// applied so far by ID.
+func (o *ORM) withMigrations(ctx context.Context, fn func(hist *ORM, done map[string]string) error) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	// Named locks belong to a session, so hold the lock on a connection of
+	// its own
+	lockTx, err := o.RawConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = lockTx.Rollback()
+	}()
+	_, err = o.GetLock(ctx, lockTx, migrationLock)
+	locked := err == nil
+	if err != nil && (err != sg.ErrLocksUnsupported || !o.MigrateWithoutLock) {
+		return errors.Wrap(err, "Migrate/GetLock")
+	}
+	if locked {
+		defer func() {
+			_, _ = o.ReleaseLock(context.Background(), lockTx, migrationLock)
+		}()
+	} else {
+		// Let the migrations use the connection instead
+		_ = lockTx.Rollback()
+	}
+
+	hist := New(o.sqlGen, migrationsSchema(), o.RawConn)
+	if _, err := hist.Count(ctx, nil, MigrationsTable, query.All()); err != nil {
+		if err := hist.CreateTable(ctx, hist.s, MigrationsTable); err != nil {
+			return errors.Wrap(err, "Migrate")
+		}
+	}
+
+	rows, err := hist.RetrieveManyQuery(ctx, MigrationsTable, query.All(), nil)
+	if err != nil {
+		return errors.Wrap(err, "Migrate")
+	}
+	done := make(map[string]string, len(rows))
+	for _, r := range rows {
+		id, err := r.GetStringAlways("MigrationID")
+		if err != nil {
+			return errors.Wrap(err, "Migrate")
+		}
+		sum, err := r.GetStringAlways("Checksum")
+		if err != nil {
+			return errors.Wrap(err, "Migrate")
+		}
+		done[id] = sum
+	}
+	return fn(hist, done)
+}
+
+// runMigrationStep executes the statements of step followed by record,
+// inside a transaction where the database allows DDL in one.
+func (o *ORM) runMigrationStep(ctx context.Context, step MigrationStep, record func(tx *sql.Tx) error) error {
+	stmts, err := step.statements(o.sqlGen)
+	if err != nil {
+		return err
+	}
+
+	if o.sqlGen.IsMYSQL || o.sqlGen.IsORACLE {
+		for _, stmt := range stmts {
+			if o.sqlGen.Tracing {
+				fmt.Println("Migrate/sqlStr=", stmt)
+			}
+			if _, err := o.RawConn.ExecContext(ctx, stmt); err != nil {
+				return errors.Wrap(err, stmt)
+			}
+		}
+		return record(nil)
+	}
+
+	tx, err := o.RawConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range stmts {
+		if o.sqlGen.Tracing {
+			fmt.Println("Migrate/sqlStr=", stmt)
+		}
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			_ = tx.Rollback()
+			return errors.Wrap(err, stmt)
+		}
+	}
+	if err := record(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -2,6 +2,7 @@ package orm
 
 import (
 	"database/sql"
+	"sync"
 
 	"github.com/rbastic/dyndao/schema"
 	sg "github.com/rbastic/dyndao/sqlgen"
@@ -23,6 +24,31 @@ type ORM struct {
 
 	BeforeDeleteHooks map[string]HookFunction
 	AfterDeleteHooks  map[string]HookFunction
+
+	// Hooks registered with RegisterHook, by event
+	hooks         map[HookEvent][]registeredHook
+	retrieveHooks []registeredRetrieveHook
+	nextHookID    int
+	hooksMu       sync.RWMutex
+
+	// InsertBatchSize is the maximum number of rows InsertMany will put in
+	// a single INSERT statement. Zero means DefaultInsertBatchSize.
+	InsertBatchSize int
+
+	// PreloadBatchSize is the maximum number of parent keys Preload will
+	// put in a single query. Zero means DefaultPreloadBatchSize.
+	PreloadBatchSize int
+
+	// DatabaseTime has AutoCreateTime and AutoUpdateTime columns set to the
+	// database's current UTC time, using the generator's CurrentTimestamp
+	// SQL, rather than the time in Go. Objects then hold that SQL, as an
+	// *object.SQLValue, until they are retrieved again.
+	DatabaseTime bool
+
+	// MigrateWithoutLock lets Migrate and MigrateDown run on adapters that
+	// can't take named locks, which they otherwise refuse to do. Only set
+	// it where no other process can migrate the database at the same time.
+	MigrateWithoutLock bool
 }
 
 // GetSchema returns the ORM's active schema
@@ -41,7 +67,7 @@ func (o *ORM) GetSQLGenerator() *sg.SQLGenerator {
 
 // New is the ORM constructor. It expects a SQL generator, JSON/SQL Schema object, and database connection.
 func New(gen *sg.SQLGenerator, s *schema.Schema, db *sql.DB) *ORM {
-	o := ORM{sqlGen: gen, s: s, RawConn: db}
+	o := ORM{sqlGen: gen, s: s, RawConn: db, hooks: make(map[HookEvent][]registeredHook)}
 
 	o.BeforeCreateHooks = makeEmptyHookMap()
 	o.AfterCreateHooks = makeEmptyHookMap()
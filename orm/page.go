@@ -0,0 +1,304 @@
+package orm
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/rbastic/dyndao/object"
+	"github.com/rbastic/dyndao/query"
+	"github.com/rbastic/dyndao/schema"
+)
+
+// DefaultPageSize is the page size used by RetrievePage when opts doesn't
+// supply a Limit.
+const DefaultPageSize = 100
+
+// ErrBadCursor is returned by RetrievePage when it is given a cursor that it
+// did not produce, or one that was produced for a different ordering.
+var ErrBadCursor = errors.New("dyndao: RetrievePage: invalid cursor")
+
+// pageCursor is the decoded form of a RetrievePage cursor: the sort key
+// columns and directions, and the last row's values for them.
+type pageCursor struct {
+	Columns []string      `json:"c"`
+	Desc    []bool        `json:"d"`
+	Values  []interface{} `json:"v"`
+}
+
+// RetrievePageTx is the transactional version of RetrievePage.
+func (o *ORM) RetrievePageTx(ctx context.Context, tx *sql.Tx, table string, q *query.Query, cursor string, opts *query.Options) (object.Array, string, error) {
+	objTable := o.s.GetTable(table)
+	if objTable == nil {
+		return nil, "", errors.New("RetrievePage: unknown object table " + table)
+	}
+
+	pageOpts := query.Options{Limit: DefaultPageSize}
+	if opts != nil {
+		if opts.Offset != 0 {
+			return nil, "", errors.New("RetrievePage: Offset cannot be combined with a cursor")
+		}
+		pageOpts.OrderBy = opts.OrderBy
+		pageOpts.Deleted = opts.Deleted
+		if opts.Limit > 0 {
+			pageOpts.Limit = opts.Limit
+		}
+	}
+	if len(pageOpts.OrderBy) == 0 {
+		pageOpts.OrderBy = objTable.DefaultOrder
+	}
+
+	orders, err := keysetOrder(objTable, pageOpts.OrderBy)
+	if err != nil {
+		return nil, "", err
+	}
+	pageOpts.OrderBy = orders
+
+	if cursor != "" {
+		after, err := o.decodeCursor(objTable, orders, cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		q = query.And(q, after)
+	}
+
+	// Ask for one row more than we need, so that we know whether there is
+	// another page without issuing a second query.
+	pageSize := pageOpts.Limit
+	pageOpts.Limit++
+
+	objs, err := o.retrieveManyCore(ctx, tx, table, q, &pageOpts)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(objs) <= pageSize {
+		return objs, "", nil
+	}
+	objs = objs[:pageSize]
+
+	next, err := encodeCursor(orders, objs[pageSize-1])
+	if err != nil {
+		return nil, "", err
+	}
+	return objs, next, nil
+}
+
+// RetrievePage retrieves a single page of the objects matching q, using
+// keyset pagination. Rather than skipping rows with an OFFSET (which gets
+// slower the deeper you page), each page is located by comparing against
+// the sort keys of the last row of the previous page.
+//
+// Pass an empty cursor for the first page. The returned cursor is an opaque
+// string that fetches the following page, and is empty once there are no
+// more rows. opts (which may be nil) supplies the ordering (or the table's
+// DefaultOrder is used) and the page size (Limit, defaulting to
+// DefaultPageSize). The primary key is always appended to the ordering as a
+// tiebreaker, so that pages are stable. Every sort key must be one of the
+// table's EssentialColumns, and must not allow NULL values. For example:
+//
+//	cursor := ""
+//	for {
+//		objs, next, err := o.RetrievePage(ctx, "audit", query.Gt("Created", since), cursor, &query.Options{Limit: 500})
+//		if err != nil {
+//			return err
+//		}
+//		// ... process objs ...
+//		if next == "" {
+//			break
+//		}
+//		cursor = next
+//	}
+func (o *ORM) RetrievePage(ctx context.Context, table string, q *query.Query, cursor string, opts *query.Options) (object.Array, string, error) {
+	return o.RetrievePageTx(ctx, nil, table, q, cursor, opts)
+}
+
+// keysetOrder validates the requested ordering for keyset pagination, and
+// appends the primary key (and, for MultiKey tables, the foreign keys) as a
+// tiebreaker where they aren't already part of it. The returned Order
+// columns are the EssentialColumns names used as object keys.
+func keysetOrder(objTable *schema.Table, orderBy []query.Order) ([]query.Order, error) {
+	seen := make(map[string]bool)
+	var orders []query.Order
+
+	add := func(ord query.Order) error {
+		col := objTable.GetColumn(ord.Column)
+		if col == nil {
+			return errors.New("RetrievePage: unknown field " + ord.Column + " in table " + objTable.Name)
+		}
+		if seen[col.Name] {
+			return nil
+		}
+		if col.AllowNull {
+			return errors.New("RetrievePage: cannot page on nullable field " + ord.Column + " in table " + objTable.Name)
+		}
+		essential := ""
+		for _, ec := range objTable.EssentialColumns {
+			if c := objTable.GetColumn(ec); c != nil && c.Name == col.Name {
+				essential = ec
+				break
+			}
+		}
+		if essential == "" {
+			return errors.New("RetrievePage: field " + ord.Column + " is not one of the EssentialColumns for table " + objTable.Name)
+		}
+		seen[col.Name] = true
+		ord.Column = essential
+		orders = append(orders, ord)
+		return nil
+	}
+
+	for _, ord := range orderBy {
+		if err := add(ord); err != nil {
+			return nil, err
+		}
+	}
+
+	tiebreak := []string{objTable.Primary}
+	if objTable.MultiKey {
+		tiebreak = append(tiebreak, objTable.ForeignKeys...)
+	}
+	for _, pk := range tiebreak {
+		if err := add(query.Asc(pk)); err != nil {
+			return nil, err
+		}
+	}
+	return orders, nil
+}
+
+// keysetPredicate builds the query for the rows that sort after vals:
+//
+//	(k1 > v1) OR (k1 = v1 AND k2 > v2) OR (k1 = v1 AND k2 = v2 AND k3 > v3) ...
+//
+// with < in place of > for descending keys.
+func keysetPredicate(orders []query.Order, vals []interface{}) *query.Query {
+	ors := make([]*query.Query, len(orders))
+	for i, ord := range orders {
+		ands := make([]*query.Query, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, query.Eq(orders[j].Column, vals[j]))
+		}
+		if ord.Desc {
+			ands = append(ands, query.Lt(ord.Column, vals[i]))
+		} else {
+			ands = append(ands, query.Gt(ord.Column, vals[i]))
+		}
+		ors[i] = query.And(ands...)
+	}
+	return query.Or(ors...)
+}
+
+func encodeCursor(orders []query.Order, obj *object.Object) (string, error) {
+	c := pageCursor{
+		Columns: make([]string, len(orders)),
+		Desc:    make([]bool, len(orders)),
+		Values:  make([]interface{}, len(orders)),
+	}
+	for i, ord := range orders {
+		v, err := cursorValue(obj.Get(ord.Column))
+		if err != nil {
+			return "", errors.Wrap(err, "RetrievePage: column "+ord.Column)
+		}
+		if v == nil {
+			return "", errors.New("RetrievePage: NULL value for sort key " + ord.Column)
+		}
+		c.Columns[i] = ord.Column
+		c.Desc[i] = ord.Desc
+		c.Values[i] = v
+	}
+	buf, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// cursorValue normalises an object value into something that survives a
+// round trip through JSON: driver.Valuer types (e.g. sql.NullString) are
+// unwrapped and time-like values become a time.Time.
+func cursorValue(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if _, ok := v.(*object.SQLValue); ok {
+		return nil, errors.New("cannot encode a raw SQL value in a cursor")
+	}
+	if valuer, ok := v.(driver.Valuer); ok {
+		dv, err := valuer.Value()
+		if err != nil {
+			return nil, err
+		}
+		v = dv
+		if v == nil {
+			return nil, nil
+		}
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Type().ConvertibleTo(timeType) {
+		return rv.Convert(timeType).Interface().(time.Time).UTC(), nil
+	}
+	return rv.Interface(), nil
+}
+
+func (o *ORM) decodeCursor(objTable *schema.Table, orders []query.Order, cursor string) (*query.Query, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrBadCursor
+	}
+	var c pageCursor
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	if err := dec.Decode(&c); err != nil {
+		return nil, ErrBadCursor
+	}
+	if len(c.Columns) != len(orders) || len(c.Desc) != len(orders) || len(c.Values) != len(orders) {
+		return nil, ErrBadCursor
+	}
+
+	vals := make([]interface{}, len(orders))
+	for i, ord := range orders {
+		if c.Columns[i] != ord.Column || c.Desc[i] != ord.Desc {
+			return nil, ErrBadCursor
+		}
+		v, err := o.decodeCursorValue(objTable.GetColumn(ord.Column), c.Values[i])
+		if err != nil {
+			return nil, errors.Wrap(ErrBadCursor, err.Error())
+		}
+		vals[i] = v
+	}
+	return keysetPredicate(orders, vals), nil
+}
+
+// decodeCursorValue restores the Go type of a cursor value, using the
+// column definition to tell timestamps apart from other strings.
+func (o *ORM) decodeCursorValue(col *schema.Column, v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i, nil
+		}
+		return val.Float64()
+	case string:
+		if o.sqlGen.IsTimestampType(col.DBType) {
+			return time.Parse(time.RFC3339Nano, val)
+		}
+		return val, nil
+	case bool:
+		return val, nil
+	}
+	return nil, errors.New("unexpected value for column " + col.Name)
+}
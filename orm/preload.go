@@ -0,0 +1,175 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/rbastic/dyndao/object"
+	"github.com/rbastic/dyndao/query"
+)
+
+// DefaultPreloadBatchSize is the number of parent keys Preload puts in a
+// single query, unless ORM.PreloadBatchSize says otherwise.
+const DefaultPreloadBatchSize = 500
+
+// PreloadTx loads the children of every object in parents with one query per
+// child table (more when there are more than ORM.PreloadBatchSize distinct
+// parent keys), rather than one query per parent, and sets them in each
+// parent's Children. The parents must all be of the same type. childTables
+// names the schema.ChildTable relations to load; all of the table's children
+// are loaded if none are given. Parents without child rows are given an
+// empty array. Children of the children are not loaded. tx may be nil.
+func (o *ORM) PreloadTx(ctx context.Context, tx *sql.Tx, parents object.Array, childTables ...string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+	if len(parents) == 0 {
+		return nil
+	}
+	table := parents[0].Type
+	for _, p := range parents {
+		if p.Type != table {
+			return fmt.Errorf("Preload: parents must share a type, found %s and %s", table, p.Type)
+		}
+	}
+	objTable := o.s.GetTable(table)
+	if objTable == nil {
+		return errors.New("Preload: unknown object table " + table)
+	}
+
+	if len(childTables) == 0 {
+		for name := range objTable.Children {
+			childTables = append(childTables, name)
+		}
+		sort.Strings(childTables)
+	}
+
+	for _, name := range childTables {
+		child, ok := objTable.Children[name]
+		if !ok {
+			return fmt.Errorf("Preload: %s is not a child table of %s", name, table)
+		}
+		childTable := o.s.GetTable(name)
+		if childTable == nil {
+			return fmt.Errorf("Preload: unknown object table for child type %s", name)
+		}
+		local, foreign := childKeys(objTable, childTable, child)
+		if len(local) != len(foreign) {
+			return fmt.Errorf("Preload: mismatched key columns for child type %s", name)
+		}
+
+		// Collect the distinct parent keys
+		var keys [][]interface{}
+		seen := make(map[string]bool)
+		for _, p := range parents {
+			p.Children[name] = object.NewEmptyArray()
+			vals := keyValues(p, local)
+			if vals == nil {
+				continue
+			}
+			if k := preloadKey(vals); !seen[k] {
+				seen[k] = true
+				keys = append(keys, vals)
+			}
+		}
+
+		children, err := o.preloadChildren(ctx, tx, name, foreign, keys)
+		if err != nil {
+			return err
+		}
+
+		// Group the children by their key, then hand them to their parents
+		byKey := make(map[string]object.Array)
+		for _, c := range children {
+			vals := keyValues(c, foreign)
+			if vals == nil {
+				continue
+			}
+			k := preloadKey(vals)
+			byKey[k] = append(byKey[k], c)
+		}
+		for _, p := range parents {
+			vals := keyValues(p, local)
+			if vals == nil {
+				continue
+			}
+			if found, ok := byKey[preloadKey(vals)]; ok {
+				p.Children[name] = found
+			}
+		}
+	}
+	return nil
+}
+
+// Preload loads the children of every object in parents, in as few queries
+// as possible. See PreloadTx.
+func (o *ORM) Preload(ctx context.Context, parents object.Array, childTables ...string) error {
+	return o.PreloadTx(ctx, nil, parents, childTables...)
+}
+
+// preloadChildren retrieves the rows of table whose foreign columns match
+// any of keys, in batches that stay within the binding parameter limits.
+func (o *ORM) preloadChildren(ctx context.Context, tx *sql.Tx, table string, foreign []string, keys [][]interface{}) (object.Array, error) {
+	batchSize := o.PreloadBatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultPreloadBatchSize
+	}
+	if perKey := maxBindArgs / len(foreign); perKey < batchSize {
+		batchSize = perKey
+	}
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	var children object.Array
+	for start := 0; start < len(keys); start += batchSize {
+		end := start + batchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		batch := keys[start:end]
+
+		var q *query.Query
+		if len(foreign) == 1 {
+			vals := make([]interface{}, len(batch))
+			for i, k := range batch {
+				vals[i] = k[0]
+			}
+			q = query.In(foreign[0], vals...)
+		} else {
+			alts := make([]*query.Query, len(batch))
+			for i, k := range batch {
+				eqs := make([]*query.Query, len(foreign))
+				for j, col := range foreign {
+					eqs[j] = query.Eq(col, k[j])
+				}
+				alts[i] = query.And(eqs...)
+			}
+			q = query.Or(alts...)
+		}
+
+		objs, err := o.retrieveManyCore(ctx, tx, table, q, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "Preload/RetrieveMany("+table+")")
+		}
+		children = append(children, objs...)
+	}
+	return children, nil
+}
+
+// preloadKey identifies a key's values irrespective of their Go types, so
+// that e.g. an int key set by the caller matches an int64 read back.
+func preloadKey(vals []interface{}) string {
+	parts := make([]string, len(vals))
+	for i, v := range vals {
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, "\x00")
+}
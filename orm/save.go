@@ -47,28 +47,15 @@ func (o *ORM) recurseAndSave(ctx context.Context, tx *sql.Tx, obj *object.Object
 	}
 
 	table := o.s.GetTable(obj.Type)
-	pkVal := obj.Get(table.Primary)
 
-	for _, v := range obj.Children {
+	for name, v := range obj.Children {
 		for _, childObj := range v {
-			// set the primary key in the child object, if it exists in the child object's table
 			childTable, ok := o.s.Tables[childObj.Type]
 			if !ok {
 				return 0, fmt.Errorf("recurseAndSave: Unknown child object type %s for parent type %s", childObj.Type, obj.Type)
 			}
-			// TODO: support propagation of additional primary keys
-			// that are saved from previous recursive saves ...
-			// check if the child schema table contains the
-			// parent's primary key field as a name
-			_, ok = childTable.Columns[table.Primary]
-			if ok {
-				// set in the child object if the table contains the primary
-				childObj.Set(table.Primary, pkVal)
-			}
+			propagateKeys(table, obj, childTable, childObj, table.Children[name])
 
-			// TODO: Likely we can just use pkQueryValsFromKV here,
-			// but I would like to add some test cases and verify
-			// the functionality under different scenarios.
 			aff, err := o.recurseAndSave(ctx, tx, childObj)
 			if err != nil {
 				return rowsAff + aff, err
@@ -78,6 +65,30 @@ func (o *ORM) recurseAndSave(ctx context.Context, tx *sql.Tx, obj *object.Object
 	return rowsAff, err
 }
 
+// propagateKeys copies the values relating parent to child into child, as
+// given by childKeys (rel may be nil), so that keys propagate down through
+// multi-column relationships. Columns the child's table doesn't have, and
+// parent values that are unset, are skipped.
+func propagateKeys(parentTable *schema.Table, parent *object.Object, childTable *schema.Table, child *object.Object, rel *schema.ChildTable) {
+	local, foreign := childKeys(parentTable, childTable, rel)
+
+	set := make(map[string]bool, len(foreign))
+	for i := range local {
+		if i >= len(foreign) || set[foreign[i]] {
+			continue
+		}
+		if _, ok := childTable.Columns[foreign[i]]; !ok {
+			continue
+		}
+		v := parent.Get(local[i])
+		if v == nil {
+			continue
+		}
+		child.Set(foreign[i], v)
+		set[foreign[i]] = true
+	}
+}
+
 // SaveAllInsideTx will attempt to save an entire nested object structure inside of a single transaction.
 func (o *ORM) SaveAllInsideTx(ctx context.Context, tx *sql.Tx, obj *object.Object) (int64, error) {
 	select {
@@ -112,10 +123,7 @@ func (o *ORM) SaveAll(ctx context.Context, obj *object.Object) (int64, error) {
 	}
 	rowsAff, err := o.SaveAllInsideTx(ctx, tx, obj)
 	if err != nil {
-		rollErr := tx.Rollback()
-		if rollErr != nil {
-			return 0, errors.Wrap(err, rollErr.Error())
-		}
+		// SaveAllInsideTx has already rolled back
 		return 0, err
 	}
 
@@ -0,0 +1,117 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+
+	"github.com/rbastic/dyndao/object"
+	"github.com/rbastic/dyndao/query"
+	"github.com/rbastic/dyndao/schema"
+)
+
+// deletedFilter restricts q to the rows of objTable that opts.Deleted asks
+// for, when the table has soft deletion. opts may be nil.
+func deletedFilter(objTable *schema.Table, q *query.Query, opts *query.Options) *query.Query {
+	col := objTable.SoftDeleteColumn
+	if col == "" {
+		return q
+	}
+	deleted := query.ExcludeDeleted
+	if opts != nil {
+		deleted = opts.Deleted
+	}
+	switch deleted {
+	case query.WithDeleted:
+		return q
+	case query.OnlyDeleted:
+		return query.And(q, query.IsNotNull(col))
+	}
+	return query.And(q, query.IsNull(col))
+}
+
+// isSoftDeleted reports whether obj has a value for its table's
+// SoftDeleteColumn, as set by Delete or loaded from a soft deleted row.
+// Values loaded from NULL columns, such as an invalid sql.NullString or a
+// nil pointer, don't count.
+func isSoftDeleted(objTable *schema.Table, obj *object.Object) bool {
+	if objTable.SoftDeleteColumn == "" {
+		return false
+	}
+	v := obj.Get(objTable.SoftDeleteColumn)
+	if v == nil || obj.ValueIsNULL(v) {
+		return false
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return false
+	}
+	if valuer, ok := v.(driver.Valuer); ok {
+		dv, err := valuer.Value()
+		return err == nil && dv != nil
+	}
+	return true
+}
+
+// primaryKeyQuery matches obj's row by its primary key, and for MultiKey
+// tables, its foreign keys.
+func primaryKeyQuery(objTable *schema.Table, obj *object.Object) (*query.Query, error) {
+	keys := []string{objTable.Primary}
+	if objTable.MultiKey {
+		keys = append(keys, objTable.ForeignKeys...)
+	}
+	qs := make([]*query.Query, len(keys))
+	for i, k := range keys {
+		v := obj.Get(k)
+		if v == nil {
+			return nil, errors.New("dyndao: missing primary key " + k + " for table " + objTable.Name)
+		}
+		qs[i] = query.Eq(k, v)
+	}
+	return query.And(qs...), nil
+}
+
+// Restore undoes the soft deletion of obj, which is matched by its primary
+// key. ErrNoResult is returned if there is no such soft deleted row. The
+// table must have a SoftDeleteColumn. tx may be nil.
+func (o *ORM) Restore(ctx context.Context, tx *sql.Tx, obj *object.Object) (int64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+	objTable := o.s.GetTable(obj.Type)
+	if objTable == nil {
+		return 0, errors.New("Restore: unknown object table " + obj.Type)
+	}
+	col := objTable.SoftDeleteColumn
+	if col == "" {
+		return 0, errors.New("Restore: table " + obj.Type + " has no SoftDeleteColumn")
+	}
+	q, err := primaryKeyQuery(objTable, obj)
+	if err != nil {
+		return 0, errors.Wrap(err, "Restore")
+	}
+
+	sg := o.sqlGen
+	sqlStr, bindArgs, err := sg.BindingUpdateWhere(sg, o.s, obj.Type, map[string]interface{}{col: nil}, query.And(q, query.IsNotNull(col)))
+	if err != nil {
+		return 0, err
+	}
+	if sg.Tracing {
+		fmt.Println("Restore/sqlStr=", sqlStr, "bindArgs=", bindArgs)
+	}
+	rowsAff, err := o.execRowsAffected(ctx, tx, sqlStr, bindArgs)
+	if err != nil {
+		return 0, errors.Wrap(err, "Restore")
+	}
+	if rowsAff == 0 {
+		return 0, ErrNoResult
+	}
+
+	obj.SetCore(col, object.NewNULLValue())
+	return rowsAff, nil
+}
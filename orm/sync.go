@@ -0,0 +1,135 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/rbastic/dyndao/object"
+	"github.com/rbastic/dyndao/query"
+)
+
+// SyncAllInsideTx saves an entire nested object structure like
+// SaveAllInsideTx, and also makes the stored children match obj's: any
+// stored child row of a child table present in obj.Children that isn't
+// among the children given is deleted, along with its own children (or soft
+// deleted, for tables with a SoftDeleteColumn). An empty array removes every
+// child of that table, while a child table with no entry in obj.Children at
+// all is left alone. Children are matched to stored rows by primary key, so
+// children that are new (without a primary key) are inserted. Only child
+// tables configured in the schema's Children can be synced. The transaction
+// is rolled back on error.
+func (o *ORM) SyncAllInsideTx(ctx context.Context, tx *sql.Tx, obj *object.Object) (int64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+	rowsAff, err := o.recurseAndSync(ctx, tx, obj)
+	if err != nil {
+		err2 := tx.Rollback()
+		if err2 != nil {
+			return 0, errors.Wrap(err, err2.Error())
+		}
+		return 0, err
+	}
+	return rowsAff, nil
+}
+
+// SyncAll begins a transaction and calls SyncAllInsideTx, then commits it.
+func (o *ORM) SyncAll(ctx context.Context, obj *object.Object) (int64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	tx, err := o.RawConn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	rowsAff, err := o.SyncAllInsideTx(ctx, tx, obj)
+	if err != nil {
+		// SyncAllInsideTx has already rolled back
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		rollErr := tx.Rollback()
+		if rollErr != nil {
+			return 0, errors.Wrap(err, rollErr.Error())
+		}
+		return 0, err
+	}
+	return rowsAff, nil
+}
+
+func (o *ORM) recurseAndSync(ctx context.Context, tx *sql.Tx, obj *object.Object) (int64, error) {
+	rowsAff, err := o.Save(ctx, tx, obj)
+	if err != nil {
+		return 0, err
+	}
+
+	table := o.s.GetTable(obj.Type)
+
+	names := make([]string, 0, len(obj.Children))
+	for name := range obj.Children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		rel, ok := table.Children[name]
+		if !ok {
+			return rowsAff, errors.New("SyncAll: " + name + " is not a child table of " + obj.Type)
+		}
+		childTable := o.s.GetTable(name)
+		if childTable == nil {
+			return rowsAff, errors.New("SyncAll: unknown object table for child type " + name)
+		}
+
+		keep := make(map[string]bool, len(obj.Children[name]))
+		for _, childObj := range obj.Children[name] {
+			if childObj.Type != name {
+				return rowsAff, errors.New("SyncAll: child object type " + childObj.Type + " stored as " + name)
+			}
+			propagateKeys(table, obj, childTable, childObj, rel)
+
+			aff, err := o.recurseAndSync(ctx, tx, childObj)
+			rowsAff += aff
+			if err != nil {
+				return rowsAff, err
+			}
+			keep[rowKey(childTable, childObj)] = true
+		}
+
+		// Remove the stored children that are no longer present
+		local, foreign := childKeys(table, childTable, rel)
+		vals := keyValues(obj, local)
+		if vals == nil || len(local) != len(foreign) {
+			continue
+		}
+		queryVals := make(map[string]interface{}, len(foreign))
+		for i, v := range vals {
+			queryVals[foreign[i]] = v
+		}
+		stored, err := o.retrieveManyCore(ctx, tx, name, query.FromKV(queryVals), nil)
+		if err != nil {
+			return rowsAff, errors.Wrap(err, "SyncAll/RetrieveMany("+name+")")
+		}
+		for _, orphan := range stored {
+			if keep[rowKey(childTable, orphan)] {
+				continue
+			}
+			aff, err := o.recurseAndDelete(ctx, tx, orphan, &DeleteAllOptions{LoadChildren: true}, make(map[string]bool), true)
+			rowsAff += aff
+			if err != nil {
+				return rowsAff, err
+			}
+		}
+	}
+	return rowsAff, nil
+}
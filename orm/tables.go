@@ -9,13 +9,19 @@ import (
 	"database/sql"
 	"github.com/pkg/errors"
 	"github.com/rbastic/dyndao/schema"
+	sg "github.com/rbastic/dyndao/sqlgen"
 )
 
 // CreateTables executes a CreateTable operation for every table specified in
-// the schema.
+// the schema, in the schema's CreationOrder, so that the tables a FOREIGN
+// KEY constraint refers to exist before it is created.
 func (o *ORM) CreateTables(ctx context.Context) error {
-	for tName := range o.s.Tables {
-		err := o.CreateTable(ctx, o.s, tName)
+	order, err := o.s.CreationOrder()
+	if err != nil {
+		return errors.Wrap(err, "CreateTables")
+	}
+	for _, tName := range order {
+		err = o.CreateTable(ctx, o.s, tName)
 		if err != nil {
 			return err
 		}
@@ -25,10 +31,14 @@ func (o *ORM) CreateTables(ctx context.Context) error {
 }
 
 // DropTables executes a DropTable operation for every table specified in the
-// schema.
+// schema, in the reverse of the schema's CreationOrder.
 func (o *ORM) DropTables(ctx context.Context) error {
-	for tName := range o.s.Tables {
-		err := o.DropTable(ctx, tName)
+	order, err := o.s.CreationOrder()
+	if err != nil {
+		return errors.Wrap(err, "DropTables")
+	}
+	for i := len(order) - 1; i >= 0; i-- {
+		err = o.DropTable(ctx, order[i])
 		if err != nil {
 			return err
 		}
@@ -38,7 +48,8 @@ func (o *ORM) DropTables(ctx context.Context) error {
 }
 
 // CreateTable will execute a CreateTable operation for the specified table in
-// a given schema.
+// a given schema, followed by a CreateIndex operation for each of its
+// Indexes.
 func (o *ORM) CreateTable(ctx context.Context, sch *schema.Schema, tableName string) error {
 	sqlStr, err := o.sqlGen.CreateTable(o.sqlGen, sch, tableName)
 	if err != nil {
@@ -55,6 +66,20 @@ func (o *ORM) CreateTable(ctx context.Context, sch *schema.Schema, tableName str
 	if err != nil {
 		return errors.Wrap(err, "CreateTable")
 	}
+
+	indexes, err := sg.RenderCreateIndexes(o.sqlGen, sch, tableName)
+	if err != nil {
+		return errors.Wrap(err, "CreateTable")
+	}
+	for _, sqlStr := range indexes {
+		if debug != "" {
+			fmt.Println("CreateIndex:", sqlStr)
+		}
+		_, err = prepareAndExecSQL(ctx, o.RawConn, sqlStr)
+		if err != nil {
+			return errors.Wrap(err, "CreateTable/CreateIndex")
+		}
+	}
 	return nil
 }
 
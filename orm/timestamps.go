@@ -0,0 +1,53 @@
+package orm
+
+import (
+	"time"
+
+	"github.com/rbastic/dyndao/object"
+	"github.com/rbastic/dyndao/schema"
+)
+
+// stampCreateTimes sets the AutoCreateTime and AutoUpdateTime columns of a
+// new object to now, where the caller hasn't supplied a value.
+func stampCreateTimes(objTable *schema.Table, obj *object.Object, now interface{}) {
+	for k, col := range objTable.Columns {
+		if !col.AutoCreateTime && !col.AutoUpdateTime {
+			continue
+		}
+		if v := obj.Get(k); v == nil || obj.ValueIsNULL(v) {
+			obj.Set(k, now)
+		}
+	}
+}
+
+// stampUpdateTimes sets the AutoUpdateTime columns of an object about to be
+// updated to now. When only the changed columns of obj are going to be
+// updated (partial), the stamped columns are marked as changed too.
+func stampUpdateTimes(objTable *schema.Table, obj *object.Object, now interface{}, partial bool) {
+	for k, col := range objTable.Columns {
+		if !col.AutoUpdateTime {
+			continue
+		}
+		if partial {
+			if _, ok := obj.ChangedColumns[k]; !ok {
+				obj.ColumnChanged(k, obj.Get(k))
+			}
+		}
+		obj.SetCore(k, now)
+	}
+}
+
+// autoTime is the current UTC time in Go
+func autoTime() time.Time {
+	return time.Now().UTC()
+}
+
+// stampTime is the value used for AutoCreateTime and AutoUpdateTime
+// columns: the database's current time if ORM.DatabaseTime is set, or else
+// autoTime.
+func (o *ORM) stampTime() interface{} {
+	if o.DatabaseTime {
+		return object.NewSQLValue(o.sqlGen.CurrentTimestamp())
+	}
+	return autoTime()
+}
@@ -8,9 +8,13 @@ import (
 	"github.com/inconshreveable/log15"
 	"github.com/pkg/errors"
 	"github.com/rbastic/dyndao/object"
+	"github.com/rbastic/dyndao/query"
 )
 
-// Update function will UPDATE a record ...
+// Update function will UPDATE a record ... For tables with a VersionColumn,
+// only the row with the object's current version is updated, and the
+// object's version is advanced. ErrStaleObject is returned if there is no
+// such row. AutoUpdateTime columns are set to the current UTC time.
 func (o *ORM) Update(ctx context.Context, tx *sql.Tx, obj *object.Object) (int64, error) {
 	sg := o.sqlGen
 	tracing := sg.Tracing
@@ -23,7 +27,12 @@ func (o *ORM) Update(ctx context.Context, tx *sql.Tx, obj *object.Object) (int64
 	default:
 	}
 
-	err := o.CallBeforeUpdateHookIfNeeded(obj)
+	objTable := o.s.GetTable(obj.Type)
+	if objTable == nil {
+		return 0, errors.New("Update: unknown object table " + obj.Type)
+	}
+
+	err := o.runHooks(ctx, tx, BeforeUpdate, obj)
 	if err != nil {
 		if tracing {
 			log15.Error(errorString, "BeforeUpdateHookError", err)
@@ -31,8 +40,18 @@ func (o *ORM) Update(ctx context.Context, tx *sql.Tx, obj *object.Object) (int64
 		return 0, err
 	}
 
+	// Decide between a partial and a full update before the ORM changes
+	// anything itself
+	stampUpdateTimes(objTable, obj, o.stampTime(), len(obj.ChangedColumns) > 0)
+
+	restoreVersion, err := o.advanceVersion(objTable, obj)
+	if err != nil {
+		return 0, errors.Wrap(err, "Update")
+	}
+
 	sqlStr, bindArgs, bindWhere, err := sg.BindingUpdate(sg, o.s, obj)
 	if err != nil {
+		restoreVersion()
 		if tracing {
 			fmt.Println("Update/sqlStr, err=", err)
 		}
@@ -44,6 +63,7 @@ func (o *ORM) Update(ctx context.Context, tx *sql.Tx, obj *object.Object) (int64
 
 	stmt, err := stmtFromDbOrTx(ctx, o, tx, sqlStr)
 	if err != nil {
+		restoreVersion()
 		return 0, err
 	}
 	defer func() {
@@ -64,15 +84,21 @@ func (o *ORM) Update(ctx context.Context, tx *sql.Tx, obj *object.Object) (int64
 	}
 	res, err := stmt.ExecContext(ctx, newAllBind...)
 	if err != nil {
+		restoreVersion()
 		return 0, errors.Wrap(err, "Update")
 	}
 
 	rowsAff, err := res.RowsAffected()
 	if err != nil {
+		restoreVersion()
 		return 0, err
 	}
+	if rowsAff == 0 && objTable.VersionColumn != "" {
+		restoreVersion()
+		return 0, ErrStaleObject
+	}
 
-	err = o.CallAfterUpdateHookIfNeeded(obj)
+	err = o.runHooks(ctx, tx, AfterUpdate, obj)
 	if err != nil {
 		if tracing {
 			log15.Error(errorString, "BeforeAfterUpdateHookError", err)
@@ -85,3 +111,51 @@ func (o *ORM) Update(ctx context.Context, tx *sql.Tx, obj *object.Object) (int64
 
 	return rowsAff, nil
 }
+
+// UpdateWhere applies set to every row of table matching q in a single
+// statement, returning the number of rows affected. Values in set may be
+// *object.SQLValue for raw SQL, or nil for NULL. AutoUpdateTime columns are
+// set to the current UTC time (see ORM.DatabaseTime) unless set supplies
+// them. No hooks are called, as no objects are involved. An empty q returns
+// ErrEmptyFilter; pass query.All() to update every row deliberately. tx may
+// be nil.
+func (o *ORM) UpdateWhere(ctx context.Context, tx *sql.Tx, table string, set map[string]interface{}, q *query.Query) (int64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+	if q.IsEmpty() {
+		return 0, ErrEmptyFilter
+	}
+	objTable := o.s.GetTable(table)
+	if objTable == nil {
+		return 0, errors.New("UpdateWhere: unknown object table " + table)
+	}
+
+	// Copy set rather than adding timestamps to the caller's map
+	stamped := make(map[string]interface{}, len(set))
+	for k, v := range set {
+		stamped[k] = v
+	}
+	now := o.stampTime()
+	for k, col := range objTable.Columns {
+		if _, ok := stamped[k]; col.AutoUpdateTime && !ok {
+			stamped[k] = now
+		}
+	}
+
+	sg := o.sqlGen
+	sqlStr, bindArgs, err := sg.BindingUpdateWhere(sg, o.s, table, stamped, q)
+	if err != nil {
+		return 0, err
+	}
+	if sg.Tracing {
+		fmt.Println("UpdateWhere/sqlStr=", sqlStr, "bindArgs=", bindArgs)
+	}
+	rowsAff, err := o.execRowsAffected(ctx, tx, sqlStr, bindArgs)
+	if err != nil {
+		return 0, errors.Wrap(err, "UpdateWhere")
+	}
+	return rowsAff, nil
+}
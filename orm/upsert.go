@@ -0,0 +1,86 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/rbastic/dyndao/object"
+	"github.com/rbastic/dyndao/query"
+)
+
+// Upsert inserts obj, or, where a row with the same values for
+// conflictColumns already exists, updates that row with the rest of obj's
+// values, as a single statement. conflictColumns must be covered by a
+// primary key or unique index (MySQL ignores them and uses whichever unique
+// key the row collides with). Identity and AutoCreateTime columns are never
+// updated. AutoUpdateTime columns are set to the current time whether obj
+// has a value for them or not. Unless obj has a value for the table's
+// SoftDeleteColumn, it is set to NULL, so a soft deleted row that conflicts
+// is restored. Tables with a VersionColumn aren't supported, as the version
+// can be neither matched nor advanced; use Save for those.
+//
+// If obj has no primary key value and the table doesn't have
+// CallerSuppliesPK set, the key of the inserted or updated row is retrieved
+// using the conflictColumns values, whether the row is soft deleted or not,
+// and set on obj. No create or update hooks are called, as which of the two
+// happened isn't known. The number of rows affected is returned as reported
+// by the driver, which varies between databases for updates (e.g. MySQL
+// reports 2). tx may be nil.
+func (o *ORM) Upsert(ctx context.Context, tx *sql.Tx, obj *object.Object, conflictColumns []string) (int64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+	objTable := o.s.GetTable(obj.Type)
+	if objTable == nil {
+		return 0, errors.New("Upsert: unknown object table " + obj.Type)
+	}
+	if objTable.VersionColumn != "" {
+		return 0, errors.New("Upsert: table " + obj.Type + " has a VersionColumn")
+	}
+
+	// The row may be updated, so AutoUpdateTime columns are always stamped,
+	// rather than only when obj lacks a value as for inserts
+	now := o.stampTime()
+	stampCreateTimes(objTable, obj, now)
+	stampUpdateTimes(objTable, obj, now, false)
+	if col := objTable.SoftDeleteColumn; col != "" && obj.Get(col) == nil {
+		obj.Set(col, object.NewNULLValue())
+	}
+
+	sg := o.sqlGen
+	sqlStr, bindArgs, err := sg.BindingUpsert(sg, o.s, obj.Type, obj.KV, conflictColumns)
+	if err != nil {
+		return 0, err
+	}
+	if sg.Tracing {
+		fmt.Println("Upsert/sqlStr=", sqlStr, "bindArgs=", bindArgs)
+	}
+	rowsAff, err := o.execRowsAffected(ctx, tx, sqlStr, bindArgs)
+	if err != nil {
+		return 0, errors.Wrap(err, "Upsert")
+	}
+
+	if !objTable.CallerSuppliesPK && obj.Get(objTable.Primary) == nil {
+		queryVals := make(map[string]interface{}, len(conflictColumns))
+		for _, c := range conflictColumns {
+			queryVals[c] = obj.Get(c)
+		}
+		found, err := o.retrieveManyCore(ctx, tx, obj.Type, query.FromKV(queryVals), &query.Options{Limit: 1, Deleted: query.WithDeleted})
+		if err != nil {
+			return 0, errors.Wrap(err, "Upsert")
+		}
+		if len(found) == 0 {
+			return 0, errors.New("Upsert: unable to retrieve upserted row from table " + obj.Type)
+		}
+		obj.Set(objTable.Primary, found[0].Get(objTable.Primary))
+	}
+
+	obj.MarkDirty(false)
+	obj.ResetChangedColumns()
+	return rowsAff, nil
+}
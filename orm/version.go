@@ -0,0 +1,100 @@
+package orm
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/rbastic/dyndao/object"
+	"github.com/rbastic/dyndao/schema"
+)
+
+// initVersion gives a new object the first version for tables that have a
+// VersionColumn, unless the caller has supplied one.
+func (o *ORM) initVersion(objTable *schema.Table, obj *object.Object) error {
+	vc := objTable.VersionColumn
+	if vc == "" || obj.Get(vc) != nil {
+		return nil
+	}
+	col := objTable.GetColumn(vc)
+	if col == nil {
+		return errors.New("dyndao: unknown version column " + vc + " for table " + objTable.Name)
+	}
+	if o.sqlGen.IsTimestampType(col.DBType) {
+		obj.Set(vc, time.Now().UTC().Truncate(time.Second))
+	} else {
+		obj.Set(vc, int64(1))
+	}
+	return nil
+}
+
+// advanceVersion moves obj on to its next version ahead of an update, and
+// records the current version as the previous value, which the update must
+// match. If the caller has set the version themselves, that is used as is.
+// The returned func puts things back, for when the update doesn't happen.
+func (o *ORM) advanceVersion(objTable *schema.Table, obj *object.Object) (func(), error) {
+	noop := func() {}
+	vc := objTable.VersionColumn
+	if vc == "" {
+		return noop, nil
+	}
+	if _, ok := obj.ChangedColumns[vc]; ok {
+		return noop, nil
+	}
+	col := objTable.GetColumn(vc)
+	if col == nil {
+		return nil, errors.New("dyndao: unknown version column " + vc + " for table " + objTable.Name)
+	}
+
+	cur := obj.Get(vc)
+	if cur == nil || obj.ValueIsNULL(cur) {
+		return nil, errors.New("dyndao: missing version column " + vc + " for table " + objTable.Name)
+	}
+	next, err := o.nextVersion(col, cur)
+	if err != nil {
+		return nil, err
+	}
+
+	obj.ColumnChanged(vc, cur)
+	obj.SetCore(vc, next)
+	return func() {
+		delete(obj.ChangedColumns, vc)
+		obj.SetCore(vc, cur)
+	}, nil
+}
+
+// nextVersion returns the version that follows cur. Integer versions are
+// incremented. Timestamp versions become the current time, truncated to the
+// second so that they survive databases that store no more than that, and
+// are always moved forward by at least a second.
+func (o *ORM) nextVersion(col *schema.Column, cur interface{}) (interface{}, error) {
+	v, err := cursorValue(cur)
+	if err != nil {
+		return nil, errors.Wrap(err, "dyndao: version column "+col.Name)
+	}
+
+	if o.sqlGen.IsTimestampType(col.DBType) {
+		next := time.Now().UTC().Truncate(time.Second)
+		if t, ok := v.(time.Time); ok && !next.After(t) {
+			next = t.Truncate(time.Second).Add(time.Second)
+		}
+		return next, nil
+	}
+
+	switch n := v.(type) {
+	case int64:
+		return n + 1, nil
+	case int:
+		return int64(n) + 1, nil
+	case float64:
+		return int64(n) + 1, nil
+	case string:
+		i, err := strconv.ParseInt(n, 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "dyndao: version column "+col.Name)
+		}
+		return i + 1, nil
+	}
+	return nil, errors.New("dyndao: unsupported value for version column " + col.Name)
+}
@@ -0,0 +1,53 @@
+package query
+
+// AggFunc is an SQL aggregate function.
+type AggFunc string
+
+// Supported aggregate functions.
+const (
+	AggCount AggFunc = "COUNT"
+	AggSum   AggFunc = "SUM"
+	AggMin   AggFunc = "MIN"
+	AggMax   AggFunc = "MAX"
+	AggAvg   AggFunc = "AVG"
+)
+
+// Aggregate is a single aggregate expression in a SELECT list, such as
+// SUM(Column) AS Alias. An empty Column is only valid for AggCount, where it
+// means COUNT(*). Alias is the key the result is stored under, and must be a
+// plain identifier.
+type Aggregate struct {
+	Func   AggFunc
+	Column string
+	Alias  string
+}
+
+// Count counts the matching rows, as COUNT(*)
+func Count(alias string) Aggregate {
+	return Aggregate{Func: AggCount, Alias: alias}
+}
+
+// CountColumn counts the non-NULL values of col
+func CountColumn(col string, alias string) Aggregate {
+	return Aggregate{Func: AggCount, Column: col, Alias: alias}
+}
+
+// Sum adds up the values of col
+func Sum(col string, alias string) Aggregate {
+	return Aggregate{Func: AggSum, Column: col, Alias: alias}
+}
+
+// Min finds the smallest value of col
+func Min(col string, alias string) Aggregate {
+	return Aggregate{Func: AggMin, Column: col, Alias: alias}
+}
+
+// Max finds the largest value of col
+func Max(col string, alias string) Aggregate {
+	return Aggregate{Func: AggMax, Column: col, Alias: alias}
+}
+
+// Avg averages the values of col
+func Avg(col string, alias string) Aggregate {
+	return Aggregate{Func: AggAvg, Column: col, Alias: alias}
+}
@@ -0,0 +1,66 @@
+package query
+
+// Nulls controls where NULL values sort within an Order. The default leaves
+// it up to the database, which is not consistent from one to the next.
+type Nulls string
+
+// Supported Nulls placements.
+const (
+	NullsDefault Nulls = ""
+	NullsFirst   Nulls = "FIRST"
+	NullsLast    Nulls = "LAST"
+)
+
+// Order is a single ORDER BY key. Column may be a real column name or a
+// column alias.
+type Order struct {
+	Column string `json:"Column"`
+	Desc   bool   `json:"Desc"`
+	Nulls  Nulls  `json:"Nulls"`
+}
+
+// Asc orders by col, ascending
+func Asc(col string) Order {
+	return Order{Column: col}
+}
+
+// Desc orders by col, descending
+func Desc(col string) Order {
+	return Order{Column: col, Desc: true}
+}
+
+// NullsFirst returns a copy of o that sorts NULL values before all others
+func (o Order) NullsFirst() Order {
+	o.Nulls = NullsFirst
+	return o
+}
+
+// NullsLast returns a copy of o that sorts NULL values after all others
+func (o Order) NullsLast() Order {
+	o.Nulls = NullsLast
+	return o
+}
+
+// Deleted selects which soft deleted rows (see
+// schema.Table.SoftDeleteColumn) a retrieval includes. It has no effect on
+// tables without soft deletion.
+type Deleted int
+
+// Supported Deleted selections. ExcludeDeleted is the default.
+const (
+	ExcludeDeleted Deleted = iota
+	WithDeleted
+	OnlyDeleted
+)
+
+// Options controls the shape of a retrieval: it's ordering, and how many rows
+// to skip (Offset) and return (Limit). A zero Limit means no limit. An empty
+// OrderBy falls back to the table's schema.Table.DefaultOrder, if any.
+// Deleted controls whether soft deleted rows are returned. A nil *Options is
+// equivalent to the zero value.
+type Options struct {
+	OrderBy []Order
+	Limit   int
+	Offset  int
+	Deleted Deleted
+}
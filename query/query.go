@@ -0,0 +1,209 @@
+// Package query is a small, composable WHERE clause expression tree. It lets
+// callers describe filters beyond simple equality (comparisons, IN, LIKE,
+// NULL checks, BETWEEN, boolean combinations) without writing any
+// database-specific SQL. Rendering is left to the SQL generators, so that
+// each adapter can apply it's own binding parameter syntax.
+package query
+
+import (
+	"sort"
+)
+
+// Op is the operator for a given Query node.
+type Op int
+
+// Supported Query operators.
+const (
+	OpAnd Op = iota
+	OpOr
+	OpNot
+	OpEq
+	OpNe
+	OpLt
+	OpLe
+	OpGt
+	OpGe
+	OpIn
+	OpNotIn
+	OpLike
+	OpIsNull
+	OpIsNotNull
+	OpBetween
+	OpAll
+)
+
+// Query is a single node in a WHERE clause expression tree. Comparison nodes
+// use Column and Values, while OpAnd, OpOr and OpNot use Children.
+//
+// Column may be either a real column name or a column alias, the SQL
+// generator resolves it against the schema.Table when rendering.
+type Query struct {
+	Op       Op
+	Column   string
+	Values   []interface{}
+	Children []*Query
+}
+
+// IsEmpty reports whether a query has no predicates at all. A nil query, or
+// an AND/OR with no non-empty children, is empty. Empty queries render as no
+// WHERE clause.
+func (q *Query) IsEmpty() bool {
+	if q == nil {
+		return true
+	}
+	switch q.Op {
+	case OpAnd, OpOr:
+		for _, c := range q.Children {
+			if !c.IsEmpty() {
+				return false
+			}
+		}
+		return true
+	case OpNot:
+		return len(q.Children) == 0 || q.Children[0].IsEmpty()
+	default:
+		return false
+	}
+}
+
+// All matches every row. Operations that refuse to run without a filter
+// (such as orm.DeleteWhere) accept it as an explicit request to affect the
+// whole table.
+func All() *Query {
+	return &Query{Op: OpAll}
+}
+
+// Columns returns the column names referenced anywhere in the query, in the
+// order they are first encountered.
+func (q *Query) Columns() []string {
+	var cols []string
+	seen := make(map[string]bool)
+	q.walk(func(n *Query) {
+		if n.Column != "" && !seen[n.Column] {
+			seen[n.Column] = true
+			cols = append(cols, n.Column)
+		}
+	})
+	return cols
+}
+
+func (q *Query) walk(fn func(*Query)) {
+	if q == nil {
+		return
+	}
+	fn(q)
+	for _, c := range q.Children {
+		c.walk(fn)
+	}
+}
+
+func compare(op Op, col string, v interface{}) *Query {
+	return &Query{Op: op, Column: col, Values: []interface{}{v}}
+}
+
+// Eq matches rows where col = v
+func Eq(col string, v interface{}) *Query {
+	return compare(OpEq, col, v)
+}
+
+// Ne matches rows where col <> v
+func Ne(col string, v interface{}) *Query {
+	return compare(OpNe, col, v)
+}
+
+// Lt matches rows where col < v
+func Lt(col string, v interface{}) *Query {
+	return compare(OpLt, col, v)
+}
+
+// Le matches rows where col <= v
+func Le(col string, v interface{}) *Query {
+	return compare(OpLe, col, v)
+}
+
+// Gt matches rows where col > v
+func Gt(col string, v interface{}) *Query {
+	return compare(OpGt, col, v)
+}
+
+// Ge matches rows where col >= v
+func Ge(col string, v interface{}) *Query {
+	return compare(OpGe, col, v)
+}
+
+// Like matches rows where col LIKE pattern. The pattern is passed through as
+// a binding parameter, so the usual % and _ wildcards apply.
+func Like(col string, pattern string) *Query {
+	return compare(OpLike, col, pattern)
+}
+
+// In matches rows where col is any of vals. An empty IN list matches
+// nothing.
+func In(col string, vals ...interface{}) *Query {
+	return &Query{Op: OpIn, Column: col, Values: vals}
+}
+
+// NotIn matches rows where col is none of vals. An empty NOT IN list matches
+// everything.
+func NotIn(col string, vals ...interface{}) *Query {
+	return &Query{Op: OpNotIn, Column: col, Values: vals}
+}
+
+// IsNull matches rows where col IS NULL
+func IsNull(col string) *Query {
+	return &Query{Op: OpIsNull, Column: col}
+}
+
+// IsNotNull matches rows where col IS NOT NULL
+func IsNotNull(col string) *Query {
+	return &Query{Op: OpIsNotNull, Column: col}
+}
+
+// Between matches rows where col BETWEEN lo AND hi (inclusive)
+func Between(col string, lo interface{}, hi interface{}) *Query {
+	return &Query{Op: OpBetween, Column: col, Values: []interface{}{lo, hi}}
+}
+
+// And matches rows that satisfy every one of qs. Nil and empty children are
+// ignored.
+func And(qs ...*Query) *Query {
+	return &Query{Op: OpAnd, Children: compact(qs)}
+}
+
+// Or matches rows that satisfy at least one of qs. Nil and empty children are
+// ignored.
+func Or(qs ...*Query) *Query {
+	return &Query{Op: OpOr, Children: compact(qs)}
+}
+
+// Not negates q.
+func Not(q *Query) *Query {
+	return &Query{Op: OpNot, Children: compact([]*Query{q})}
+}
+
+func compact(qs []*Query) []*Query {
+	out := make([]*Query, 0, len(qs))
+	for _, q := range qs {
+		if !q.IsEmpty() {
+			out = append(out, q)
+		}
+	}
+	return out
+}
+
+// FromKV converts the classic dyndao 'queryVals' map into an equality query,
+// with each key/value pair joined using AND. Keys are sorted so that the
+// rendered SQL is stable from one call to the next.
+func FromKV(kv map[string]interface{}) *Query {
+	keys := make([]string, 0, len(kv))
+	for k := range kv {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	qs := make([]*Query, len(keys))
+	for i, k := range keys {
+		qs[i] = Eq(k, kv[k])
+	}
+	return And(qs...)
+}
@@ -0,0 +1,68 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsEmpty(t *testing.T) {
+	var nilQuery *Query
+	if !nilQuery.IsEmpty() {
+		t.Fatal("nil query should be empty")
+	}
+	if !And().IsEmpty() {
+		t.Fatal("empty AND should be empty")
+	}
+	if !And(nil, Or()).IsEmpty() {
+		t.Fatal("AND of empty children should be empty")
+	}
+	if And(Eq("Name", "Joe")).IsEmpty() {
+		t.Fatal("AND with a predicate should not be empty")
+	}
+	if !FromKV(map[string]interface{}{}).IsEmpty() {
+		t.Fatal("FromKV of an empty map should be empty")
+	}
+	if All().IsEmpty() || And(All()).IsEmpty() {
+		t.Fatal("All should not be empty")
+	}
+}
+
+func TestFromKV(t *testing.T) {
+	q := FromKV(map[string]interface{}{
+		"Zip":  "02865",
+		"City": "Nowhere",
+		"Age":  30,
+	})
+	if q.Op != OpAnd || len(q.Children) != 3 {
+		t.Fatalf("expected AND with 3 children, got %v", q)
+	}
+	expected := []string{"Age", "City", "Zip"}
+	if !reflect.DeepEqual(q.Columns(), expected) {
+		t.Fatalf("expected sorted columns %v, got %v", expected, q.Columns())
+	}
+	for _, c := range q.Children {
+		if c.Op != OpEq {
+			t.Fatalf("expected OpEq, got %d", c.Op)
+		}
+	}
+}
+
+func TestCompose(t *testing.T) {
+	q := And(
+		Like("Name", "J%"),
+		nil,
+		Or(IsNull("NullInt"), Between("NullInt", 1, 10)),
+		Not(In("PersonID", 1, 2, 3)),
+	)
+	if len(q.Children) != 3 {
+		t.Fatalf("expected nil child to be dropped, got %d children", len(q.Children))
+	}
+	expected := []string{"Name", "NullInt", "PersonID"}
+	if !reflect.DeepEqual(q.Columns(), expected) {
+		t.Fatalf("expected columns %v, got %v", expected, q.Columns())
+	}
+	in := q.Children[2].Children[0]
+	if in.Op != OpIn || len(in.Values) != 3 {
+		t.Fatalf("unexpected IN node %v", in)
+	}
+}
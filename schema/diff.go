@@ -0,0 +1,235 @@
+package schema
+
+import (
+	"sort"
+	"strings"
+)
+
+// SchemaDiff describes the changes between two schemas, as computed by Diff.
+// Tables are identified by their key in Schema.Tables, so a renamed table
+// appears as one table removed and another added.
+type SchemaDiff struct {
+	Old *Schema
+	New *Schema
+
+	AddedTables   []string
+	RemovedTables []string
+	ChangedTables []*TableDiff
+}
+
+// TableDiff describes the changes to the columns, indexes and FOREIGN KEY
+// constraints of a table present in both schemas. Columns are identified by
+// their key in Table.Columns, indexes by their IndexName, and constraints by
+// their Reference name. An index or constraint that changed otherwise
+// appears as removed and added again.
+type TableDiff struct {
+	Name string
+	Old  *Table
+	New  *Table
+
+	AddedColumns   []*Column
+	RemovedColumns []*Column
+	ChangedColumns []*ColumnDiff
+
+	AddedIndexes   []*Index
+	RemovedIndexes []*Index
+
+	AddedForeignKeys   []*Reference
+	RemovedForeignKeys []*Reference
+}
+
+// ColumnDiff describes how a column present in both versions of a table has
+// changed.
+type ColumnDiff struct {
+	Old *Column
+	New *Column
+
+	// TypeChanged is set when DBType (compared without regard to case) or
+	// IsIdentity differ.
+	TypeChanged   bool
+	NullChanged   bool
+	LengthChanged bool
+	UniqueChanged bool
+}
+
+// Diff compares two schemas, reporting the tables, columns, indexes and
+// FOREIGN KEY constraints that were added to or removed from oldSch to
+// arrive at newSch, and the columns whose type, nullability, length or
+// uniqueness changed. The results are sorted by name
+// so that they are stable from one call to the next.
+func Diff(oldSch *Schema, newSch *Schema) *SchemaDiff {
+	d := &SchemaDiff{Old: oldSch, New: newSch}
+
+	for _, name := range sortedTableNames(newSch) {
+		oldTbl, ok := oldSch.Tables[name]
+		if !ok {
+			d.AddedTables = append(d.AddedTables, name)
+			continue
+		}
+		td := diffTable(name, oldTbl, newSch.Tables[name])
+		td.AddedForeignKeys, td.RemovedForeignKeys = diffReferences(oldSch.References(name), newSch.References(name))
+		if !td.IsEmpty() {
+			d.ChangedTables = append(d.ChangedTables, td)
+		}
+	}
+	for _, name := range sortedTableNames(oldSch) {
+		if _, ok := newSch.Tables[name]; !ok {
+			d.RemovedTables = append(d.RemovedTables, name)
+		}
+	}
+	return d
+}
+
+// IsEmpty reports whether the schemas were found to be the same.
+func (d *SchemaDiff) IsEmpty() bool {
+	return len(d.AddedTables) == 0 && len(d.RemovedTables) == 0 && len(d.ChangedTables) == 0
+}
+
+// IsEmpty reports whether the table was found to be the same.
+func (td *TableDiff) IsEmpty() bool {
+	return len(td.AddedColumns) == 0 && len(td.RemovedColumns) == 0 && len(td.ChangedColumns) == 0 &&
+		len(td.AddedIndexes) == 0 && len(td.RemovedIndexes) == 0 &&
+		len(td.AddedForeignKeys) == 0 && len(td.RemovedForeignKeys) == 0
+}
+
+func diffTable(name string, oldTbl *Table, newTbl *Table) *TableDiff {
+	td := &TableDiff{Name: name, Old: oldTbl, New: newTbl}
+
+	for _, k := range sortedColumnNames(newTbl) {
+		newCol := newTbl.Columns[k]
+		oldCol, ok := oldTbl.Columns[k]
+		if !ok {
+			td.AddedColumns = append(td.AddedColumns, newCol)
+			continue
+		}
+		cd := &ColumnDiff{
+			Old:           oldCol,
+			New:           newCol,
+			TypeChanged:   !strings.EqualFold(oldCol.DBType, newCol.DBType) || oldCol.IsIdentity != newCol.IsIdentity,
+			NullChanged:   oldCol.AllowNull != newCol.AllowNull,
+			LengthChanged: oldCol.Length != newCol.Length,
+			UniqueChanged: oldCol.IsUnique != newCol.IsUnique,
+		}
+		if cd.TypeChanged || cd.NullChanged || cd.LengthChanged || cd.UniqueChanged {
+			td.ChangedColumns = append(td.ChangedColumns, cd)
+		}
+	}
+	for _, k := range sortedColumnNames(oldTbl) {
+		if _, ok := newTbl.Columns[k]; !ok {
+			td.RemovedColumns = append(td.RemovedColumns, oldTbl.Columns[k])
+		}
+	}
+
+	oldIdx := indexesByName(name, oldTbl)
+	newIdx := indexesByName(name, newTbl)
+	for _, k := range sortedIndexNames(newIdx) {
+		idx := newIdx[k]
+		if prev, ok := oldIdx[k]; !ok || !sameIndex(prev, idx) {
+			td.AddedIndexes = append(td.AddedIndexes, idx)
+		}
+	}
+	for _, k := range sortedIndexNames(oldIdx) {
+		idx := oldIdx[k]
+		if next, ok := newIdx[k]; !ok || !sameIndex(idx, next) {
+			td.RemovedIndexes = append(td.RemovedIndexes, idx)
+		}
+	}
+	return td
+}
+
+// indexesByName returns the indexes of t by their IndexName, in lower case,
+// since databases don't agree on the case of the names they report.
+func indexesByName(name string, t *Table) map[string]*Index {
+	tableName := GetTableName(t.Name, name)
+	m := make(map[string]*Index, len(t.Indexes))
+	for _, idx := range t.Indexes {
+		m[strings.ToLower(idx.IndexName(tableName))] = idx
+	}
+	return m
+}
+
+func sortedIndexNames(m map[string]*Index) []string {
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sameIndex(a *Index, b *Index) bool {
+	return a.Unique == b.Unique && a.Where == b.Where && sameColumns(a.Columns, b.Columns)
+}
+
+func sortedTableNames(s *Schema) []string {
+	names := make([]string, 0, len(s.Tables))
+	for k := range s.Tables {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedColumnNames(t *Table) []string {
+	names := make([]string, 0, len(t.Columns))
+	for k := range t.Columns {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// diffReferences returns the constraints of newRefs that aren't among
+// oldRefs, and those of oldRefs that aren't among newRefs, matching them by
+// name without regard to case.
+func diffReferences(oldRefs []*Reference, newRefs []*Reference) (added []*Reference, removed []*Reference) {
+	oldByName := make(map[string]*Reference, len(oldRefs))
+	for _, ref := range oldRefs {
+		oldByName[strings.ToLower(ref.Name)] = ref
+	}
+	newByName := make(map[string]*Reference, len(newRefs))
+	for _, ref := range newRefs {
+		newByName[strings.ToLower(ref.Name)] = ref
+	}
+	for _, ref := range newRefs {
+		if prev, ok := oldByName[strings.ToLower(ref.Name)]; !ok || !sameReference(prev, ref) {
+			added = append(added, ref)
+		}
+	}
+	for _, ref := range oldRefs {
+		if next, ok := newByName[strings.ToLower(ref.Name)]; !ok || !sameReference(ref, next) {
+			removed = append(removed, ref)
+		}
+	}
+	return added, removed
+}
+
+func sameReference(a *Reference, b *Reference) bool {
+	return strings.EqualFold(a.RefTable, b.RefTable) &&
+		sameColumns(a.Columns, b.Columns) && sameColumns(a.RefColumns, b.RefColumns) &&
+		sameFKAction(a.OnDelete, b.OnDelete) && sameFKAction(a.OnUpdate, b.OnUpdate)
+}
+
+// sameFKAction compares referential actions, taking FKDefault to be NO
+// ACTION, as it is for the databases supported.
+func sameFKAction(a FKAction, b FKAction) bool {
+	if a == FKDefault {
+		a = FKNoAction
+	}
+	if b == FKDefault {
+		b = FKNoAction
+	}
+	return a == b
+}
+
+func sameColumns(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
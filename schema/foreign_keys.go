@@ -0,0 +1,95 @@
+package schema
+
+import (
+	"errors"
+	"strings"
+)
+
+// Reference is a FOREIGN KEY constraint on a table, as declared by a
+// ChildTable relation with its ForeignKey set: Columns of Table must match
+// RefColumns of a row of RefTable. Tables are identified by their key in
+// Schema.Tables.
+type Reference struct {
+	Name       string
+	Table      string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+	OnDelete   FKAction
+	OnUpdate   FKAction
+}
+
+// References returns the FOREIGN KEY constraints on the named table, from
+// the relations of its parent tables, sorted by parent table.
+func (s *Schema) References(table string) []*Reference {
+	var refs []*Reference
+	for _, parentName := range sortedTableNames(s) {
+		parent := s.Tables[parentName]
+		rel, ok := parent.Children[table]
+		if !ok || rel.ForeignKey == nil {
+			continue
+		}
+		child := s.Tables[table]
+		if child == nil {
+			continue
+		}
+		local, foreign := rel.Keys(parent)
+
+		tableName := GetTableName(child.Name, table)
+		name := rel.ForeignKey.Name
+		if name == "" {
+			name = tableName + "_" + GetTableName(parent.Name, parentName) + "_fkey"
+		}
+		refs = append(refs, &Reference{
+			Name:       name,
+			Table:      table,
+			Columns:    foreign,
+			RefTable:   parentName,
+			RefColumns: local,
+			OnDelete:   rel.ForeignKey.OnDelete,
+			OnUpdate:   rel.ForeignKey.OnUpdate,
+		})
+	}
+	return refs
+}
+
+// CreationOrder returns the keys of the schema's tables in an order they
+// can be created in: each table after the tables its References refer to.
+// Tables are otherwise sorted by name. Dropping them in the reverse order
+// removes each table before those it refers to. A table may refer to
+// itself, but an error is returned if the References of several tables
+// form a cycle.
+func (s *Schema) CreationOrder() ([]string, error) {
+	names := sortedTableNames(s)
+	order := make([]string, 0, len(names))
+	state := make(map[string]int, len(names)) // 1: visiting, 2: done
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case 1:
+			return errors.New("dyndao: CreationOrder: foreign keys form a cycle: " + strings.Join(append(path, name), " -> "))
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		for _, ref := range s.References(name) {
+			if ref.RefTable == name {
+				continue
+			}
+			if err := visit(ref.RefTable, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
@@ -11,7 +11,6 @@
 
 /*
 	TODO: foreign key identification
-	TODO: Indexes
 	TODO: Constraints
 	TODO: interface type for infoschema package (so that we
 	have an interface to implement an oracle-alike version for,
@@ -28,6 +27,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"github.com/rbastic/dyndao/schema"
 )
@@ -55,6 +55,14 @@ func LoadSchema(ctx context.Context, db *sql.DB, dbName string) (*schema.Schema,
 	if err != nil {
 		return nil, err
 	}
+	err = ParseIndexes(ctx, db, dbName, sch)
+	if err != nil {
+		return nil, err
+	}
+	err = ParseForeignKeys(ctx, db, dbName, sch)
+	if err != nil {
+		return nil, err
+	}
 	SetDefaultEssentialColumns(sch)
 	return sch, nil
 }
@@ -85,7 +93,7 @@ func ParseSchema(ctx context.Context, db *sql.DB, dbName string) (*schema.Schema
 
 func getColumnMetaSQL(db string) string {
 	return fmt.Sprintf(`
-SELECT TABLE_NAME, COLUMN_NAME, DATA_TYPE, COLUMN_DEFAULT, IS_NULLABLE, COLUMN_KEY, EXTRA
+SELECT TABLE_NAME, COLUMN_NAME, DATA_TYPE, COLUMN_TYPE, CHARACTER_MAXIMUM_LENGTH, COLUMN_DEFAULT, IS_NULLABLE, COLUMN_KEY, EXTRA
 FROM INFORMATION_SCHEMA.COLUMNS
 WHERE TABLE_SCHEMA='%s'
 ORDER BY TABLE_NAME
@@ -106,31 +114,40 @@ func ParseTables(ctx context.Context, db *sql.DB, dbName string, sch *schema.Sch
 		var tblName string
 		var colName sql.NullString
 		var dataType string
+		var columnType string
+		var charMaxLength sql.NullInt64
 		var columnDefault sql.NullString
 		var isNullable string
 		var columnKey string
 		var extra string
 
-		err := rows.Scan(&tblName, &colName, &dataType, &columnDefault, &isNullable, &columnKey, &extra)
+		err := rows.Scan(&tblName, &colName, &dataType, &columnType, &charMaxLength, &columnDefault, &isNullable, &columnKey, &extra)
 		if err != nil {
 			return err
 		}
 
 		// Mutates the schema.Table for the given tblName and colName
-		setTableCol(sch, tblName, colName, dataType, columnDefault, isNullable, columnKey, extra)
+		setTableCol(sch, tblName, colName, dataType, columnType, charMaxLength, columnDefault, isNullable, columnKey, extra)
 	}
 
 	err = rows.Err()
 	return err
 }
 
-func setTableCol(sch *schema.Schema, tblName string, colName sql.NullString, dataType string, colDefault sql.NullString, isNullable string, columnKey string, extra string) {
+// setTableCol adds the column colName to the schema.Table for tblName. Its
+// Length is only set for the types declared with one, such as VARCHAR(n):
+// MySQL reports a CHARACTER_MAXIMUM_LENGTH for TEXT and BLOB types too, but
+// their COLUMN_TYPE has no length.
+func setTableCol(sch *schema.Schema, tblName string, colName sql.NullString, dataType string, columnType string, charMaxLength sql.NullInt64, colDefault sql.NullString, isNullable string, columnKey string, extra string) {
 	tbl := sch.Tables[tblName]
 	tbl.Name = tblName
 
 	df := schema.DefaultColumn()
 	df.Name = colName.String
 	df.DBType = dataType
+	if charMaxLength.Valid && strings.Contains(columnType, "(") {
+		df.Length = int(charMaxLength.Int64)
+	}
 	df.DefaultValue = colDefault.String
 
 	isNullBool := false
@@ -152,6 +169,192 @@ func setTableCol(sch *schema.Schema, tblName string, colName sql.NullString, dat
 	tbl.Columns[colName.String] = df
 }
 
+func getIndexMetaSQL(db string) string {
+	return fmt.Sprintf(`
+SELECT TABLE_NAME, INDEX_NAME, NON_UNIQUE, COLUMN_NAME
+FROM INFORMATION_SCHEMA.STATISTICS
+WHERE TABLE_SCHEMA='%s' AND INDEX_NAME <> 'PRIMARY'
+ORDER BY TABLE_NAME, INDEX_NAME, SEQ_IN_INDEX
+	`, db)
+}
+
+// ParseIndexes loads the indexes of the tables in a given schema, other
+// than their primary keys, into the relevant tables. A unique index on a
+// single column is recorded by setting the column's IsUnique, since that is
+// how unique columns are created. The STATISTICS view is a MySQL (and
+// MariaDB) extension to the information schema.
+func ParseIndexes(ctx context.Context, db *sql.DB, dbName string, sch *schema.Schema) error {
+	rows, err := db.QueryContext(ctx, getIndexMetaSQL(dbName))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = rows.Close()
+	}()
+
+	for rows.Next() {
+		var tblName string
+		var indexName string
+		var nonUnique int64
+		var colName string
+
+		err := rows.Scan(&tblName, &indexName, &nonUnique, &colName)
+		if err != nil {
+			return err
+		}
+
+		// Mutates the schema.Table for the given tblName
+		setTableIndexCol(sch, tblName, indexName, nonUnique == 0, colName)
+	}
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+
+	setUniqueColumns(sch)
+	return nil
+}
+
+// setTableIndexCol adds colName to the index of the given name, which is
+// created if this is its first column.
+func setTableIndexCol(sch *schema.Schema, tblName string, indexName string, unique bool, colName string) {
+	tbl, ok := sch.Tables[tblName]
+	if !ok {
+		return
+	}
+	for _, idx := range tbl.Indexes {
+		if idx.Name == indexName {
+			idx.Columns = append(idx.Columns, colName)
+			return
+		}
+	}
+	tbl.Indexes = append(tbl.Indexes, &schema.Index{Name: indexName, Columns: []string{colName}, Unique: unique})
+}
+
+// setUniqueColumns replaces each unique index on a single column with
+// IsUnique on the column.
+func setUniqueColumns(sch *schema.Schema) {
+	for _, tbl := range sch.Tables {
+		var indexes []*schema.Index
+		for _, idx := range tbl.Indexes {
+			col := tbl.Columns[idx.Columns[0]]
+			if idx.Unique && len(idx.Columns) == 1 && col != nil {
+				col.IsUnique = true
+				continue
+			}
+			indexes = append(indexes, idx)
+		}
+		tbl.Indexes = indexes
+	}
+}
+
+func getForeignKeyMetaSQL(db string) string {
+	return fmt.Sprintf(`
+SELECT k.TABLE_NAME, k.CONSTRAINT_NAME, k.COLUMN_NAME, k.REFERENCED_TABLE_NAME, k.REFERENCED_COLUMN_NAME, r.DELETE_RULE, r.UPDATE_RULE
+FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE k
+JOIN INFORMATION_SCHEMA.REFERENTIAL_CONSTRAINTS r
+ON r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA AND r.TABLE_NAME = k.TABLE_NAME AND r.CONSTRAINT_NAME = k.CONSTRAINT_NAME
+WHERE k.TABLE_SCHEMA='%s' AND k.REFERENCED_TABLE_NAME IS NOT NULL
+ORDER BY k.TABLE_NAME, k.CONSTRAINT_NAME, k.ORDINAL_POSITION
+	`, db)
+}
+
+// ParseForeignKeys loads the FOREIGN KEY constraints of the tables in a
+// given schema, recording each as a ChildTable relation of the table it
+// refers to, with its ForeignKey set. A schema.ChildTable can only describe
+// one relation between two tables, so any others are left out. MySQL
+// creates an index for each constraint, named after it, where there is none
+// it can use; those indexes are left out too, since they come and go with
+// the constraint. The REFERENCED_ columns of KEY_COLUMN_USAGE are a MySQL
+// extension to the information schema.
+func ParseForeignKeys(ctx context.Context, db *sql.DB, dbName string, sch *schema.Schema) error {
+	rows, err := db.QueryContext(ctx, getForeignKeyMetaSQL(dbName))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = rows.Close()
+	}()
+
+	for rows.Next() {
+		var tblName, fkName, colName, refTblName, refColName, deleteRule, updateRule string
+
+		err := rows.Scan(&tblName, &fkName, &colName, &refTblName, &refColName, &deleteRule, &updateRule)
+		if err != nil {
+			return err
+		}
+
+		// Mutates the schema.Table for the given refTblName
+		setTableForeignKeyCol(sch, tblName, fkName, colName, refTblName, refColName, deleteRule, updateRule)
+	}
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+
+	removeForeignKeyIndexes(sch)
+	return nil
+}
+
+// setTableForeignKeyCol adds colName, referring to refColName, to the
+// foreign key of the given name, whose relation is created if this is its
+// first column.
+func setTableForeignKeyCol(sch *schema.Schema, tblName string, fkName string, colName string, refTblName string, refColName string, deleteRule string, updateRule string) {
+	tbl, ok := sch.Tables[tblName]
+	if !ok {
+		return
+	}
+	refTbl, ok := sch.Tables[refTblName]
+	if !ok {
+		return
+	}
+	rel, ok := refTbl.Children[tblName]
+	if ok {
+		if rel.ForeignKey != nil && rel.ForeignKey.Name == fkName {
+			rel.LocalColumns = append(rel.LocalColumns, refColName)
+			rel.ForeignColumns = append(rel.ForeignColumns, colName)
+		}
+		return
+	}
+	rel = schema.DefaultChildTable()
+	rel.ParentTable = refTblName
+	rel.LocalColumns = []string{refColName}
+	rel.ForeignColumns = []string{colName}
+	rel.ForeignKey = &schema.ForeignKey{
+		Name:     fkName,
+		OnDelete: schema.FKAction(deleteRule),
+		OnUpdate: schema.FKAction(updateRule),
+	}
+	refTbl.Children[tblName] = rel
+	tbl.ParentTables = append(tbl.ParentTables, refTblName)
+}
+
+// removeForeignKeyIndexes removes the indexes named after a FOREIGN KEY
+// constraint of their table.
+func removeForeignKeyIndexes(sch *schema.Schema) {
+	fkNames := make(map[string]map[string]bool)
+	for _, tbl := range sch.Tables {
+		for childName, rel := range tbl.Children {
+			if rel.ForeignKey == nil {
+				continue
+			}
+			if fkNames[childName] == nil {
+				fkNames[childName] = make(map[string]bool)
+			}
+			fkNames[childName][rel.ForeignKey.Name] = true
+		}
+	}
+	for name, tbl := range sch.Tables {
+		var indexes []*schema.Index
+		for _, idx := range tbl.Indexes {
+			if !fkNames[name][idx.Name] {
+				indexes = append(indexes, idx)
+			}
+		}
+		tbl.Indexes = indexes
+	}
+}
+
 // SetDefaultEssentialColumns configures the EssentialColumns
 // for each schema.Table to be the entire list of field names.
 func SetDefaultEssentialColumns(sch *schema.Schema) {
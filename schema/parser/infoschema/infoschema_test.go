@@ -2,6 +2,7 @@ package infoschema
 
 import (
 	"context"
+	"database/sql"
 	"os"
 	"testing"
 
@@ -45,3 +46,25 @@ func TestBasicIS(t *testing.T) {
 	err = schema.Validate(sch)
 	fatalIf(t, err)
 }
+
+func TestSetTableColLength(t *testing.T) {
+	sch := schema.DefaultSchema()
+	sch.Tables["t"] = schema.DefaultTable()
+	name := func(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+	length := func(n int64) sql.NullInt64 { return sql.NullInt64{Int64: n, Valid: true} }
+
+	setTableCol(sch, "t", name("Name"), "varchar", "varchar(64)", length(64), sql.NullString{}, "NO", "", "")
+	setTableCol(sch, "t", name("Bio"), "text", "text", length(65535), sql.NullString{}, "YES", "", "")
+	setTableCol(sch, "t", name("ID"), "int", "int(11)", sql.NullInt64{}, sql.NullString{}, "NO", "PRI", "auto_increment")
+
+	cols := sch.Tables["t"].Columns
+	if c := cols["Name"]; c.Length != 64 || c.AllowNull {
+		t.Fatal("expected a NOT NULL column of length 64, got", c.Length, c.AllowNull)
+	}
+	if c := cols["Bio"]; c.Length != 0 || !c.AllowNull {
+		t.Fatal("expected a nullable column without a length, got", c.Length, c.AllowNull)
+	}
+	if c := cols["ID"]; c.Length != 0 || !c.IsIdentity {
+		t.Fatal("expected an identity column without a length, got", c.Length, c.IsIdentity)
+	}
+}
@@ -2,7 +2,6 @@
 //
 /*
 	TODO: foreign key identification
-	TODO: Indexes
 	TODO: Constraints
 	TODO: interface type for infoschema package (so that we
 	have an interface to implement an oracle-alike version for,
@@ -38,6 +37,14 @@ func LoadSchema(ctx context.Context, db *sql.DB, dbName string) (*schema.Schema,
 	if err != nil {
 		return nil, errors.Wrap(err, "ParseTables")
 	}
+	err = ParseIndexes(ctx, db, dbName, sch)
+	if err != nil {
+		return nil, errors.Wrap(err, "ParseIndexes")
+	}
+	err = ParseForeignKeys(ctx, db, dbName, sch)
+	if err != nil {
+		return nil, errors.Wrap(err, "ParseForeignKeys")
+	}
 	SetDefaultEssentialColumns(sch)
 	return sch, nil
 }
@@ -208,6 +215,169 @@ func setTableCol(sch *schema.Schema, tblName string, colName sql.NullString, dat
 	tbl.Columns[colName.String] = df
 }
 
+// getIndexMetaSQL lists the columns of a table's indexes. Function-based and
+// LOB indexes, and those backing primary keys, are left out.
+func getIndexMetaSQL(db string, tblName string) string {
+	return fmt.Sprintf(`
+ select i.INDEX_NAME, i.UNIQUENESS, c.COLUMN_NAME
+ FROM all_indexes i
+ JOIN all_ind_columns c ON c.INDEX_OWNER = i.OWNER AND c.INDEX_NAME = i.INDEX_NAME
+ WHERE i.TABLE_NAME = '%s' AND i.OWNER = '%s' AND i.INDEX_TYPE = 'NORMAL'
+ AND NOT EXISTS (
+   select 1 FROM all_constraints k
+   WHERE k.OWNER = i.OWNER AND k.INDEX_NAME = i.INDEX_NAME AND k.CONSTRAINT_TYPE = 'P'
+ )
+ ORDER BY i.INDEX_NAME, c.COLUMN_POSITION
+`, tblName, strings.ToUpper(db))
+}
+
+// ParseIndexes loads the indexes of each table in a given schema, other
+// than their primary keys, into the table. A unique index on a single
+// column is recorded by setting the column's IsUnique, since that is how
+// unique columns are created.
+func ParseIndexes(ctx context.Context, db *sql.DB, dbName string, sch *schema.Schema) error {
+	for _, tbl := range sch.Tables {
+		metasql := getIndexMetaSQL(dbName, tbl.Name)
+		if os.Getenv("DB_TRACE") != "" {
+			fmt.Printf("dyndao: ParseIndexes getIndexMetaSQL: %s\n", metasql)
+		}
+		rows, err := db.QueryContext(ctx, metasql)
+		if err != nil {
+			return errors.Wrap(err, "QueryContext")
+		}
+
+		for rows.Next() {
+			var indexName, uniqueness, colName string
+
+			err := rows.Scan(&indexName, &uniqueness, &colName)
+			if err != nil {
+				_ = rows.Close()
+				return errors.Wrap(err, "rows.Scan()")
+			}
+
+			// Mutates the schema.Table for the given tblName
+			setTableIndexCol(sch, tbl.Name, indexName, uniqueness == "UNIQUE", colName)
+		}
+
+		err = rows.Err()
+		_ = rows.Close()
+		if err != nil {
+			return errors.Wrap(err, "rows.Err()")
+		}
+	}
+
+	setUniqueColumns(sch)
+	return nil
+}
+
+// setTableIndexCol adds colName to the index of the given name, which is
+// created if this is its first column.
+func setTableIndexCol(sch *schema.Schema, tblName string, indexName string, unique bool, colName string) {
+	tbl := sch.Tables[tblName]
+	for _, idx := range tbl.Indexes {
+		if idx.Name == indexName {
+			idx.Columns = append(idx.Columns, colName)
+			return
+		}
+	}
+	tbl.Indexes = append(tbl.Indexes, &schema.Index{Name: indexName, Columns: []string{colName}, Unique: unique})
+}
+
+// setUniqueColumns replaces each unique index on a single column with
+// IsUnique on the column.
+func setUniqueColumns(sch *schema.Schema) {
+	for _, tbl := range sch.Tables {
+		var indexes []*schema.Index
+		for _, idx := range tbl.Indexes {
+			col := tbl.Columns[idx.Columns[0]]
+			if idx.Unique && len(idx.Columns) == 1 && col != nil {
+				col.IsUnique = true
+				continue
+			}
+			indexes = append(indexes, idx)
+		}
+		tbl.Indexes = indexes
+	}
+}
+
+// getForeignKeyMetaSQL lists the columns of a table's FOREIGN KEY
+// constraints, with the columns they refer to.
+func getForeignKeyMetaSQL(db string, tblName string) string {
+	return fmt.Sprintf(`
+ select c.CONSTRAINT_NAME, cc.COLUMN_NAME, r.TABLE_NAME, rc.COLUMN_NAME, c.DELETE_RULE
+ FROM all_constraints c
+ JOIN all_cons_columns cc ON cc.OWNER = c.OWNER AND cc.CONSTRAINT_NAME = c.CONSTRAINT_NAME
+ JOIN all_constraints r ON r.OWNER = c.R_OWNER AND r.CONSTRAINT_NAME = c.R_CONSTRAINT_NAME
+ JOIN all_cons_columns rc ON rc.OWNER = r.OWNER AND rc.CONSTRAINT_NAME = r.CONSTRAINT_NAME AND rc.POSITION = cc.POSITION
+ WHERE c.TABLE_NAME = '%s' AND c.OWNER = '%s' AND c.CONSTRAINT_TYPE = 'R'
+ ORDER BY c.CONSTRAINT_NAME, cc.POSITION
+`, tblName, strings.ToUpper(db))
+}
+
+// ParseForeignKeys loads the FOREIGN KEY constraints of each table in a
+// given schema, recording each as a ChildTable relation of the table it
+// refers to, with its ForeignKey set. A schema.ChildTable can only describe
+// one relation between two tables, so any others are left out. Oracle has
+// no ON UPDATE actions, so OnUpdate is left as the default.
+func ParseForeignKeys(ctx context.Context, db *sql.DB, dbName string, sch *schema.Schema) error {
+	for _, tbl := range sch.Tables {
+		metasql := getForeignKeyMetaSQL(dbName, tbl.Name)
+		if os.Getenv("DB_TRACE") != "" {
+			fmt.Printf("dyndao: ParseForeignKeys getForeignKeyMetaSQL: %s\n", metasql)
+		}
+		rows, err := db.QueryContext(ctx, metasql)
+		if err != nil {
+			return errors.Wrap(err, "QueryContext")
+		}
+
+		for rows.Next() {
+			var fkName, colName, refTblName, refColName, deleteRule string
+
+			err := rows.Scan(&fkName, &colName, &refTblName, &refColName, &deleteRule)
+			if err != nil {
+				_ = rows.Close()
+				return errors.Wrap(err, "rows.Scan()")
+			}
+
+			// Mutates the schema.Table for the given refTblName
+			setTableForeignKeyCol(sch, tbl.Name, fkName, colName, refTblName, refColName, deleteRule)
+		}
+
+		err = rows.Err()
+		_ = rows.Close()
+		if err != nil {
+			return errors.Wrap(err, "rows.Err()")
+		}
+	}
+	return nil
+}
+
+// setTableForeignKeyCol adds colName, referring to refColName, to the
+// foreign key of the given name, whose relation is created if this is its
+// first column.
+func setTableForeignKeyCol(sch *schema.Schema, tblName string, fkName string, colName string, refTblName string, refColName string, deleteRule string) {
+	tbl := sch.Tables[tblName]
+	refTbl, ok := sch.Tables[refTblName]
+	if !ok {
+		return
+	}
+	rel, ok := refTbl.Children[tblName]
+	if ok {
+		if rel.ForeignKey != nil && rel.ForeignKey.Name == fkName {
+			rel.LocalColumns = append(rel.LocalColumns, refColName)
+			rel.ForeignColumns = append(rel.ForeignColumns, colName)
+		}
+		return
+	}
+	rel = schema.DefaultChildTable()
+	rel.ParentTable = refTblName
+	rel.LocalColumns = []string{refColName}
+	rel.ForeignColumns = []string{colName}
+	rel.ForeignKey = &schema.ForeignKey{Name: fkName, OnDelete: schema.FKAction(deleteRule)}
+	refTbl.Children[tblName] = rel
+	tbl.ParentTables = append(tbl.ParentTables, refTblName)
+}
+
 // SetDefaultEssentialColumns configures the EssentialColumns
 // for each schema.Table to be the entire list of field names.
 func SetDefaultEssentialColumns(sch *schema.Schema) {
@@ -2,6 +2,7 @@ package schema
 
 import (
 	"encoding/json"
+	"strings"
 	//"fmt"
 )
 
@@ -109,6 +110,7 @@ func (t *Table) GetColumn(n string) *Column {
 func DefaultTable() *Table {
 	fieldsMap := make(map[string]*Column)
 	childrenMap := make(map[string]*ChildTable)
+	manyToManyMap := make(map[string]*ManyToMany)
 	emptyAliasesMap := make(map[string]string)
 
 	tbl := &Table{
@@ -117,6 +119,7 @@ func DefaultTable() *Table {
 		Columns:          fieldsMap,
 		EssentialColumns: nil,
 		Children:         childrenMap,
+		ManyToMany:       manyToManyMap,
 		ColumnAliases:    emptyAliasesMap,
 	}
 	return tbl
@@ -148,3 +151,61 @@ func DefaultChildTable() *ChildTable {
 	}
 	return chld
 }
+
+// Keys returns the columns that relate a row of parent to its child rows:
+// a child row belongs to the parent row when each of its foreign columns
+// equals the corresponding local column of the parent. LocalColumns and
+// ForeignColumns are used if set, then LocalColumn and ForeignColumn (with
+// an empty ForeignColumn meaning the same name as LocalColumn). Otherwise,
+// the parent's primary key is assumed to have the same name in the child.
+func (c *ChildTable) Keys(parent *Table) (local []string, foreign []string) {
+	if len(c.LocalColumns) > 0 {
+		foreign = c.ForeignColumns
+		if len(foreign) == 0 {
+			foreign = c.LocalColumns
+		}
+		return c.LocalColumns, foreign
+	}
+	if c.LocalColumn != "" {
+		f := c.ForeignColumn
+		if f == "" {
+			f = c.LocalColumn
+		}
+		return []string{c.LocalColumn}, []string{f}
+	}
+	return []string{parent.Primary}, []string{parent.Primary}
+}
+
+// IndexName returns the name of the index, or if it has none, a name made
+// from the table name and the indexed columns.
+func (idx *Index) IndexName(tableName string) string {
+	if idx.Name != "" {
+		return idx.Name
+	}
+	return tableName + "_" + strings.Join(idx.Columns, "_") + "_idx"
+}
+
+// Keys returns the columns of a many-to-many relation from table to
+// related, with the defaults filled in: a row of table is related to a row
+// of related when there is a join table row whose joinLocal columns equal
+// its local columns, and whose joinForeign columns equal the related row's
+// foreign columns.
+func (m *ManyToMany) Keys(table *Table, related *Table) (local []string, joinLocal []string, joinForeign []string, foreign []string) {
+	local = m.LocalColumns
+	if len(local) == 0 {
+		local = []string{table.Primary}
+	}
+	foreign = m.ForeignColumns
+	if len(foreign) == 0 {
+		foreign = []string{related.Primary}
+	}
+	joinLocal = m.JoinLocalColumns
+	if len(joinLocal) == 0 {
+		joinLocal = local
+	}
+	joinForeign = m.JoinForeignColumns
+	if len(joinForeign) == 0 {
+		joinForeign = foreign
+	}
+	return local, joinLocal, joinForeign, foreign
+}
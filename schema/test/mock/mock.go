@@ -8,6 +8,7 @@ import (
 
 const PeopleObjectType string = "people"
 const AddressesObjectType string = "addresses"
+const FriendsObjectType string = "friends"
 
 // Basic test mock
 func fieldName() *schema.Column {
@@ -143,6 +144,20 @@ func addressTable() *schema.Table {
 	return tbl
 }
 
+// Join table relating people to the people they are friends with
+func friendsTable() *schema.Table {
+	tbl := schema.DefaultTable()
+	tbl.Name = "friends"
+	tbl.Primary = "FriendshipID"
+
+	tbl.Columns["FriendshipID"] = primaryColumn("FriendshipID")
+	tbl.Columns["PersonID"] = fkColumn("PersonID")
+	tbl.Columns["FriendID"] = fkColumn("FriendID")
+
+	tbl.EssentialColumns = []string{"FriendshipID", "PersonID", "FriendID"}
+	return tbl
+}
+
 // BasicSchema is the basic mock for one table
 func BasicSchema() *schema.Schema {
 	sch := schema.DefaultSchema()
@@ -152,7 +167,8 @@ func BasicSchema() *schema.Schema {
 	return sch
 }
 
-// NestedSchema is the basic mock for two tables (one table that references a foreign table)
+// NestedSchema is the basic mock for two tables (one table that references a foreign table),
+// plus a join table relating people to people
 func NestedSchema() *schema.Schema {
 	sch := BasicSchema()
 	personTable := sch.Tables["people"]
@@ -162,6 +178,13 @@ func NestedSchema() *schema.Schema {
 
 	addrTable := addressTable()
 	sch.Tables["addresses"] = addrTable
+
+	personTable.ManyToMany["friends"] = &schema.ManyToMany{
+		JoinTable:          "friends",
+		Table:              "people",
+		JoinForeignColumns: []string{"FriendID"},
+	}
+	sch.Tables["friends"] = friendsTable()
 	return sch
 }
 
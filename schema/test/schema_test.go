@@ -2,8 +2,10 @@ package test
 
 import (
 	"fmt"
+	"github.com/rbastic/dyndao/query"
 	"github.com/rbastic/dyndao/schema"
 	"github.com/rbastic/dyndao/schema/test/mock"
+	"reflect"
 	"testing"
 )
 
@@ -30,3 +32,303 @@ func TestDefaultChildTable(t *testing.T) {
 func TestSchemaBasic(t *testing.T) {
 	_ = mock.BasicSchema()
 }
+
+func TestValidateDefaultOrder(t *testing.T) {
+	sch := mock.BasicSchema()
+	tbl := sch.GetTable(mock.PeopleObjectType)
+
+	tbl.DefaultOrder = []query.Order{query.Desc("PersonID"), query.Asc("Name").NullsLast()}
+	if err := schema.Validate(sch); err != nil {
+		t.Fatal(err)
+	}
+
+	tbl.DefaultOrder = []query.Order{query.Asc("NoSuchColumn")}
+	if err := schema.Validate(sch); err == nil {
+		t.Fatal("expected an error for an unknown DefaultOrder column")
+	}
+}
+
+func TestValidateVersionColumn(t *testing.T) {
+	sch := mock.BasicSchema()
+	tbl := sch.GetTable(mock.PeopleObjectType)
+
+	tbl.VersionColumn = "NullInt"
+	if err := schema.Validate(sch); err != nil {
+		t.Fatal(err)
+	}
+
+	tbl.VersionColumn = "NoSuchColumn"
+	if err := schema.Validate(sch); err == nil {
+		t.Fatal("expected an error for an unknown VersionColumn")
+	}
+}
+
+func TestValidateSoftDeleteColumn(t *testing.T) {
+	sch := mock.BasicSchema()
+	tbl := sch.GetTable(mock.PeopleObjectType)
+
+	tbl.SoftDeleteColumn = "NullTimestamp"
+	if err := schema.Validate(sch); err != nil {
+		t.Fatal(err)
+	}
+
+	tbl.SoftDeleteColumn = "Name"
+	if err := schema.Validate(sch); err == nil {
+		t.Fatal("expected an error for a SoftDeleteColumn that doesn't allow NULL")
+	}
+
+	tbl.SoftDeleteColumn = "NoSuchColumn"
+	if err := schema.Validate(sch); err == nil {
+		t.Fatal("expected an error for an unknown SoftDeleteColumn")
+	}
+}
+
+func TestValidateIndexes(t *testing.T) {
+	sch := mock.BasicSchema()
+	tbl := sch.GetTable(mock.PeopleObjectType)
+
+	idx := &schema.Index{Columns: []string{"Name", "NullInt"}, Unique: true}
+	tbl.Indexes = []*schema.Index{idx}
+	if err := schema.Validate(sch); err != nil {
+		t.Fatal(err)
+	}
+	if name := idx.IndexName(tbl.Name); name != "people_Name_NullInt_idx" {
+		t.Fatal("unexpected default index name", name)
+	}
+
+	idx.Columns = []string{"NoSuchColumn"}
+	if err := schema.Validate(sch); err == nil {
+		t.Fatal("expected an error for an index on an unknown column")
+	}
+
+	idx.Columns = nil
+	if err := schema.Validate(sch); err == nil {
+		t.Fatal("expected an error for an index without columns")
+	}
+}
+
+func TestChildTableKeys(t *testing.T) {
+	sch := mock.NestedSchema()
+	people := sch.GetTable(mock.PeopleObjectType)
+	child := people.Children[mock.AddressesObjectType]
+
+	local, foreign := child.Keys(people)
+	if len(local) != 1 || local[0] != "PersonID" || len(foreign) != 1 || foreign[0] != "PersonID" {
+		t.Fatalf("expected the primary key by default, got %v %v", local, foreign)
+	}
+	if err := schema.Validate(sch); err != nil {
+		t.Fatal(err)
+	}
+
+	child.LocalColumn = "PersonID"
+	child.ForeignColumn = "NoSuchColumn"
+	if err := schema.Validate(sch); err == nil {
+		t.Fatal("expected an error for an unknown foreign column")
+	}
+
+	child.LocalColumns = []string{"PersonID", "Name"}
+	child.ForeignColumns = []string{"PersonID"}
+	if err := schema.Validate(sch); err == nil {
+		t.Fatal("expected an error for mismatched key columns")
+	}
+}
+
+func TestCreationOrder(t *testing.T) {
+	sch := mock.NestedSchema()
+	people := sch.GetTable(mock.PeopleObjectType)
+	people.Children[mock.AddressesObjectType].ForeignKey = &schema.ForeignKey{OnDelete: schema.FKCascade}
+	if err := schema.Validate(sch); err != nil {
+		t.Fatal(err)
+	}
+
+	refs := sch.References(mock.AddressesObjectType)
+	if len(refs) != 1 || refs[0].RefTable != mock.PeopleObjectType || refs[0].Name != "addresses_people_fkey" {
+		t.Fatal("expected addresses to reference people", refs)
+	}
+
+	order, err := sch.CreationOrder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos := map[string]int{}
+	for i, name := range order {
+		pos[name] = i
+	}
+	if len(order) != len(sch.Tables) || pos[mock.PeopleObjectType] > pos[mock.AddressesObjectType] {
+		t.Fatal("expected people to be created before addresses", order)
+	}
+
+	// A table may refer to itself, but not to a table that refers to it
+	people.Children[mock.PeopleObjectType] = &schema.ChildTable{LocalColumn: "PersonID", ForeignColumn: "NullInt", ForeignKey: &schema.ForeignKey{}}
+	if _, err := sch.CreationOrder(); err != nil {
+		t.Fatal(err)
+	}
+	addresses := sch.GetTable(mock.AddressesObjectType)
+	addresses.Children[mock.PeopleObjectType] = &schema.ChildTable{LocalColumn: "AddressID", ForeignColumn: "NullInt", ForeignKey: &schema.ForeignKey{}}
+	if _, err := sch.CreationOrder(); err == nil {
+		t.Fatal("expected an error for a cycle of foreign keys")
+	}
+
+	people.Children[mock.AddressesObjectType].ForeignKey.OnUpdate = "EXPLODE"
+	if err := schema.Validate(sch); err == nil {
+		t.Fatal("expected an error for an unknown foreign key action")
+	}
+}
+
+func TestValidateManyToMany(t *testing.T) {
+	sch := mock.NestedSchema()
+	if err := schema.Validate(sch); err != nil {
+		t.Fatal(err)
+	}
+
+	rel := sch.GetTable(mock.PeopleObjectType).ManyToMany[mock.FriendsObjectType]
+	rel.JoinForeignColumns = []string{"NoSuchColumn"}
+	if err := schema.Validate(sch); err == nil {
+		t.Fatal("expected an error for an unknown join column")
+	}
+
+	rel.JoinForeignColumns = nil
+	rel.JoinTable = "nosuchtable"
+	if err := schema.Validate(sch); err == nil {
+		t.Fatal("expected an error for an unknown join table")
+	}
+}
+
+func TestManyToManyJSON(t *testing.T) {
+	sch, err := schema.FromJSON(`{"Tables": {"people": {"Name": "people", "Primary": "PersonID",
+		"ManyToMany": {"friends": {"JoinTable": "friends", "Table": "people", "JoinForeignColumns": ["FriendID"]}}}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	people := sch.GetTable("people")
+	local, joinLocal, joinForeign, foreign := people.ManyToMany["friends"].Keys(people, people)
+	if local[0] != "PersonID" || joinLocal[0] != "PersonID" || joinForeign[0] != "FriendID" || foreign[0] != "PersonID" {
+		t.Fatal("unexpected many-to-many keys", local, joinLocal, joinForeign, foreign)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := mock.NestedSchema()
+	if d := schema.Diff(old, mock.NestedSchema()); !d.IsEmpty() {
+		t.Fatal("expected no differences between identical schemas", d)
+	}
+
+	new := mock.NestedSchema()
+	delete(new.Tables, mock.FriendsObjectType)
+	extra := schema.DefaultTable()
+	extra.Name = "extra"
+	new.Tables["extra"] = extra
+
+	people := new.GetTable(mock.PeopleObjectType)
+	delete(people.Columns, "NullBlob")
+	people.Columns["Email"] = &schema.Column{Name: "Email", DBType: "varchar", Length: 100, AllowNull: true}
+	people.Columns["Name"].AllowNull = true
+	people.Columns["NullVarchar"].Length = 60
+	people.Columns["NullText"].IsUnique = true
+	people.Columns["NullInt"].DBType = "INTEGER" // case doesn't matter
+
+	d := schema.Diff(old, new)
+	if len(d.AddedTables) != 1 || d.AddedTables[0] != "extra" {
+		t.Fatal("expected the extra table to be added", d.AddedTables)
+	}
+	if len(d.RemovedTables) != 1 || d.RemovedTables[0] != mock.FriendsObjectType {
+		t.Fatal("expected the friends table to be removed", d.RemovedTables)
+	}
+	if len(d.ChangedTables) != 1 || d.ChangedTables[0].Name != mock.PeopleObjectType {
+		t.Fatal("expected only the people table to change", d.ChangedTables)
+	}
+
+	td := d.ChangedTables[0]
+	if len(td.AddedColumns) != 1 || td.AddedColumns[0].Name != "Email" {
+		t.Fatal("expected Email to be added", td.AddedColumns)
+	}
+	if len(td.RemovedColumns) != 1 || td.RemovedColumns[0].Name != "NullBlob" {
+		t.Fatal("expected NullBlob to be removed", td.RemovedColumns)
+	}
+	changes := map[string]*schema.ColumnDiff{}
+	for _, cd := range td.ChangedColumns {
+		changes[cd.New.Name] = cd
+	}
+	if len(changes) != 3 {
+		t.Fatal("expected 3 changed columns", changes)
+	}
+	if cd := changes["Name"]; cd == nil || !cd.NullChanged || cd.TypeChanged || cd.LengthChanged || cd.UniqueChanged {
+		t.Fatal("expected Name to change nullability only", cd)
+	}
+	if cd := changes["NullVarchar"]; cd == nil || !cd.LengthChanged || cd.NullChanged {
+		t.Fatal("expected NullVarchar to change length only", cd)
+	}
+	if cd := changes["NullText"]; cd == nil || !cd.UniqueChanged || cd.TypeChanged {
+		t.Fatal("expected NullText to change uniqueness only", cd)
+	}
+}
+
+func TestDiffForeignKeys(t *testing.T) {
+	old := mock.NestedSchema()
+	old.GetTable(mock.PeopleObjectType).Children[mock.AddressesObjectType].ForeignKey = &schema.ForeignKey{}
+	same := mock.NestedSchema()
+	same.GetTable(mock.PeopleObjectType).Children[mock.AddressesObjectType].ForeignKey = &schema.ForeignKey{OnDelete: schema.FKNoAction}
+	if d := schema.Diff(old, same); !d.IsEmpty() {
+		t.Fatal("expected the default action to be taken as NO ACTION", d.ChangedTables)
+	}
+
+	new := mock.NestedSchema()
+	new.GetTable(mock.PeopleObjectType).Children[mock.AddressesObjectType].ForeignKey = &schema.ForeignKey{OnDelete: schema.FKCascade}
+	d := schema.Diff(old, new)
+	if len(d.ChangedTables) != 1 || d.ChangedTables[0].Name != mock.AddressesObjectType {
+		t.Fatal("expected only the addresses table to change", d.ChangedTables)
+	}
+	td := d.ChangedTables[0]
+	if len(td.AddedForeignKeys) != 1 || td.AddedForeignKeys[0].OnDelete != schema.FKCascade {
+		t.Fatal("expected the changed foreign key to be added", td.AddedForeignKeys)
+	}
+	if len(td.RemovedForeignKeys) != 1 || td.RemovedForeignKeys[0].Name != td.AddedForeignKeys[0].Name {
+		t.Fatal("expected the changed foreign key to be removed", td.RemovedForeignKeys)
+	}
+
+	d = schema.Diff(old, mock.NestedSchema())
+	if len(d.ChangedTables) != 1 || len(d.ChangedTables[0].RemovedForeignKeys) != 1 || len(d.ChangedTables[0].AddedForeignKeys) != 0 {
+		t.Fatal("expected the foreign key to be removed", d.ChangedTables)
+	}
+}
+
+func TestDiffIndexes(t *testing.T) {
+	old := mock.NestedSchema()
+	old.GetTable(mock.PeopleObjectType).Indexes = []*schema.Index{
+		{Columns: []string{"Name"}},
+		{Name: "people_kept", Columns: []string{"NullInt"}},
+		{Name: "people_changed", Columns: []string{"NullVarchar"}},
+	}
+	if d := schema.Diff(old, old); !d.IsEmpty() {
+		t.Fatal("expected no differences between identical schemas", d)
+	}
+
+	new := mock.NestedSchema()
+	new.GetTable(mock.PeopleObjectType).Indexes = []*schema.Index{
+		{Name: "PEOPLE_KEPT", Columns: []string{"nullint"}}, // case doesn't matter
+		{Name: "people_changed", Columns: []string{"NullVarchar"}, Unique: true},
+		{Columns: []string{"NullText"}},
+	}
+
+	d := schema.Diff(old, new)
+	if len(d.ChangedTables) != 1 {
+		t.Fatal("expected only the people table to change", d.ChangedTables)
+	}
+	td := d.ChangedTables[0]
+	if len(td.ChangedColumns) != 0 || len(td.AddedColumns) != 0 || len(td.RemovedColumns) != 0 {
+		t.Fatal("expected no column changes", td)
+	}
+	names := func(indexes []*schema.Index) []string {
+		var n []string
+		for _, idx := range indexes {
+			n = append(n, idx.IndexName("people"))
+		}
+		return n
+	}
+	if got := names(td.AddedIndexes); !reflect.DeepEqual(got, []string{"people_changed", "people_NullText_idx"}) {
+		t.Fatal("expected people_changed and people_NullText_idx to be added, got", got)
+	}
+	if got := names(td.RemovedIndexes); !reflect.DeepEqual(got, []string{"people_changed", "people_Name_idx"}) {
+		t.Fatal("expected people_changed and people_Name_idx to be removed, got", got)
+	}
+}
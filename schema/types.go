@@ -1,5 +1,9 @@
 package schema
 
+import (
+	"github.com/rbastic/dyndao/query"
+)
+
 // Schema is the metadata container for a schema definition
 type Schema struct {
 	Name   string
@@ -34,6 +38,31 @@ type Table struct {
 	ParentTables []string               `json:"ParentTables"`
 	Children     map[string]*ChildTable `json:"Children"`
 
+	// ManyToMany holds the table's many-to-many relations, by name. Each
+	// relates rows of this table to rows of another through a join table.
+	ManyToMany map[string]*ManyToMany `json:"ManyToMany"`
+
+	// DefaultOrder is used to sort retrievals that don't specify an
+	// ordering of their own, so that results (and children fleshened by
+	// the ORM) come back in a consistent order on every database.
+	DefaultOrder []query.Order `json:"DefaultOrder"`
+
+	// VersionColumn enables optimistic locking. It names an integer or
+	// timestamp column that the ORM advances on every update, and checks
+	// when updating or deleting, so that changes made since an object was
+	// retrieved aren't silently overwritten.
+	VersionColumn string `json:"VersionColumn"`
+
+	// SoftDeleteColumn enables soft deletion. It names a nullable
+	// timestamp column that the ORM sets when deleting an object, rather
+	// than removing its row. Rows where it is set are left out of
+	// retrievals, unless asked for with query.Options.Deleted.
+	SoftDeleteColumn string `json:"SoftDeleteColumn"`
+
+	// Indexes are created along with the table, beyond those the database
+	// creates for its primary key and unique columns.
+	Indexes []*Index `json:"Indexes"`
+
 	// YAGNI?
 	// TODO: ChildrenInsertionOrder?
 	// TODO: DeletionOrder?
@@ -67,6 +96,12 @@ type Column struct {
 	// this helps with situations where you might otherwise end up transmitting integers as floats
 
 	MapToString bool `json:"MapToString"`
+
+	// AutoCreateTime columns are set to the current time by the ORM when
+	// inserting, unless a value has been supplied. AutoUpdateTime columns
+	// are too, and are also set on every update.
+	AutoCreateTime bool `json:"AutoCreateTime"`
+	AutoUpdateTime bool `json:"AutoUpdateTime"`
 }
 
 // ChildTable represents a relationship between a parent table
@@ -82,4 +117,63 @@ type ChildTable struct {
 
 	LocalColumns   []string `json:"LocalColumns"`
 	ForeignColumns []string `json:"ForeignColumns"`
+
+	// ForeignKey, if set, has the child table created with a FOREIGN KEY
+	// constraint from its foreign columns to the parent's local columns,
+	// so that the database enforces the relationship. The local columns
+	// must then be the parent's primary key, or unique.
+	ForeignKey *ForeignKey `json:"ForeignKey"`
+}
+
+// FKAction is the referential action a database takes on a child row when
+// the parent row it references is deleted or its key is updated.
+type FKAction string
+
+// Supported FKActions. FKDefault leaves it up to the database, which is
+// NO ACTION for all of those supported. Not every database supports every
+// action.
+const (
+	FKDefault    FKAction = ""
+	FKNoAction   FKAction = "NO ACTION"
+	FKRestrict   FKAction = "RESTRICT"
+	FKCascade    FKAction = "CASCADE"
+	FKSetNull    FKAction = "SET NULL"
+	FKSetDefault FKAction = "SET DEFAULT"
+)
+
+// ForeignKey describes the FOREIGN KEY constraint of a ChildTable relation.
+// Name defaults to one made from the child and parent table names.
+type ForeignKey struct {
+	Name     string   `json:"Name"`
+	OnDelete FKAction `json:"OnDelete"`
+	OnUpdate FKAction `json:"OnUpdate"`
+}
+
+// ManyToMany represents a relationship between rows of a table and rows of
+// a related table, with a row in a join table for each related pair. The
+// join table's JoinLocalColumns hold the values of the table's LocalColumns
+// (by default, its primary key), and its JoinForeignColumns hold the values
+// of the related table's ForeignColumns (by default, its primary key).
+// JoinLocalColumns and JoinForeignColumns default to the same names as the
+// columns they refer to.
+type ManyToMany struct {
+	JoinTable string `json:"JoinTable"`
+	Table     string `json:"Table"`
+
+	LocalColumns       []string `json:"LocalColumns"`
+	JoinLocalColumns   []string `json:"JoinLocalColumns"`
+	JoinForeignColumns []string `json:"JoinForeignColumns"`
+	ForeignColumns     []string `json:"ForeignColumns"`
+}
+
+// Index represents an index on one or more columns of a table. Where, if
+// set, is a raw SQL condition that makes it a partial index, covering only
+// the rows for which the condition holds. Not every database supports
+// partial indexes. The schema parsers never set Where, as neither MySQL nor
+// Oracle has partial indexes, so it only comes from hand-written schemas.
+type Index struct {
+	Name    string   `json:"Name"`
+	Columns []string `json:"Columns"`
+	Unique  bool     `json:"Unique"`
+	Where   string   `json:"Where"`
 }
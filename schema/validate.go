@@ -13,8 +13,12 @@ func errorHelper(tbl *Table, msg string) error {
 }
 
 // Validate is a basic schema validator. It ensures that each table inside the
-// schema has a name, some Columns, and EssentialColumns is set. Any other database
-// requirements are not yet considered.
+// schema has a name, some Columns, EssentialColumns is set, that any
+// DefaultOrder and VersionColumn refer to known columns, that any
+// SoftDeleteColumn is a known, nullable column, that Indexes cover known
+// columns, and that Children (with any ForeignKey actions known) and
+// ManyToMany relations relate known columns of known tables. Any other
+// database requirements are not yet considered.
 func Validate(sch *Schema) error {
 	for _, tbl := range sch.Tables {
 		if tbl.Name == "" {
@@ -29,9 +33,105 @@ func Validate(sch *Schema) error {
 			return errorHelper(tbl, "EssentialColumns is empty")
 		}
 
+		for _, ord := range tbl.DefaultOrder {
+			if tbl.GetColumn(ord.Column) == nil {
+				return errorHelper(tbl, "DefaultOrder references unknown column "+ord.Column)
+			}
+		}
+
+		if tbl.VersionColumn != "" && tbl.GetColumn(tbl.VersionColumn) == nil {
+			return errorHelper(tbl, "VersionColumn references unknown column "+tbl.VersionColumn)
+		}
+
+		if tbl.SoftDeleteColumn != "" {
+			col := tbl.GetColumn(tbl.SoftDeleteColumn)
+			if col == nil {
+				return errorHelper(tbl, "SoftDeleteColumn references unknown column "+tbl.SoftDeleteColumn)
+			}
+			if !col.AllowNull {
+				return errorHelper(tbl, "SoftDeleteColumn "+tbl.SoftDeleteColumn+" must allow NULL")
+			}
+		}
+
+		for i, idx := range tbl.Indexes {
+			if len(idx.Columns) == 0 {
+				return errorHelper(tbl, fmt.Sprintf("index %d (%s) has no Columns", i, idx.Name))
+			}
+			for _, col := range idx.Columns {
+				if tbl.GetColumn(col) == nil {
+					return errorHelper(tbl, "index "+idx.IndexName(tbl.Name)+" references unknown column "+col)
+				}
+			}
+		}
+
+		for name, child := range tbl.Children {
+			childTbl := sch.GetTable(name)
+			if childTbl == nil {
+				return errorHelper(tbl, "Children references unknown table "+name)
+			}
+			local, foreign := child.Keys(tbl)
+			if fk := child.ForeignKey; fk != nil {
+				if !validFKAction(fk.OnDelete) || !validFKAction(fk.OnUpdate) {
+					return errorHelper(tbl, "child table "+name+" has an unknown foreign key action")
+				}
+			}
+			if len(local) != len(foreign) {
+				return errorHelper(tbl, "child table "+name+" has mismatched LocalColumns and ForeignColumns")
+			}
+			for i := range local {
+				if tbl.GetColumn(local[i]) == nil {
+					return errorHelper(tbl, "child table "+name+" references unknown local column "+local[i])
+				}
+				if childTbl.GetColumn(foreign[i]) == nil {
+					return errorHelper(tbl, "child table "+name+" references unknown foreign column "+foreign[i])
+				}
+			}
+		}
+
+		for name, rel := range tbl.ManyToMany {
+			if err := validateManyToMany(sch, tbl, name, rel); err != nil {
+				return err
+			}
+		}
+
 		// TODO: What other requirements do we have for defining a valid
 		// schema?
 	}
 
 	return nil
 }
+
+func validateManyToMany(sch *Schema, tbl *Table, name string, rel *ManyToMany) error {
+	joinTbl := sch.GetTable(rel.JoinTable)
+	if joinTbl == nil {
+		return errorHelper(tbl, "many-to-many relation "+name+" references unknown join table "+rel.JoinTable)
+	}
+	related := sch.GetTable(rel.Table)
+	if related == nil {
+		return errorHelper(tbl, "many-to-many relation "+name+" references unknown table "+rel.Table)
+	}
+	local, joinLocal, joinForeign, foreign := rel.Keys(tbl, related)
+	if len(local) != len(joinLocal) || len(foreign) != len(joinForeign) {
+		return errorHelper(tbl, "many-to-many relation "+name+" has mismatched key columns")
+	}
+	check := []struct {
+		t    *Table
+		cols []string
+	}{{tbl, local}, {joinTbl, joinLocal}, {joinTbl, joinForeign}, {related, foreign}}
+	for _, c := range check {
+		for _, col := range c.cols {
+			if c.t.GetColumn(col) == nil {
+				return errorHelper(tbl, "many-to-many relation "+name+" references unknown column "+c.t.Name+"."+col)
+			}
+		}
+	}
+	return nil
+}
+
+func validFKAction(a FKAction) bool {
+	switch a {
+	case FKDefault, FKNoAction, FKRestrict, FKCascade, FKSetNull, FKSetDefault:
+		return true
+	}
+	return false
+}
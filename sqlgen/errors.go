@@ -0,0 +1,9 @@
+package sqlgen
+
+import (
+	"errors"
+)
+
+// ErrLocksUnsupported is returned by GetLock and ReleaseLock for databases
+// that dyndao can't take named locks on.
+var ErrLocksUnsupported = errors.New("dyndao: named locks are not supported by this database adapter")
@@ -0,0 +1,128 @@
+package sqlgen
+
+import (
+	"errors"
+
+	"github.com/rbastic/dyndao/schema"
+)
+
+// RenderSchemaDiff renders the statements that take a database from d.Old
+// to d.New: CREATE TABLE (and CREATE INDEX) for the tables added, ALTER
+// TABLE statements for the tables changed, preceded by DROP INDEX and
+// followed by CREATE INDEX for their indexes, and finally DROP TABLE for the
+// tables removed. The FOREIGN KEY constraints of the tables changed are
+// dropped before any of them is altered, and added once all of them have
+// been. Tables are created in the new schema's CreationOrder, and dropped in
+// the reverse of the old one's. An error is returned if the generator can't
+// express one of the changes.
+func RenderSchemaDiff(g *SQLGenerator, d *schema.SchemaDiff) ([]string, error) {
+	added, err := inCreationOrder(d.New, d.AddedTables)
+	if err != nil {
+		return nil, err
+	}
+	removed, err := inCreationOrder(d.Old, d.RemovedTables)
+	if err != nil {
+		return nil, err
+	}
+
+	var stmts []string
+	for _, name := range added {
+		sqlStr, err := g.CreateTable(g, d.New, name)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, sqlStr)
+
+		indexes, err := RenderCreateIndexes(g, d.New, name)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, indexes...)
+	}
+	for _, td := range d.ChangedTables {
+		for _, ref := range td.RemovedForeignKeys {
+			sqlStr, err := g.DropForeignKey(g, d.Old, ref)
+			if err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, sqlStr)
+		}
+	}
+	for _, td := range d.ChangedTables {
+		// Indexes are dropped before the columns they cover, and created after
+		for _, idx := range td.RemovedIndexes {
+			sqlStr, err := g.DropIndex(g, d.Old, td.Name, idx)
+			if err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, sqlStr)
+		}
+		alters, err := g.AlterTable(g, d.New, td)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, alters...)
+		for _, idx := range td.AddedIndexes {
+			sqlStr, err := g.CreateIndex(g, d.New, td.Name, idx)
+			if err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, sqlStr)
+		}
+	}
+	for _, td := range d.ChangedTables {
+		for _, ref := range td.AddedForeignKeys {
+			sqlStr, err := g.AddForeignKey(g, d.New, ref)
+			if err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, sqlStr)
+		}
+	}
+	for i := len(removed) - 1; i >= 0; i-- {
+		name := removed[i]
+		stmts = append(stmts, g.DropTable(schema.GetTableName(d.Old.Tables[name].Name, name)))
+	}
+	return stmts, nil
+}
+
+// inCreationOrder returns tables, which are keys of sch.Tables, in the
+// schema's CreationOrder.
+func inCreationOrder(sch *schema.Schema, tables []string) ([]string, error) {
+	if len(tables) == 0 {
+		return nil, nil
+	}
+	order, err := sch.CreationOrder()
+	if err != nil {
+		return nil, err
+	}
+	want := make(map[string]bool, len(tables))
+	for _, name := range tables {
+		want[name] = true
+	}
+	ordered := make([]string, 0, len(tables))
+	for _, name := range order {
+		if want[name] {
+			ordered = append(ordered, name)
+		}
+	}
+	return ordered, nil
+}
+
+// RenderCreateIndexes renders a CREATE INDEX statement for each of the
+// Indexes of a table.
+func RenderCreateIndexes(g *SQLGenerator, sch *schema.Schema, table string) ([]string, error) {
+	tbl, ok := sch.Tables[table]
+	if !ok {
+		return nil, errors.New("dyndao: unknown schema for table with name " + table)
+	}
+	stmts := make([]string, 0, len(tbl.Indexes))
+	for _, idx := range tbl.Indexes {
+		sqlStr, err := g.CreateIndex(g, sch, table, idx)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, sqlStr)
+	}
+	return stmts, nil
+}
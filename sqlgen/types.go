@@ -4,15 +4,29 @@ import (
 	"database/sql"
 
 	"github.com/rbastic/dyndao/object"
+	"github.com/rbastic/dyndao/query"
 	"github.com/rbastic/dyndao/schema"
 )
 
 type FnBindingInsert func(g *SQLGenerator, sch *schema.Schema, table string, data map[string]interface{}) (string, []interface{}, error)
+type FnBindingInsertMany func(g *SQLGenerator, sch *schema.Schema, table string, rows []map[string]interface{}) (string, []interface{}, error)
 type FnBindingUpdate func(g *SQLGenerator, sch *schema.Schema, obj *object.Object) (string, []interface{}, []interface{}, error)
 type FnBindingRetrieve func(g *SQLGenerator, sch *schema.Schema, obj *object.Object) (string, []string, []interface{}, error)
+type FnBindingRetrieveQuery func(g *SQLGenerator, sch *schema.Schema, table string, q *query.Query, opts *query.Options) (string, []string, []interface{}, error)
+type FnBindingAggregate func(g *SQLGenerator, sch *schema.Schema, table string, q *query.Query, groupBy []string, aggs []query.Aggregate, opts *query.Options) (string, []interface{}, error)
+type FnBindingExists func(g *SQLGenerator, sch *schema.Schema, table string, q *query.Query) (string, []interface{}, error)
+type FnBindingUpdateWhere func(g *SQLGenerator, sch *schema.Schema, table string, set map[string]interface{}, q *query.Query) (string, []interface{}, error)
+type FnBindingUpsert func(g *SQLGenerator, sch *schema.Schema, table string, data map[string]interface{}, conflictColumns []string) (string, []interface{}, error)
 type FnBindingDelete func(g *SQLGenerator, sch *schema.Schema, obj *object.Object) (string, []interface{}, error)
+type FnBindingDeleteWhere func(g *SQLGenerator, sch *schema.Schema, table string, q *query.Query) (string, []interface{}, error)
 type FnCreateTable func(g *SQLGenerator, sch *schema.Schema, table string) (string, error)
 type FnDropTable func(name string) string
+type FnAlterTable func(g *SQLGenerator, sch *schema.Schema, d *schema.TableDiff) ([]string, error)
+type FnCreateIndex func(g *SQLGenerator, sch *schema.Schema, table string, idx *schema.Index) (string, error)
+type FnDropIndex func(g *SQLGenerator, sch *schema.Schema, table string, idx *schema.Index) (string, error)
+type FnRenderForeignKey func(g *SQLGenerator, sch *schema.Schema, ref *schema.Reference) (string, error)
+type FnAddForeignKey func(g *SQLGenerator, sch *schema.Schema, ref *schema.Reference) (string, error)
+type FnDropForeignKey func(g *SQLGenerator, sch *schema.Schema, ref *schema.Reference) (string, error)
 type FnGetLock func(g *SQLGenerator, sch *schema.Schema, lockStr string) (string, []interface{}, error)
 type FnReleaseLock func(g *SQLGenerator, sch *schema.Schema, lockStr string) (string, []interface{}, error)
 type FnRenderBindingValueWithInt func(f *schema.Column, i int) string
@@ -25,13 +39,17 @@ type FnIsLOBType func(string) bool
 type FnDynamicObjectSetter func(g *SQLGenerator, schTable *schema.Table, columnNames []string, columnPointers []interface{}, columnTypes []*sql.ColumnType, obj *object.Object) error
 type FnMakeColumnPointers func(g *SQLGenerator, schTable *schema.Table, columnNames []string, columnTypes []*sql.ColumnType) ([]interface{}, error)
 
-type FnRenderWhereClause func(g *SQLGenerator, schTable *schema.Table, obj *object.Object) (string, []interface{}, error)
+type FnRenderWhereClause func(g *SQLGenerator, schTable *schema.Table, q *query.Query, bindI *int) (string, []interface{}, error)
+type FnRenderLimitOffset func(g *SQLGenerator, limit int, offset int, hasOrderBy bool) string
+type FnRenderOrderBy func(g *SQLGenerator, schTable *schema.Table, orders []query.Order) (string, error)
 type FnRenderUpdateWhereClause func(g *SQLGenerator, schTable *schema.Table, fieldsMap map[string]*schema.Column, obj *object.Object) (string, []interface{}, *int, error)
 
 type FnCoreBindingInsert func(g *SQLGenerator, schTable *schema.Table, data map[string]interface{}, identityCol string, fieldsMap map[string]*schema.Column) ([]string, []string, []interface{})
 
 type FnRenderCreateColumn func(g *SQLGenerator, f *schema.Column) string
+type FnCurrentTimestamp func() string
 type FnBindingInsertSQL func(schTable *schema.Table, tableName string, colNames []string, bindNames []string, identityCol string) string
+type FnBindingInsertManySQL func(schTable *schema.Table, tableName string, colNames []string, rowBindNames [][]string, identityCol string) string
 
 // SQLGenerator is the 'vtable struct' that an ORM expects a SQL string
 // generator to support.  While this does add an extra layer of indirection at
@@ -49,16 +67,30 @@ type SQLGenerator struct {
 	IsDB2      bool
 
 	BindingInsert             FnBindingInsert
+	BindingInsertMany         FnBindingInsertMany
 	BindingUpdate             FnBindingUpdate
 	BindingRetrieve           FnBindingRetrieve
+	BindingRetrieveQuery      FnBindingRetrieveQuery
+	BindingAggregate          FnBindingAggregate
+	BindingExists             FnBindingExists
+	BindingUpdateWhere        FnBindingUpdateWhere
+	BindingUpsert             FnBindingUpsert
 	BindingDelete             FnBindingDelete
+	BindingDeleteWhere        FnBindingDeleteWhere
 	GetLock                   FnGetLock
 	ReleaseLock               FnReleaseLock
 	CreateTable               FnCreateTable
 	RenderCreateColumn        FnRenderCreateColumn
+	RenderForeignKey          FnRenderForeignKey
+	AddForeignKey             FnAddForeignKey
+	DropForeignKey            FnDropForeignKey
 	DropTable                 FnDropTable
+	AlterTable                FnAlterTable
+	CreateIndex               FnCreateIndex
+	DropIndex                 FnDropIndex
 	RenderBindingValueWithInt FnRenderBindingValueWithInt
 	RenderInsertValue         FnRenderInsertValue
+	CurrentTimestamp          FnCurrentTimestamp
 
 	IsStringType FnIsStringType
 
@@ -72,6 +104,9 @@ type SQLGenerator struct {
 
 	RenderWhereClause       FnRenderWhereClause
 	RenderUpdateWhereClause FnRenderUpdateWhereClause
+	RenderLimitOffset       FnRenderLimitOffset
+	RenderOrderBy           FnRenderOrderBy
 	CoreBindingInsert       FnCoreBindingInsert
 	BindingInsertSQL        FnBindingInsertSQL
+	BindingInsertManySQL    FnBindingInsertManySQL
 }
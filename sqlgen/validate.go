@@ -4,21 +4,63 @@ func PanicIfInvalid(g *SQLGenerator) {
 	if g.BindingInsert == nil {
 		panic("dyndao: vtable BindingInsert is nil")
 	}
+	if g.BindingInsertMany == nil {
+		panic("dyndao: vtable BindingInsertMany is nil")
+	}
 	if g.BindingUpdate == nil {
 		panic("dyndao: vtable BindingUpdate is nil")
 	}
 	if g.BindingRetrieve == nil {
 		panic("dyndao: vtable BindingRetrieve is nil")
 	}
+	if g.BindingRetrieveQuery == nil {
+		panic("dyndao: vtable BindingRetrieveQuery is nil")
+	}
+	if g.BindingAggregate == nil {
+		panic("dyndao: vtable BindingAggregate is nil")
+	}
+	if g.BindingExists == nil {
+		panic("dyndao: vtable BindingExists is nil")
+	}
+	if g.BindingUpdateWhere == nil {
+		panic("dyndao: vtable BindingUpdateWhere is nil")
+	}
+	if g.BindingUpsert == nil {
+		panic("dyndao: vtable BindingUpsert is nil")
+	}
 	if g.BindingDelete == nil {
 		panic("dyndao: vtable BindingDelete is nil")
 	}
+	if g.BindingDeleteWhere == nil {
+		panic("dyndao: vtable BindingDeleteWhere is nil")
+	}
 	if g.CreateTable == nil {
 		panic("dyndao: vtable CreateTable is nil")
 	}
 	if g.DropTable == nil {
 		panic("dyndao: vtable DropTable is nil")
 	}
+	if g.AlterTable == nil {
+		panic("dyndao: vtable AlterTable is nil")
+	}
+	if g.CreateIndex == nil {
+		panic("dyndao: vtable CreateIndex is nil")
+	}
+	if g.DropIndex == nil {
+		panic("dyndao: vtable DropIndex is nil")
+	}
+	if g.CurrentTimestamp == nil {
+		panic("dyndao: vtable CurrentTimestamp is nil")
+	}
+	if g.RenderForeignKey == nil {
+		panic("dyndao: vtable RenderForeignKey is nil")
+	}
+	if g.AddForeignKey == nil {
+		panic("dyndao: vtable AddForeignKey is nil")
+	}
+	if g.DropForeignKey == nil {
+		panic("dyndao: vtable DropForeignKey is nil")
+	}
 	if g.RenderBindingValueWithInt == nil {
 		panic("dyndao: vtable RenderBindingValueWithInt is nil")
 	}
@@ -46,6 +88,12 @@ func PanicIfInvalid(g *SQLGenerator) {
 	if g.RenderWhereClause == nil {
 		panic("dyndao: vtable RenderWhereClause is nil")
 	}
+	if g.RenderLimitOffset == nil {
+		panic("dyndao: vtable RenderLimitOffset is nil")
+	}
+	if g.RenderOrderBy == nil {
+		panic("dyndao: vtable RenderOrderBy is nil")
+	}
 	if g.RenderUpdateWhereClause == nil {
 		panic("dyndao: vtable RenderUpdateWhereClause is nil")
 	}
@@ -61,4 +109,7 @@ func PanicIfInvalid(g *SQLGenerator) {
 	if g.BindingInsertSQL == nil {
 		panic("dyndao: vtable BindingInsertSQL is nil")
 	}
+	if g.BindingInsertManySQL == nil {
+		panic("dyndao: vtable BindingInsertManySQL is nil")
+	}
 }